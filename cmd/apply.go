@@ -0,0 +1,139 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/konstructio/kubefirst/cmd/akamai"
+	"github.com/konstructio/kubefirst/cmd/aws"
+	"github.com/konstructio/kubefirst/cmd/civo"
+	"github.com/konstructio/kubefirst/cmd/digitalocean"
+	"github.com/konstructio/kubefirst/cmd/google"
+	"github.com/konstructio/kubefirst/cmd/k3s"
+	"github.com/konstructio/kubefirst/cmd/vultr"
+	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/spec"
+	"github.com/konstructio/kubefirst/internal/utilities"
+	"github.com/spf13/cobra"
+)
+
+var (
+	specFileFlag    string
+	applyDryRunFlag bool
+)
+
+// applyCmd provisions the cluster described by a declarative spec file
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "create a cluster from a declarative spec file",
+	Long: `Create a cluster from a versioned YAML or JSON spec. The spec is validated, secret
+references are resolved from the environment or from files, and the cluster is
+provisioned exactly as the matching "<provider> create" command would.
+
+Example spec:
+
+  apiVersion: ` + spec.APIVersion + `
+  kind: ` + spec.KindManagementCluster + `
+  metadata:
+    name: kubefirst
+  spec:
+    provider: civo
+    region: NYC1
+    alertsEmail: admin@example.com
+    nodePools:
+      - instanceType: g4s.kube.large
+        count: 4
+    dns:
+      provider: civo
+      domain: example.com
+    git:
+      provider: github
+      owner: example-org
+    catalogApps:
+      - datadog
+    secrets:
+      CIVO_TOKEN:
+        fromEnv: CIVO_TOKEN
+      GITHUB_TOKEN:
+        fromFile: ~/.config/kubefirst/github-token`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		clusterSpec, err := spec.ReadFile(specFileFlag)
+		if err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to read cluster spec: %w", err)
+		}
+
+		if err := clusterSpec.Validate(); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("invalid cluster spec %q: %w", specFileFlag, err)
+		}
+
+		if err := clusterSpec.ResolveSecrets(); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to resolve cluster spec secrets: %w", err)
+		}
+
+		newCreateCmd, ok := createCommands[clusterSpec.Spec.Provider]
+		if !ok {
+			err = fmt.Errorf("no create command found for provider %q", clusterSpec.Spec.Provider)
+			progress.Error(err.Error())
+			return err
+		}
+
+		flags, err := clusterSpec.Flags()
+		if err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to resolve cluster spec flags: %w", err)
+		}
+		if applyDryRunFlag {
+			flags = append(flags, spec.Flag{Name: "dry-run", Value: "true"})
+		}
+
+		// a new create command starts from the defaults of every flag, the spec
+		// values are checked exactly as when they are passed on the command line
+		createCmd := newCreateCmd()
+		for _, flag := range flags {
+			if err := createCmd.Flags().Set(flag.Name, flag.Value); err != nil {
+				err = fmt.Errorf("invalid value for --%s from the cluster spec: %w", flag.Name, err)
+				progress.Error(err.Error())
+				return err
+			}
+		}
+		if err := createCmd.ValidateRequiredFlags(); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("invalid cluster spec %q: %w", specFileFlag, err)
+		}
+
+		createCmd.SetContext(cmd.Context())
+		if err := createCmd.RunE(createCmd, nil); err != nil {
+			return fmt.Errorf("failed to create cluster %q: %w", clusterSpec.Metadata.Name, err)
+		}
+
+		return nil
+	},
+}
+
+// createCommands builds the create command of each provider a spec may name
+var createCommands = map[string]func() *cobra.Command{
+	"akamai":       akamai.Create,
+	"aws":          aws.Create,
+	"civo":         civo.Create,
+	"digitalocean": digitalocean.Create,
+	"google":       google.Create,
+	"k3s":          k3s.Create,
+	"vultr":        vultr.Create,
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&specFileFlag, "file", "f", "", "Path to the YAML or JSON cluster spec (required)")
+	applyCmd.MarkFlagRequired("file")
+	applyCmd.Flags().BoolVar(&applyDryRunFlag, "dry-run", false, "Validate the spec and credentials, then print the cluster definition without provisioning")
+	utilities.AddProvisioningFlags(applyCmd)
+
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"testing"
+
+	"github.com/konstructio/kubefirst/cmd/k3d"
+	"github.com/konstructio/kubefirst/internal/spec"
+	"github.com/spf13/cobra"
+)
+
+func TestApplySpecFlagsExistOnCreateCommands(t *testing.T) {
+	t.Setenv("KUBEFIRST_TEST_SSH_KEY", "private-key")
+
+	for provider, newCreateCmd := range createCommands {
+		t.Run(provider, func(t *testing.T) {
+			s := spec.ClusterSpec{
+				Metadata: spec.Metadata{Name: "kubefirst"},
+				Spec: spec.ManagementClusterSpec{
+					Provider:    provider,
+					Region:      "region",
+					AlertsEmail: "admin@example.com",
+					NodePools:   []spec.NodePool{{InstanceType: "large", Count: 3}},
+					DNS:         spec.DNSSpec{Provider: "cloudflare", Domain: "example.com", Subdomain: "platform"},
+					Git:         spec.GitSpec{Provider: "github", Protocol: "https", Owner: "example-org", TemplateURL: "https://example.com/template.git", TemplateBranch: "main"},
+					CatalogApps: []string{"datadog"},
+				},
+			}
+			switch provider {
+			case "aws":
+				s.Spec.AWS = &spec.AWSSpec{ECR: true}
+			case "google":
+				s.Spec.Google = &spec.GoogleSpec{Project: "project"}
+			case "k3s":
+				s.Spec.K3s = &spec.K3sSpec{
+					ServersPrivateIPs: []string{"10.0.0.1"},
+					ServersPublicIPs:  []string{"192.0.2.1"},
+					ServersArgs:       []string{"--disable=traefik"},
+					SSHUser:           "root",
+					SSHPrivateKey:     spec.SecretRef{FromEnv: "KUBEFIRST_TEST_SSH_KEY"},
+				}
+			}
+
+			flags, err := s.Flags()
+			if err != nil {
+				t.Fatalf("Flags() error = %v", err)
+			}
+
+			createCmd := newCreateCmd()
+			for _, flag := range flags {
+				if err := createCmd.Flags().Set(flag.Name, flag.Value); err != nil {
+					t.Errorf("%s create does not accept --%s=%s: %v", provider, flag.Name, flag.Value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProvisioningFlagsAreDeclaredOnApplyAndCreate(t *testing.T) {
+	commands := map[string]*cobra.Command{"apply": applyCmd, "k3d": k3d.Create()}
+	for provider, newCreateCmd := range createCommands {
+		commands[provider] = newCreateCmd()
+	}
+
+	for _, name := range []string{"progress", "notify-webhook", "catalog-offline"} {
+		if rootCmd.PersistentFlags().Lookup(name) != nil {
+			t.Errorf("--%s is declared on every command, want it only on apply and create", name)
+		}
+		for command, c := range commands {
+			if c.Flags().Lookup(name) == nil {
+				t.Errorf("%s does not declare --%s", command, name)
+			}
+		}
+	}
+}
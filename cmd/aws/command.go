@@ -12,6 +12,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/constants"
 	"github.com/konstructio/kubefirst/internal/common"
 	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/utilities"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +23,8 @@ var (
 	cloudRegionFlag          string
 	clusterNameFlag          string
 	clusterTypeFlag          string
+	parentClusterFlag        string
+	environmentFlag          string
 	dnsProviderFlag          string
 	githubOrgFlag            string
 	gitlabGroupFlag          string
@@ -37,6 +40,7 @@ var (
 	nodeCountFlag            string
 	installCatalogApps       string
 	installKubefirstProFlag  bool
+	dryRunFlag               bool
 
 	// Supported argument arrays
 	supportedDNSProviders        = []string{"aws", "cloudflare"}
@@ -83,6 +87,8 @@ func Create() *cobra.Command {
 	createCmd.Flags().StringVar(&cloudRegionFlag, "cloud-region", "us-east-1", "the aws region to provision infrastructure in")
 	createCmd.Flags().StringVar(&clusterNameFlag, "cluster-name", "kubefirst", "the name of the cluster to create")
 	createCmd.Flags().StringVar(&clusterTypeFlag, "cluster-type", "mgmt", "the type of cluster to create (i.e. mgmt|workload)")
+	createCmd.Flags().StringVar(&parentClusterFlag, "parent", "", "The management cluster that provisions the workload cluster (required with --cluster-type workload)")
+	createCmd.Flags().StringVar(&environmentFlag, "environment", "", "The environment of the workload cluster (i.e. development|staging|production)")
 	createCmd.Flags().StringVar(&nodeCountFlag, "node-count", awsDefaults.NodeCount, "the node count for the cluster")
 	createCmd.Flags().StringVar(&nodeTypeFlag, "node-type", awsDefaults.InstanceSize, "the instance size of the cluster to create")
 	createCmd.Flags().StringVar(&dnsProviderFlag, "dns-provider", "aws", fmt.Sprintf("the dns provider - one of: %q", supportedDNSProviders))
@@ -99,6 +105,9 @@ func Create() *cobra.Command {
 	createCmd.Flags().BoolVar(&useTelemetryFlag, "use-telemetry", true, "whether to emit telemetry")
 	createCmd.Flags().BoolVar(&ecrFlag, "ecr", false, "whether or not to use ecr vs the git provider")
 	createCmd.Flags().BoolVar(&installKubefirstProFlag, "install-kubefirst-pro", true, "whether or not to install kubefirst pro")
+	createCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Validate flags, catalog apps and git credentials, then print the cluster definition without provisioning")
+
+	utilities.AddProvisioningFlags(createCmd)
 
 	return createCmd
 }
@@ -21,20 +21,28 @@ import (
 	"github.com/konstructio/kubefirst/internal/launch"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/konstructio/kubefirst/internal/provision"
+	"github.com/konstructio/kubefirst/internal/types"
 	"github.com/konstructio/kubefirst/internal/utilities"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 func createAws(cmd *cobra.Command, _ []string) error {
+	// a dry run goes through the same checks as a real one, the config they
+	// write is kept in memory so the active config is left untouched
+	if dryRunFlag {
+		viper.SetFs(afero.NewMemMapFs())
+	}
+
 	cliFlags, err := utilities.GetFlags(cmd, "aws")
 	if err != nil {
 		progress.Error(err.Error())
 		return nil
 	}
 
-	progress.DisplayLogHints(40)
+	progress.DisplayLogHints("aws", 40)
 
 	isValid, catalogApps, err := catalog.ValidateCatalogApps(cliFlags.InstallCatalogApps)
 	if !isValid {
@@ -47,7 +55,17 @@ func createAws(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to validate provided flags: %w", err)
 	}
 
-	utilities.CreateK1ClusterDirectory(cliFlags.ClusterName)
+	if cliFlags.ClusterType == types.ClusterTypeWorkload {
+		if err := provision.CreateWorkloadCluster(cmd.Context(), cliFlags); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to create workload cluster: %w", err)
+		}
+		return nil
+	}
+
+	if !cliFlags.DryRun {
+		utilities.CreateK1ClusterDirectory(cliFlags.ClusterName)
+	}
 
 	// If cluster setup is complete, return
 	clusterSetupComplete := viper.GetBool("kubefirst-checks.cluster-install-complete")
@@ -111,6 +129,14 @@ func createAws(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
+	if cliFlags.DryRun {
+		if err := provision.DryRunMgmtCluster(gitAuth, cliFlags, catalogApps); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to render cluster definition: %w", err)
+		}
+		return nil
+	}
+
 	k3dClusterCreationComplete := viper.GetBool("launch.deployed")
 	isK1Debug := strings.ToLower(os.Getenv("K1_LOCAL_DEBUG")) == "true"
 
@@ -124,7 +150,7 @@ func createAws(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to check kubefirst API availability: %w", err)
 	}
 
-	if err := provision.CreateMgmtCluster(gitAuth, cliFlags, catalogApps); err != nil {
+	if err := provision.CreateMgmtCluster(cmd.Context(), gitAuth, cliFlags, catalogApps); err != nil {
 		progress.Error(err.Error())
 		return fmt.Errorf("failed to create management cluster: %w", err)
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/providerConfigs"
 	"github.com/konstructio/kubefirst-api/pkg/ssl"
 	utils "github.com/konstructio/kubefirst-api/pkg/utils"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -46,6 +47,9 @@ func backupCivoSSL(_ *cobra.Command, _ []string) error {
 		os.Getenv("CF_API_TOKEN"),
 		os.Getenv("CF_ORIGIN_CA_ISSUER_API_TOKEN"),
 	)
+	if err := contexts.RebaseClusterPaths(config); err != nil {
+		return fmt.Errorf("failed to locate the cluster directory: %w", err)
+	}
 
 	if _, err := os.Stat(config.SSLBackupDir + "/certificates"); os.IsNotExist(err) {
 		// path/to/whatever does not exist
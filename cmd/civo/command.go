@@ -12,6 +12,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/constants"
 	"github.com/konstructio/kubefirst/internal/common"
 	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/utilities"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +23,8 @@ var (
 	cloudRegionFlag          string
 	clusterNameFlag          string
 	clusterTypeFlag          string
+	parentClusterFlag        string
+	environmentFlag          string
 	dnsProviderFlag          string
 	subdomainNameFlag        string
 	domainNameFlag           string
@@ -36,6 +39,7 @@ var (
 	nodeCountFlag            string
 	installCatalogApps       string
 	installKubefirstProFlag  bool
+	dryRunFlag               bool
 
 	// RootCredentials
 	copyArgoCDPasswordToClipboardFlag bool
@@ -99,6 +103,8 @@ func Create() *cobra.Command {
 	createCmd.Flags().StringVar(&cloudRegionFlag, "cloud-region", "NYC1", "The Civo region to provision infrastructure in")
 	createCmd.Flags().StringVar(&clusterNameFlag, "cluster-name", "kubefirst", "The name of the cluster to create")
 	createCmd.Flags().StringVar(&clusterTypeFlag, "cluster-type", "mgmt", "The type of cluster to create (i.e. mgmt|workload)")
+	createCmd.Flags().StringVar(&parentClusterFlag, "parent", "", "The management cluster that provisions the workload cluster (required with --cluster-type workload)")
+	createCmd.Flags().StringVar(&environmentFlag, "environment", "", "The environment of the workload cluster (i.e. development|staging|production)")
 	createCmd.Flags().StringVar(&nodeCountFlag, "node-count", civoDefaults.NodeCount, "The node count for the cluster")
 	createCmd.Flags().StringVar(&nodeTypeFlag, "node-type", civoDefaults.InstanceSize, "The instance size of the cluster to create")
 	createCmd.Flags().StringVar(&dnsProviderFlag, "dns-provider", "civo", fmt.Sprintf("The DNS provider - one of: %s", supportedDNSProviders))
@@ -114,6 +120,9 @@ func Create() *cobra.Command {
 	createCmd.Flags().StringVar(&installCatalogApps, "install-catalog-apps", "", "Comma separated values to install after provision")
 	createCmd.Flags().BoolVar(&useTelemetryFlag, "use-telemetry", true, "Whether to emit telemetry")
 	createCmd.Flags().BoolVar(&installKubefirstProFlag, "install-kubefirst-pro", true, "Whether or not to install Kubefirst Pro")
+	createCmd.Flags().BoolVar(&dryRunFlag, "dry-run", false, "Validate flags, catalog apps and git credentials, then print the cluster definition without provisioning")
+
+	utilities.AddProvisioningFlags(createCmd)
 
 	return createCmd
 }
@@ -19,20 +19,28 @@ import (
 	"github.com/konstructio/kubefirst/internal/launch"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/konstructio/kubefirst/internal/provision"
+	"github.com/konstructio/kubefirst/internal/types"
 	"github.com/konstructio/kubefirst/internal/utilities"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 func createCivo(cmd *cobra.Command, _ []string) error {
+	// a dry run goes through the same checks as a real one, the config they
+	// write is kept in memory so the active config is left untouched
+	if dryRunFlag {
+		viper.SetFs(afero.NewMemMapFs())
+	}
+
 	cliFlags, err := utilities.GetFlags(cmd, "civo")
 	if err != nil {
 		progress.Error(err.Error())
 		return fmt.Errorf("failed to get CLI flags: %w", err)
 	}
 
-	progress.DisplayLogHints(15)
+	progress.DisplayLogHints("civo", 15)
 
 	isValid, catalogApps, err := catalog.ValidateCatalogApps(cliFlags.InstallCatalogApps)
 	if !isValid {
@@ -45,9 +53,19 @@ func createCivo(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to validate provided flags: %w", err)
 	}
 
+	if cliFlags.ClusterType == types.ClusterTypeWorkload {
+		if err := provision.CreateWorkloadCluster(cmd.Context(), cliFlags); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to create workload cluster: %w", err)
+		}
+		return nil
+	}
+
 	// If cluster setup is complete, return
 
-	utilities.CreateK1ClusterDirectory(clusterNameFlag)
+	if !cliFlags.DryRun {
+		utilities.CreateK1ClusterDirectory(clusterNameFlag)
+	}
 
 	gitAuth, err := gitShim.ValidateGitCredentials(cliFlags.GitProvider, cliFlags.GithubOrg, cliFlags.GitlabGroup)
 	if err != nil {
@@ -80,6 +98,14 @@ func createCivo(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("failed to write viper config: %w", err)
 	}
 
+	if cliFlags.DryRun {
+		if err := provision.DryRunMgmtCluster(gitAuth, cliFlags, catalogApps); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to render cluster definition: %w", err)
+		}
+		return nil
+	}
+
 	k3dClusterCreationComplete := viper.GetBool("launch.deployed")
 	isK1Debug := strings.ToLower(os.Getenv("K1_LOCAL_DEBUG")) == "true"
 
@@ -93,7 +119,7 @@ func createCivo(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("API availability check failed: %w", err)
 	}
 
-	if err := provision.CreateMgmtCluster(gitAuth, cliFlags, catalogApps); err != nil {
+	if err := provision.CreateMgmtCluster(cmd.Context(), gitAuth, cliFlags, catalogApps); err != nil {
 		progress.Error(err.Error())
 		return fmt.Errorf("failed to create management cluster: %w", err)
 	}
@@ -0,0 +1,206 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/konstructio/kubefirst/internal/contexts"
+	"github.com/konstructio/kubefirst/internal/launch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	contextConsoleURLFlag string
+	contextUseFlag        bool
+	contextForceFlag      bool
+)
+
+// contextCmd manages the sets of local state kubefirst commands operate on
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "manage kubefirst contexts",
+	Long: `Manage kubefirst contexts. Each context has its own console URL, config file and
+directory for logs and cluster files, so a local k3d platform and a cloud management
+cluster can be operated side by side. Every command uses the active context, which
+can be overridden for a single command with the ` + contexts.EnvContext + ` environment variable.
+
+Each context runs its local console in its own k3d cluster, named after the context.
+Local consoles are all served on port 443, so stop one with ` + "`kubefirst launch down`" + `
+before running ` + "`kubefirst launch up`" + ` in another context.
+
+The "` + contexts.DefaultContextName + `" context uses $HOME/.kubefirst and $HOME/.k1 and always exists.`,
+}
+
+func init() {
+	contextCmd.AddCommand(contextList(), contextUse(), contextCreate(), contextDelete())
+
+	rootCmd.AddCommand(contextCmd)
+}
+
+// contextList prints every context and marks the active one
+func contextList() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list kubefirst contexts",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			list, err := contexts.List()
+			if err != nil {
+				return fmt.Errorf("unable to list contexts: %w", err)
+			}
+
+			current, err := contexts.Current()
+			if err != nil {
+				return fmt.Errorf("unable to load the active context: %w", err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "CURRENT\tNAME\tCONSOLE\tDIRECTORY")
+			for _, ctx := range list {
+				marker := ""
+				if ctx.Name == current.Name {
+					marker = "*"
+				}
+				consoleURL := ctx.ConsoleURL
+				if consoleURL == "" {
+					consoleURL = "-"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", marker, ctx.Name, consoleURL, ctx.K1Dir)
+			}
+
+			if err := tw.Flush(); err != nil {
+				return fmt.Errorf("unable to print contexts: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// contextUse makes a context the active one
+func contextUse() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use <name>",
+		Short: "switch the active kubefirst context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ctx, err := contexts.Use(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to switch context: %w", err)
+			}
+
+			fmt.Printf("Switched to context %q\n", ctx.Name)
+			if override := os.Getenv(contexts.EnvContext); override != "" && override != ctx.Name {
+				fmt.Printf("%s is set to %q and takes precedence in this shell\n", contexts.EnvContext, override)
+			}
+			return nil
+		},
+	}
+}
+
+// contextCreate registers a new context with its own config file and directory
+func contextCreate() *cobra.Command {
+	contextCreateCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "create a kubefirst context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ctx, err := contexts.Create(args[0], contextConsoleURLFlag)
+			if err != nil {
+				return fmt.Errorf("unable to create context: %w", err)
+			}
+			fmt.Printf("Created context %q in %s\n", ctx.Name, ctx.K1Dir)
+
+			if contextUseFlag {
+				if _, err := contexts.Use(ctx.Name); err != nil {
+					return fmt.Errorf("unable to switch context: %w", err)
+				}
+				fmt.Printf("Switched to context %q\n", ctx.Name)
+			}
+			return nil
+		},
+	}
+
+	contextCreateCmd.Flags().StringVar(&contextConsoleURLFlag, "console-url", "", "The Kubefirst console URL used by the context (defaults to the console selected with `kubefirst login`)")
+	contextCreateCmd.Flags().BoolVar(&contextUseFlag, "use", false, "Switch to the context once it is created")
+
+	return contextCreateCmd
+}
+
+// contextDelete removes a context and its local files
+func contextDelete() *cobra.Command {
+	contextDeleteCmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "delete a kubefirst context, its local console and its local files",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if !contextForceFlag {
+				if err := checkContextUnused(args[0]); err != nil {
+					return err
+				}
+			}
+
+			ctx, err := contexts.CheckDelete(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to delete context: %w", err)
+			}
+
+			// the k3d client of the console is kept in the directory about to be removed
+			if err := launch.DeleteConsoleCluster(ctx); err != nil {
+				return fmt.Errorf("unable to delete the console of context %q: %w", ctx.Name, err)
+			}
+
+			if _, err := contexts.Delete(ctx.Name); err != nil {
+				return fmt.Errorf("unable to delete context: %w", err)
+			}
+
+			fmt.Printf("Deleted context %q, its console cluster %q and %s\n", ctx.Name, ctx.ConsoleClusterName(), ctx.K1Dir)
+			return nil
+		},
+	}
+
+	contextDeleteCmd.Flags().BoolVar(&contextForceFlag, "force", false, "Delete the context even if it still records a deployed console or a provisioned platform")
+
+	return contextDeleteCmd
+}
+
+// checkContextUnused refuses to delete a context whose config still records
+// resources that would be orphaned along with its files
+func checkContextUnused(name string) error {
+	list, err := contexts.List()
+	if err != nil {
+		return fmt.Errorf("unable to list contexts: %w", err)
+	}
+
+	for _, ctx := range list {
+		if ctx.Name != name {
+			continue
+		}
+
+		config := viper.New()
+		config.SetConfigFile(ctx.ConfigFile)
+		config.SetConfigType("yaml")
+		if err := config.ReadInConfig(); err != nil {
+			// a context without a readable config has nothing to orphan
+			return nil
+		}
+
+		if config.GetBool("launch.deployed") {
+			return fmt.Errorf("context %q has a deployed console - run `%s=%s kubefirst launch down` first or use --force", name, contexts.EnvContext, name)
+		}
+		if checks := config.GetStringMap("kubefirst-checks"); len(checks) > 0 {
+			return fmt.Errorf("context %q records a platform for cluster %q - destroy it first or use --force", name, config.GetString("flags.cluster-name"))
+		}
+		return nil
+	}
+
+	// unknown contexts are reported by the delete itself
+	return nil
+}
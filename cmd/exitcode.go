@@ -0,0 +1,48 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"errors"
+
+	"github.com/konstructio/kubefirst/internal/cluster"
+)
+
+// Process exit codes. Each class of console API error has its own code so
+// scripts can react to failures without matching error messages.
+const (
+	ExitCodeSuccess      = 0
+	ExitCodeError        = 1
+	ExitCodeNotFound     = 3
+	ExitCodeConflict     = 4
+	ExitCodeValidation   = 5
+	ExitCodeUnauthorized = 6
+	ExitCodeForbidden    = 7
+	ExitCodeUnavailable  = 8
+
+	// ExitCodeInterrupted is returned when the command was interrupted, such
+	// as with Ctrl+C, following the shell convention of 128 + SIGINT
+	ExitCodeInterrupted = 130
+)
+
+func exitCodeFor(err error) int {
+	switch {
+	case errors.Is(err, cluster.ErrNotFound):
+		return ExitCodeNotFound
+	case errors.Is(err, cluster.ErrConflict):
+		return ExitCodeConflict
+	case errors.Is(err, cluster.ErrValidation):
+		return ExitCodeValidation
+	case errors.Is(err, cluster.ErrUnauthorized):
+		return ExitCodeUnauthorized
+	case errors.Is(err, cluster.ErrForbidden):
+		return ExitCodeForbidden
+	case errors.Is(err, cluster.ErrUnavailable):
+		return ExitCodeUnavailable
+	}
+	return ExitCodeError
+}
@@ -0,0 +1,42 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/konstructio/kubefirst/internal/cluster"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "not found", err: &cluster.APIError{Kind: cluster.ErrNotFound, StatusCode: 404}, want: ExitCodeNotFound},
+		{name: "conflict", err: &cluster.APIError{Kind: cluster.ErrConflict, StatusCode: 400}, want: ExitCodeConflict},
+		{name: "validation", err: &cluster.APIError{Kind: cluster.ErrValidation, StatusCode: 422}, want: ExitCodeValidation},
+		{name: "unauthorized", err: &cluster.APIError{Kind: cluster.ErrUnauthorized, StatusCode: 401}, want: ExitCodeUnauthorized},
+		{name: "forbidden", err: &cluster.APIError{Kind: cluster.ErrForbidden, StatusCode: 403}, want: ExitCodeForbidden},
+		{name: "unavailable", err: fmt.Errorf("%w: giving up after 4 attempts", cluster.ErrUnavailable), want: ExitCodeUnavailable},
+		{name: "wrapped", err: fmt.Errorf("error getting cluster %q: %w", "demo", &cluster.APIError{Kind: cluster.ErrNotFound}), want: ExitCodeNotFound},
+		{name: "server error", err: &cluster.APIError{Kind: cluster.ErrUnavailable, StatusCode: 500}, want: ExitCodeUnavailable},
+		{name: "unexpected response", err: &cluster.APIError{Kind: cluster.ErrUnexpectedResponse, StatusCode: 418}, want: ExitCodeError},
+		{name: "other error", err: errors.New("unable to read the config"), want: ExitCodeError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeFor(tt.err); got != tt.want {
+				t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
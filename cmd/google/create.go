@@ -19,21 +19,29 @@ import (
 	"github.com/konstructio/kubefirst/internal/launch"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/konstructio/kubefirst/internal/provision"
+	"github.com/konstructio/kubefirst/internal/types"
 	"github.com/konstructio/kubefirst/internal/utilities"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // required for authentication
 )
 
 func createGoogle(cmd *cobra.Command, _ []string) error {
+	// a dry run goes through the same checks as a real one, the config they
+	// write is kept in memory so the active config is left untouched
+	if dryRunFlag {
+		viper.SetFs(afero.NewMemMapFs())
+	}
+
 	cliFlags, err := utilities.GetFlags(cmd, "google")
 	if err != nil {
 		progress.Error(err.Error())
 		return fmt.Errorf("failed to get flags: %w", err)
 	}
 
-	progress.DisplayLogHints(20)
+	progress.DisplayLogHints("google", 20)
 
 	isValid, catalogApps, err := catalog.ValidateCatalogApps(cliFlags.InstallCatalogApps)
 	if !isValid {
@@ -46,6 +54,14 @@ func createGoogle(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("validation of provided flags failed: %w", err)
 	}
 
+	if cliFlags.ClusterType == types.ClusterTypeWorkload {
+		if err := provision.CreateWorkloadCluster(cmd.Context(), cliFlags); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to create workload cluster: %w", err)
+		}
+		return nil
+	}
+
 	clusterSetupComplete := viper.GetBool("kubefirst-checks.cluster-install-complete")
 	if clusterSetupComplete {
 		err = fmt.Errorf("this cluster install process has already completed successfully")
@@ -53,7 +69,9 @@ func createGoogle(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("cluster setup is complete: %w", err)
 	}
 
-	utilities.CreateK1ClusterDirectory(clusterNameFlag)
+	if !cliFlags.DryRun {
+		utilities.CreateK1ClusterDirectory(clusterNameFlag)
+	}
 
 	gitAuth, err := gitShim.ValidateGitCredentials(cliFlags.GitProvider, cliFlags.GithubOrg, cliFlags.GitlabGroup)
 	if err != nil {
@@ -82,6 +100,14 @@ func createGoogle(cmd *cobra.Command, _ []string) error {
 	viper.Set(fmt.Sprintf("kubefirst-checks.%s-credentials", cliFlags.GitProvider), true)
 	viper.WriteConfig()
 
+	if cliFlags.DryRun {
+		if err := provision.DryRunMgmtCluster(gitAuth, cliFlags, catalogApps); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("failed to render cluster definition: %w", err)
+		}
+		return nil
+	}
+
 	k3dClusterCreationComplete := viper.GetBool("launch.deployed")
 	isK1Debug := strings.ToLower(os.Getenv("K1_LOCAL_DEBUG")) == "true"
 
@@ -95,7 +121,7 @@ func createGoogle(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("kubefirst api availability check failed: %w", err)
 	}
 
-	if err := provision.CreateMgmtCluster(gitAuth, cliFlags, catalogApps); err != nil {
+	if err := provision.CreateMgmtCluster(cmd.Context(), gitAuth, cliFlags, catalogApps); err != nil {
 		progress.Error(err.Error())
 		return fmt.Errorf("failed to create management cluster: %w", err)
 	}
@@ -13,6 +13,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/konstructio/kubefirst-api/pkg/configs"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/spf13/cobra"
 )
@@ -23,27 +24,39 @@ var infoCmd = &cobra.Command{
 	Short: "provides general Kubefirst setup data",
 	Long:  `Provides machine data, files and folders paths`,
 	Run: func(_ *cobra.Command, _ []string) {
-		config := configs.ReadConfig()
+		progress.Success(infoSummary())
+	},
+}
 
-		var buf bytes.Buffer
+// infoSummary describes the machine and the kubefirst setup
+func infoSummary() string {
+	config := configs.ReadConfig()
 
-		tw := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', tabwriter.Debug)
+	var buf bytes.Buffer
 
-		fmt.Fprintln(&buf, "##")
-		fmt.Fprintln(&buf, "# Info summary")
-		fmt.Fprintln(&buf, "")
+	tw := tabwriter.NewWriter(&buf, 0, 0, 1, ' ', tabwriter.Debug)
 
-		fmt.Fprintf(tw, "Name\tValue\n")
-		fmt.Fprintf(tw, "---\t---\n")
-		fmt.Fprintf(tw, "Operational System\t%s\n", config.LocalOs)
-		fmt.Fprintf(tw, "Architecture\t%s\n", config.LocalArchitecture)
-		fmt.Fprintf(tw, "Golang version\t%s\n", runtime.Version())
+	fmt.Fprintln(&buf, "##")
+	fmt.Fprintln(&buf, "# Info summary")
+	fmt.Fprintln(&buf, "")
+
+	fmt.Fprintf(tw, "Name\tValue\n")
+	fmt.Fprintf(tw, "---\t---\n")
+	fmt.Fprintf(tw, "Operational System\t%s\n", config.LocalOs)
+	fmt.Fprintf(tw, "Architecture\t%s\n", config.LocalArchitecture)
+	fmt.Fprintf(tw, "Golang version\t%s\n", runtime.Version())
+	if activeContext, err := contexts.Current(); err == nil {
+		fmt.Fprintf(tw, "Kubefirst context\t%s\n", activeContext.Name)
+		fmt.Fprintf(tw, "Kubefirst config file\t%s\n", activeContext.ConfigFile)
+		fmt.Fprintf(tw, "Kubefirst config folder\t%s\n", activeContext.K1Dir)
+	} else {
 		fmt.Fprintf(tw, "Kubefirst config file\t%s\n", config.KubefirstConfigFilePath)
 		fmt.Fprintf(tw, "Kubefirst config folder\t%s\n", config.K1FolderPath)
-		fmt.Fprintf(tw, "Kubefirst Version\t%s\n", configs.K1Version)
+	}
+	fmt.Fprintf(tw, "Kubefirst Version\t%s\n", configs.K1Version)
+	tw.Flush()
 
-		progress.Success(buf.String())
-	},
+	return buf.String()
 }
 
 func init() {
@@ -41,8 +41,10 @@ import (
 	utils "github.com/konstructio/kubefirst-api/pkg/utils"
 	"github.com/konstructio/kubefirst-api/pkg/wrappers"
 	"github.com/konstructio/kubefirst/internal/catalog"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/gitShim"
 	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/redact"
 	"github.com/konstructio/kubefirst/internal/segment"
 	"github.com/konstructio/kubefirst/internal/utilities"
 	"github.com/kubefirst/metrics-client/pkg/telemetry"
@@ -265,7 +267,11 @@ func runK3d(cmd *cobra.Command, _ []string) error {
 		gitDestDescriptor = "Group"
 	}
 
+	// the files of the platform are kept in the directory of the active context
 	config := k3d.GetConfig(clusterNameFlag, gitProviderFlag, cGitOwner, gitProtocolFlag)
+	if err := contexts.RebaseClusterPaths(config); err != nil {
+		return fmt.Errorf("failed to locate the cluster directory: %w", err)
+	}
 	switch gitProviderFlag {
 	case "github":
 		config.GithubToken = cGitToken
@@ -393,6 +399,7 @@ func runK3d(cmd *cobra.Command, _ []string) error {
 			return fmt.Errorf("failed to create SSH key pair: %w", err)
 		}
 		log.Info().Msg("ssh key pair creation complete")
+		redact.Register(sshPrivateKey)
 
 		viper.Set("kbot.private-key", sshPrivateKey)
 		viper.Set("kbot.public-key", sshPublicKey)
@@ -466,16 +473,21 @@ func runK3d(cmd *cobra.Command, _ []string) error {
 	if !viper.GetBool("kubefirst-checks.tools-downloaded") {
 		log.Info().Msg("installing kubefirst dependencies")
 
-		err := k3d.DownloadTools(clusterNameFlag, config.GitProvider, cGitOwner, config.ToolsDir, config.GitProtocol)
+		// DownloadTools derives the paths of the tools from the name it is given
+		clusterDir, err := contexts.ClusterDirName(clusterNameFlag)
+		if err != nil {
+			return fmt.Errorf("failed to locate the cluster directory: %w", err)
+		}
+		err = k3d.DownloadTools(clusterDir, config.GitProvider, cGitOwner, config.ToolsDir, config.GitProtocol)
 		if err != nil {
 			return fmt.Errorf("failed to download tools: %w", err)
 		}
 
-		log.Info().Msg("download dependencies `$HOME/.k1/tools` complete")
+		log.Info().Msgf("download dependencies to %q complete", config.ToolsDir)
 		viper.Set("kubefirst-checks.tools-downloaded", true)
 		viper.WriteConfig()
 	} else {
-		log.Info().Msg("already completed download of dependencies to `$HOME/.k1/tools` - continuing")
+		log.Info().Msgf("already completed download of dependencies to %q - continuing", config.ToolsDir)
 	}
 	progressPrinter.IncrementTracker("preflight-checks", 1)
 
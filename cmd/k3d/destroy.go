@@ -20,6 +20,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/progressPrinter"
 	"github.com/konstructio/kubefirst-api/pkg/terraform"
 	utils "github.com/konstructio/kubefirst-api/pkg/utils"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -63,6 +64,9 @@ func destroyK3d(_ *cobra.Command, _ []string) error {
 	}
 
 	config := k3d.GetConfig(clusterName, gitProvider, cGitOwner, gitProtocol)
+	if err := contexts.RebaseClusterPaths(config); err != nil {
+		return fmt.Errorf("failed to locate the cluster directory: %w", err)
+	}
 	switch gitProvider {
 	case "github":
 		config.GithubToken = cGitToken
@@ -12,6 +12,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/k3d"
 	"github.com/konstructio/kubefirst-api/pkg/k8s"
 	utils "github.com/konstructio/kubefirst-api/pkg/utils"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -42,6 +43,9 @@ func mkCert(cmd *cobra.Command, _ []string) error {
 		viper.GetString(fmt.Sprintf("flags.%s-owner", flags.GitProvider)),
 		flags.GitProtocol,
 	)
+	if err := contexts.RebaseClusterPaths(config); err != nil {
+		return fmt.Errorf("failed to locate the cluster directory: %w", err)
+	}
 	kcfg := k8s.CreateKubeConfig(false, config.Kubeconfig)
 
 	log.Infof("Generating certificate for %s.%s...", appNameFlag, k3d.DomainName)
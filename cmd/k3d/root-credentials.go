@@ -13,6 +13,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/credentials"
 	"github.com/konstructio/kubefirst-api/pkg/k3d"
 	"github.com/konstructio/kubefirst-api/pkg/k8s"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -57,6 +58,9 @@ func getK3dRootCredentials(cmd *cobra.Command, _ []string) error {
 
 	// Instantiate kubernetes client
 	config := k3d.GetConfig(clusterName, gitProvider, gitOwner, gitProtocol)
+	if err := contexts.RebaseClusterPaths(config); err != nil {
+		return fmt.Errorf("failed to locate the cluster directory: %w", err)
+	}
 	kcfg := k8s.CreateKubeConfig(false, config.Kubeconfig)
 
 	err = credentials.ParseAuthData(kcfg.Clientset, k3d.CloudProvider, gitProvider, domainName, &opts)
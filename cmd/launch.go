@@ -8,13 +8,35 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/konstructio/kubefirst/internal/launch"
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/slices"
 )
 
-// additionalHelmFlags can optionally pass user-supplied flags to helm
-var additionalHelmFlags []string
+var (
+	// additionalHelmFlags can optionally pass user-supplied flags to helm
+	additionalHelmFlags []string
+
+	// outputFlag selects the format used to print cluster records
+	outputFlag string
+
+	// assumeYesFlag skips confirmation prompts
+	assumeYesFlag bool
+
+	// installKubefirstProFlag overrides whether a retried cluster installs Kubefirst Pro
+	installKubefirstProFlag bool
+
+	// waitFlag follows an operation until the console reports its outcome, bounded by timeoutFlag
+	waitFlag    bool
+	timeoutFlag time.Duration
+
+	// mockFlag serves an in-memory console API instead of launching the console
+	mockFlag             bool
+	mockAddressFlag      string
+	mockStepIntervalFlag time.Duration
+)
 
 func LaunchCommand() *cobra.Command {
 	launchCommand := &cobra.Command{
@@ -35,12 +57,20 @@ func launchUp() *cobra.Command {
 		Use:              "up",
 		Short:            "launch new console and api instance",
 		TraverseChildren: true,
-		Run: func(_ *cobra.Command, _ []string) {
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if mockFlag {
+				return launch.UpMock(cmd.Context(), mockAddressFlag, mockStepIntervalFlag)
+			}
+
 			launch.Up(additionalHelmFlags, false, true)
+			return nil
 		},
 	}
 
 	launchUpCmd.Flags().StringSliceVar(&additionalHelmFlags, "helm-flag", []string{}, "additional helm flag to pass to the launch up command - can be used any number of times")
+	launchUpCmd.Flags().BoolVar(&mockFlag, "mock", false, "serve an in-memory console API for demos and offline development instead of launching the console")
+	launchUpCmd.Flags().StringVar(&mockAddressFlag, "mock-address", launch.DefaultMockAddress, "address the mock console listens on")
+	launchUpCmd.Flags().DurationVar(&mockStepIntervalFlag, "mock-step-interval", 5*time.Second, "time the mock console takes to complete each provisioning step")
 
 	return launchUpCmd
 }
@@ -67,7 +97,7 @@ func launchCluster() *cobra.Command {
 		TraverseChildren: true,
 	}
 
-	launchClusterCmd.AddCommand(launchListClusters(), launchDeleteCluster())
+	launchClusterCmd.AddCommand(launchListClusters(), launchGetCluster(), launchDescribeCluster(), launchSummarizeCluster(), launchRetryCluster(), launchDeleteCluster(), launchClusterUI())
 
 	return launchClusterCmd
 }
@@ -78,30 +108,136 @@ func launchListClusters() *cobra.Command {
 		Use:              "list",
 		Short:            "list clusters created by the Kubefirst console",
 		TraverseChildren: true,
-		Run: func(_ *cobra.Command, _ []string) {
-			launch.ListClusters()
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return launch.ListClusters(cmd.Context())
 		},
 	}
 
 	return launchListClustersCmd
 }
 
+// launchGetCluster makes a request to the console API to print a single cluster
+func launchGetCluster() *cobra.Command {
+	launchGetClusterCmd := &cobra.Command{
+		Use:              "get <name>",
+		Short:            "get a cluster created by the Kubefirst console",
+		TraverseChildren: true,
+		Args:             clusterNameArg,
+		PreRunE:          validateOutputFlag,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return launch.GetCluster(cmd.Context(), args[0], outputFlag)
+		},
+	}
+
+	launchGetClusterCmd.Flags().StringVarP(&outputFlag, "output", "o", launch.OutputTable, fmt.Sprintf("Output format - one of: %s", launch.SupportedOutputFormats))
+
+	return launchGetClusterCmd
+}
+
+// launchDescribeCluster makes a request to the console API to print every detail of a single cluster
+func launchDescribeCluster() *cobra.Command {
+	launchDescribeClusterCmd := &cobra.Command{
+		Use:              "describe <name>",
+		Short:            "describe a cluster created by the Kubefirst console, including its provisioning checks",
+		TraverseChildren: true,
+		Args:             clusterNameArg,
+		PreRunE:          validateOutputFlag,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return launch.DescribeCluster(cmd.Context(), args[0], outputFlag)
+		},
+	}
+
+	launchDescribeClusterCmd.Flags().StringVarP(&outputFlag, "output", "o", launch.OutputTable, fmt.Sprintf("Output format - one of: %s", launch.SupportedOutputFormats))
+
+	return launchDescribeClusterCmd
+}
+
+// launchSummarizeCluster regenerates the summary report of a provisioned cluster
+func launchSummarizeCluster() *cobra.Command {
+	launchSummarizeClusterCmd := &cobra.Command{
+		Use:              "summary <name>",
+		Short:            "write the summary of a cluster created by the Kubefirst console to ~/.k1/<name>/summary.md and summary.json",
+		TraverseChildren: true,
+		Args:             clusterNameArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return launch.SummarizeCluster(cmd.Context(), args[0])
+		},
+	}
+
+	return launchSummarizeClusterCmd
+}
+
+// clusterNameArg requires a cluster name as the only argument
+func clusterNameArg(cmd *cobra.Command, args []string) error {
+	if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+		return fmt.Errorf("you must provide a cluster name as the only argument to this command")
+	}
+	return nil
+}
+
+func validateOutputFlag(_ *cobra.Command, _ []string) error {
+	if !slices.Contains(launch.SupportedOutputFormats, outputFlag) {
+		return fmt.Errorf("unsupported output format %q - one of: %s", outputFlag, launch.SupportedOutputFormats)
+	}
+	return nil
+}
+
+// launchRetryCluster resets a cluster that failed to provision and follows the new attempt
+func launchRetryCluster() *cobra.Command {
+	launchRetryClusterCmd := &cobra.Command{
+		Use:              "retry <name>",
+		Short:            "retry provisioning a cluster created by the Kubefirst console that ended in error",
+		TraverseChildren: true,
+		Args:             clusterNameArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := launch.RetryOptions{AssumeYes: assumeYesFlag}
+			if cmd.Flags().Changed("install-kubefirst-pro") {
+				opts.InstallKubefirstPro = &installKubefirstProFlag
+			}
+			return launch.RetryCluster(cmd.Context(), args[0], opts)
+		},
+	}
+
+	launchRetryClusterCmd.Flags().BoolVarP(&assumeYesFlag, "yes", "y", false, "Retry without asking for confirmation")
+	launchRetryClusterCmd.Flags().BoolVar(&installKubefirstProFlag, "install-kubefirst-pro", true, "Whether to install Kubefirst Pro, required when the cluster wasn't created from this context")
+
+	return launchRetryClusterCmd
+}
+
 // launchDeleteCluster makes a request to the console API to delete a single cluster
 func launchDeleteCluster() *cobra.Command {
 	launchDeleteClusterCmd := &cobra.Command{
-		Use:              "delete",
+		Use:              "delete <name>",
 		Short:            "delete a cluster created by the Kubefirst console",
 		TraverseChildren: true,
-		Args: func(cmd *cobra.Command, args []string) error {
-			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
-				return fmt.Errorf("you must provide a cluster name as the only argument to this command")
-			}
-			return nil
-		},
-		Run: func(_ *cobra.Command, args []string) {
-			launch.DeleteCluster(args[0])
+		Args:             clusterNameArg,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return launch.DeleteCluster(cmd.Context(), args[0], launch.DeleteOptions{
+				Wait:      waitFlag,
+				Timeout:   timeoutFlag,
+				AssumeYes: assumeYesFlag,
+			})
 		},
 	}
 
+	launchDeleteClusterCmd.Flags().BoolVarP(&assumeYesFlag, "yes", "y", false, "Delete without typing the cluster name to confirm")
+	launchDeleteClusterCmd.Flags().BoolVar(&waitFlag, "wait", false, "Follow the deletion until it completes and exit non-zero if it fails")
+	launchDeleteClusterCmd.Flags().DurationVar(&timeoutFlag, "timeout", 30*time.Minute, "Maximum time to wait for the deletion with --wait, 0 waits indefinitely")
+
 	return launchDeleteClusterCmd
 }
+
+// launchClusterUI opens an interactive table of the clusters created by the console
+func launchClusterUI() *cobra.Command {
+	launchClusterUICmd := &cobra.Command{
+		Use:              "ui",
+		Short:            "browse and manage clusters created by the Kubefirst console interactively",
+		TraverseChildren: true,
+		Args:             cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return launch.ClusterUI(cmd.Context())
+		},
+	}
+
+	return launchClusterUICmd
+}
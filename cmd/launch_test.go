@@ -0,0 +1,277 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+	"github.com/konstructio/kubefirst/internal/progress"
+)
+
+// useConsole points kubefirst at a fake console whose clusters complete every
+// step as soon as they are submitted or deleted
+func useConsole(t *testing.T) *clustertest.Server {
+	t.Helper()
+
+	console := clustertest.NewServer(clustertest.Options{StepInterval: time.Nanosecond})
+	server := httptest.NewServer(console)
+	t.Cleanup(server.Close)
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("K1_CONTEXT", "")
+	t.Setenv("K1_LOCAL_DEBUG", "true")
+	t.Setenv("K1_CONSOLE_REMOTE_URL", server.URL)
+
+	// retry a failing console without waiting
+	client := cluster.NewClient(server.URL)
+	client.RetryWait = time.Millisecond
+	cluster.DefaultClient = client
+	t.Cleanup(func() { cluster.DefaultClient = nil })
+
+	return console
+}
+
+// execute runs kubefirst with args the way main does, with the progress
+// terminal reporting json events, and returns its exit code
+func execute(t *testing.T, args ...string) int {
+	t.Helper()
+
+	progress.InitializeProgressTerminal(progress.OutputJSON)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := progress.Progress.Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+			t.Errorf("progress terminal failed: %v", err)
+		}
+	}()
+
+	rootCmd.SetArgs(args)
+	exitCode := Execute()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		progress.Progress.Kill()
+		<-done
+		t.Fatalf("kubefirst %v did not quit the progress terminal", args)
+	}
+
+	return exitCode
+}
+
+func countRequests(console *clustertest.Server, method, path string) int {
+	count := 0
+	for _, req := range console.Requests() {
+		if req.Method == method && req.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func TestLaunchClusterExitCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		failure *clustertest.RequestFailure
+		want    int
+	}{
+		{
+			name: "list",
+			args: []string{"launch", "cluster", "list"},
+			want: ExitCodeSuccess,
+		},
+		{
+			name: "missing cluster",
+			args: []string{"launch", "cluster", "get", "missing"},
+			want: ExitCodeNotFound,
+		},
+		{
+			name:    "unauthenticated list",
+			args:    []string{"launch", "cluster", "list"},
+			failure: &clustertest.RequestFailure{StatusCode: http.StatusUnauthorized},
+			want:    ExitCodeUnauthorized,
+		},
+		{
+			name:    "forbidden delete",
+			args:    []string{"launch", "cluster", "delete", "demo", "--yes", "--wait=false"},
+			failure: &clustertest.RequestFailure{Method: http.MethodDelete, StatusCode: http.StatusForbidden},
+			want:    ExitCodeForbidden,
+		},
+		{
+			name:    "failing console",
+			args:    []string{"launch", "cluster", "get", "demo"},
+			failure: &clustertest.RequestFailure{StatusCode: http.StatusInternalServerError},
+			want:    ExitCodeUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			console := useConsole(t)
+			console.Seed(apiTypes.Cluster{ClusterName: "demo", CloudProvider: "civo"})
+			if tt.failure != nil {
+				console.FailRequests(*tt.failure)
+			}
+
+			if code := execute(t, tt.args...); code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestLaunchClusterDelete(t *testing.T) {
+	console := useConsole(t)
+	console.Seed(apiTypes.Cluster{ClusterName: "demo", CloudProvider: "civo"})
+
+	if code := execute(t, "launch", "cluster", "delete", "demo", "--yes", "--wait=false"); code != ExitCodeSuccess {
+		t.Errorf("exit code = %d, want %d", code, ExitCodeSuccess)
+	}
+	if got := countRequests(console, http.MethodDelete, "/cluster/demo"); got != 1 {
+		t.Errorf("cluster was deleted %d times, want once", got)
+	}
+}
+
+func TestLaunchClusterDeleteWaits(t *testing.T) {
+	console := useConsole(t)
+	console.Seed(apiTypes.Cluster{ClusterName: "demo", CloudProvider: "civo"})
+
+	if code := execute(t, "launch", "cluster", "delete", "demo", "--yes", "--wait", "--timeout=1m"); code != ExitCodeSuccess {
+		t.Errorf("exit code = %d, want %d", code, ExitCodeSuccess)
+	}
+	if _, ok := console.Cluster("demo"); ok {
+		t.Error("cluster still exists once the deletion was followed to completion")
+	}
+}
+
+func TestLaunchClusterDeleteWaitFails(t *testing.T) {
+	console := useConsole(t)
+	console.Seed(apiTypes.Cluster{ClusterName: "demo", CloudProvider: "civo"})
+	console.FailDeletion("demo", "unable to destroy the vpc")
+
+	if code := execute(t, "launch", "cluster", "delete", "demo", "--yes", "--wait", "--timeout=1m"); code != ExitCodeError {
+		t.Errorf("exit code = %d, want %d", code, ExitCodeError)
+	}
+	if cl, _ := console.Cluster("demo"); cl.Status != "error" {
+		t.Errorf("cluster = %q, want error", cl.Status)
+	}
+}
+
+// launchMock runs `launch up --mock` on a free port until the test ends and
+// points kubefirst at it
+func launchMock(t *testing.T) *cluster.Client {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	mockCmd := LaunchCommand()
+	mockCmd.SetArgs([]string{"up", "--mock", "--mock-address", address, "--mock-step-interval", "1ms"})
+	go func() { stopped <- mockCmd.ExecuteContext(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-stopped; err != nil {
+			t.Errorf("launch up --mock error = %v, want nil once stopped", err)
+		}
+	})
+
+	consoleURL := fmt.Sprintf("http://%s", address)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("K1_CONTEXT", "")
+	t.Setenv("K1_LOCAL_DEBUG", "true")
+	t.Setenv("K1_CONSOLE_REMOTE_URL", consoleURL)
+
+	client := cluster.NewClient(consoleURL)
+	client.RetryWait = 10 * time.Millisecond
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		_, err := client.GetClusters(context.Background())
+		if err == nil {
+			return client
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("launch up --mock is not serving on %s: %v", address, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLaunchUpMock(t *testing.T) {
+	client := launchMock(t)
+
+	definition := apiTypes.ClusterDefinition{
+		AdminEmail:    "admin@example.com",
+		CloudProvider: "civo",
+		ClusterName:   "demo",
+		DomainName:    "example.com",
+	}
+	if err := client.CreateCluster(context.Background(), definition); err != nil {
+		t.Fatalf("CreateCluster() error = %v", err)
+	}
+
+	if code := execute(t, "launch", "cluster", "list"); code != ExitCodeSuccess {
+		t.Errorf("launch cluster list exit code = %d, want %d", code, ExitCodeSuccess)
+	}
+	if code := execute(t, "launch", "cluster", "delete", "demo", "--yes", "--wait"); code != ExitCodeSuccess {
+		t.Errorf("launch cluster delete exit code = %d, want %d", code, ExitCodeSuccess)
+	}
+	if _, err := client.GetCluster(context.Background(), "demo"); !errors.Is(err, cluster.ErrNotFound) {
+		t.Errorf("GetCluster() of the deleted cluster error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLaunchClusterSummary(t *testing.T) {
+	console := useConsole(t)
+	console.Seed(apiTypes.Cluster{
+		ClusterName:   "demo",
+		CloudProvider: "civo",
+		DomainName:    "example.com",
+		Status:        "provisioned",
+		GitAuth:       apiTypes.GitAuth{Owner: "example-org", Token: "ghp_gitsecret"},
+	})
+	console.Seed(apiTypes.Cluster{ClusterName: "pending", CloudProvider: "civo", Status: "provisioning"})
+
+	// the summary is printed without the progress terminal
+	rootCmd.SetArgs([]string{"launch", "cluster", "summary", "demo"})
+	if code := Execute(); code != ExitCodeSuccess {
+		t.Fatalf("exit code = %d, want %d", code, ExitCodeSuccess)
+	}
+	home, _ := os.UserHomeDir()
+	summary, err := os.ReadFile(filepath.Join(home, ".k1", "demo", "summary.json"))
+	if err != nil {
+		t.Fatalf("summary was not written: %v", err)
+	}
+	if !strings.Contains(string(summary), "https://kubefirst.example.com") || strings.Contains(string(summary), "ghp_gitsecret") {
+		t.Errorf("summary = %s, want the console URL and no git token", summary)
+	}
+
+	rootCmd.SetArgs([]string{"launch", "cluster", "summary", "pending"})
+	if code := Execute(); code != ExitCodeError {
+		t.Errorf("exit code of a cluster still provisioning = %d, want %d", code, ExitCodeError)
+	}
+}
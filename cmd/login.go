@@ -0,0 +1,96 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var consoleURLFlag string
+
+// loginCmd stores credentials used to authenticate against a console API
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "store credentials for a Kubefirst console",
+	Long: `Store a bearer token or API key for a Kubefirst console. The console becomes
+the active console and every request made to it carries the stored credentials.
+
+Credentials are read from the ` + cluster.EnvConsoleToken + ` or ` + cluster.EnvConsoleAPIKey + ` environment
+variables, from stdin when it is not a terminal, or prompted for.`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		consoleURL, err := cluster.LoginConsoleURL(consoleURLFlag)
+		if err != nil {
+			return err
+		}
+
+		creds := cluster.ConsoleCredentials{
+			Token:  os.Getenv(cluster.EnvConsoleToken),
+			APIKey: os.Getenv(cluster.EnvConsoleAPIKey),
+		}
+
+		if creds.Token == "" && creds.APIKey == "" {
+			token, err := readToken(cmd.InOrStdin(), consoleURL)
+			if err != nil {
+				return fmt.Errorf("unable to read token: %w", err)
+			}
+			creds.Token = token
+		}
+
+		if creds.Token == "" && creds.APIKey == "" {
+			return errors.New("no credentials provided")
+		}
+
+		// verify the credentials before storing them
+		client := cluster.NewClient(consoleURL)
+		client.Token = creds.Token
+		client.APIKey = creds.APIKey
+		if _, err := client.GetClusters(cmd.Context()); err != nil {
+			return fmt.Errorf("unable to authenticate against %s: %w", consoleURL, err)
+		}
+
+		if err := cluster.SaveCredentials(consoleURL, creds); err != nil {
+			return fmt.Errorf("unable to store console credentials: %w", err)
+		}
+
+		fmt.Printf("Logged in to %s\n", consoleURL)
+		return nil
+	},
+}
+
+// readToken prompts for a token without echoing it, or reads it from in when
+// it is piped
+func readToken(in io.Reader, consoleURL string) (string, error) {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Printf("Bearer token for %s: ", consoleURL)
+		token, err := term.ReadPassword(int(f.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+
+	token, err := io.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func init() {
+	loginCmd.Flags().StringVar(&consoleURLFlag, "console-url", "", "The Kubefirst console URL to authenticate against (defaults to the active console)")
+
+	rootCmd.AddCommand(loginCmd)
+}
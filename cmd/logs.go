@@ -7,44 +7,201 @@ See the LICENSE file for more details.
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
 
-	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/dustin/go-humanize"
 	"github.com/konstructio/kubefirst/internal/provisionLogs"
-	"github.com/nxadm/tail"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+var (
+	logsLevelFlag  string
+	logsSinceFlag  time.Duration
+	logsGrepFlag   string
+	logsFileFlag   string
+	logsSourceFlag string
+
+	logsOlderThanFlag time.Duration
+	logsKeepFlag      int
+	logsDryRunFlag    bool
 )
 
 // infoCmd represents the info command
 var logsCmd = &cobra.Command{
 	Use:   "logs",
 	Short: "kubefirst real time logs",
-	Long:  `kubefirst real time logs`,
+	Long: `kubefirst real time logs
+
+Follows the log of the last kubefirst command in a scrollable, searchable viewer.
+When the output is not a terminal the matching lines are printed instead.
+
+With --source, the logs of the kubefirst-api (api) or console (console) pods of the
+local console cluster are followed too, merged in time order with the CLI log.
+
+Use "kubefirst logs list" to see the log files and "kubefirst logs clean" to delete old ones.`,
 	RunE: func(_ *cobra.Command, _ []string) error {
-		provisionLogs.InitializeProvisionLogsTerminal()
+		filter, err := provisionLogs.NewFilter(logsLevelFlag, logsSinceFlag, logsGrepFlag)
+		if err != nil {
+			return fmt.Errorf("invalid logs filter: %w", err)
+		}
 
-		go func() {
-			t, err := tail.TailFile(viper.GetString("k1-paths.log-file"), tail.Config{Follow: true, ReOpen: true})
-			if err != nil {
-				fmt.Printf("Error tailing log file: %v\n", err)
-				progress.Progress.Quit()
-				return
-			}
+		if err := provisionLogs.ValidateSource(logsSourceFlag); err != nil {
+			return fmt.Errorf("invalid logs source: %w", err)
+		}
 
-			for line := range t.Lines {
-				provisionLogs.AddLog(line.Text)
-			}
-		}()
+		logFile, err := logFilePath(logsFileFlag)
+		if err != nil {
+			return err
+		}
 
-		if _, err := provisionLogs.ProvisionLogs.Run(); err != nil {
-			return fmt.Errorf("failed to run provision logs: %w", err)
+		if !term.IsTerminal(int(os.Stdout.Fd())) {
+			if err := provisionLogs.Print(os.Stdout, logFile, logsSourceFlag, filter); err != nil {
+				return fmt.Errorf("unable to print logs: %w", err)
+			}
+			return nil
 		}
 
+		if err := provisionLogs.Follow(logFile, logsSourceFlag, filter); err != nil {
+			return fmt.Errorf("unable to follow logs: %w", err)
+		}
 		return nil
 	},
 }
 
+// logFilePath resolves --file in the logs directory, log_<name>.log can be
+// given as its cluster name. The log of the last command is used by default.
+func logFilePath(name string) (string, error) {
+	if name == "" {
+		return viper.GetString("k1-paths.log-file"), nil
+	}
+
+	logsDir := viper.GetString("k1-paths.logs-dir")
+	candidates := []string{name, filepath.Join(logsDir, name), filepath.Join(logsDir, fmt.Sprintf("log_%s.log", name))}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("unable to read log file %q: %w", candidate, err)
+		}
+	}
+
+	return "", fmt.Errorf("log file %q not found in %s", name, logsDir)
+}
+
+// logsList prints the log files of the logs directory
+func logsList() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list kubefirst log files",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			files, err := provisionLogs.ListFiles(viper.GetString("k1-paths.logs-dir"))
+			if err != nil {
+				return fmt.Errorf("unable to list logs: %w", err)
+			}
+			provisionLogs.ReadCommands(files)
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tSIZE\tMODIFIED\tCLUSTER\tCOMMAND")
+			for _, file := range files {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", file.Name, humanize.Bytes(uint64(file.Size)), file.Modified.Local().Format("2006-01-02 15:04:05"), orDash(file.Cluster), orDash(file.Command))
+			}
+
+			if err := tw.Flush(); err != nil {
+				return fmt.Errorf("unable to print logs: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// logsClean deletes the log files expired by --older-than and --keep
+func logsClean() *cobra.Command {
+	logsCleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "delete old kubefirst log files",
+		Long: `Delete old kubefirst log files. A file is deleted when it is older than --older-than
+or when it is not one of the --keep most recent files. Without flags, the retention of
+the logs section of the kubefirst config is used:
+
+  logs:
+    retention:
+      older-than: 720h
+      keep: 50
+
+The retention of the config is also applied every time kubefirst starts.`,
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if logsOlderThanFlag < 0 || logsKeepFlag < 0 {
+				return errors.New("--older-than and --keep can't be negative")
+			}
+
+			retention := provisionLogs.Retention{OlderThan: logsOlderThanFlag, Keep: logsKeepFlag}
+			if retention.Empty() {
+				var err error
+				retention, err = provisionLogs.CurrentRetention()
+				if err != nil {
+					return fmt.Errorf("invalid logs retention: %w", err)
+				}
+			}
+			if retention.Empty() {
+				return errors.New("nothing to clean - use --older-than or --keep, or set a logs retention in the kubefirst config")
+			}
+
+			logsDir := viper.GetString("k1-paths.logs-dir")
+			if logsDryRunFlag {
+				expired, err := provisionLogs.ExpiredFiles(logsDir, retention, viper.GetString("k1-paths.log-file"))
+				if err != nil {
+					return fmt.Errorf("unable to list logs: %w", err)
+				}
+				for _, file := range expired {
+					fmt.Printf("Would delete %s\n", file.Name)
+				}
+				return nil
+			}
+
+			deleted, err := provisionLogs.Clean(logsDir, retention, viper.GetString("k1-paths.log-file"))
+			var size int64
+			for _, file := range deleted {
+				fmt.Printf("Deleted %s\n", file.Name)
+				size += file.Size
+			}
+			fmt.Printf("Deleted %d log files, %s freed\n", len(deleted), humanize.Bytes(uint64(size)))
+			if err != nil {
+				return fmt.Errorf("unable to clean logs: %w", err)
+			}
+			return nil
+		},
+	}
+
+	logsCleanCmd.Flags().DurationVar(&logsOlderThanFlag, "older-than", 0, "Delete the log files not modified for longer than this duration, e.g. 72h")
+	logsCleanCmd.Flags().IntVar(&logsKeepFlag, "keep", 0, "Delete the log files past this number of most recent ones")
+	logsCleanCmd.Flags().BoolVar(&logsDryRunFlag, "dry-run", false, "Print the log files that would be deleted without deleting them")
+
+	return logsCleanCmd
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 func init() {
+	logsCmd.AddCommand(logsList(), logsClean())
+
+	logsCmd.Flags().StringVar(&logsLevelFlag, "level", "", fmt.Sprintf("Only show entries at this level or above - one of: %s", provisionLogs.Levels))
+	logsCmd.Flags().DurationVar(&logsSinceFlag, "since", 0, "Only show entries newer than this duration, e.g. 30m or 2h")
+	logsCmd.Flags().StringVar(&logsGrepFlag, "grep", "", "Only show entries matching this regular expression")
+	logsCmd.Flags().StringVar(&logsFileFlag, "file", "", "Log file to show from the logs directory, e.g. log_<cluster>.log or <cluster>, instead of the last one")
+	logsCmd.Flags().StringVar(&logsSourceFlag, "source", provisionLogs.SourceCLI, fmt.Sprintf("Logs to show with the CLI log - one of: %s", provisionLogs.Sources))
 	rootCmd.AddCommand(logsCmd)
 }
@@ -14,6 +14,7 @@ import (
 
 	"github.com/konstructio/kubefirst-api/pkg/progressPrinter"
 	utils "github.com/konstructio/kubefirst-api/pkg/utils"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -102,11 +103,10 @@ func runReset() error {
 
 	log.Info().Msg("removing previous platform content")
 
-	homePath, err := os.UserHomeDir()
+	k1Dir, err := contexts.CurrentK1Dir()
 	if err != nil {
-		return fmt.Errorf("unable to get user home directory: %w", err)
+		return fmt.Errorf("unable to get the active context directory: %w", err)
 	}
-	k1Dir := fmt.Sprintf("%s/.k1", homePath)
 
 	if err := utils.ResetK1Dir(k1Dir); err != nil {
 		return fmt.Errorf("error resetting k1 directory: %w", err)
@@ -114,7 +114,7 @@ func runReset() error {
 	log.Info().Msg("previous platform content removed")
 	progressPrinter.IncrementTracker("removing-platform-content", 1)
 
-	log.Info().Msg("resetting $HOME/.kubefirst config")
+	log.Info().Msgf("resetting %s config", viper.ConfigFileUsed())
 	viper.Set("argocd", "")
 	viper.Set("github", "")
 	viper.Set("gitlab", "")
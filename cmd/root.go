@@ -8,6 +8,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/konstructio/kubefirst-api/pkg/configs"
 	"github.com/konstructio/kubefirst-api/pkg/progressPrinter"
@@ -15,7 +16,10 @@ import (
 	"github.com/konstructio/kubefirst/cmd/civo"
 	"github.com/konstructio/kubefirst/cmd/digitalocean"
 	"github.com/konstructio/kubefirst/cmd/k3d"
+	"github.com/konstructio/kubefirst/internal/catalog"
+	"github.com/konstructio/kubefirst/internal/cluster"
 	"github.com/konstructio/kubefirst/internal/common"
+	"github.com/konstructio/kubefirst/internal/notify"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/spf13/cobra"
 )
@@ -29,7 +33,22 @@ var rootCmd = &cobra.Command{
 	checkout the docs at docs.kubefirst.io.`,
 	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 		// wire viper config for flags for all commands
-		return configs.InitializeViperConfig(cmd)
+		if err := configs.InitializeViperConfig(cmd); err != nil {
+			return fmt.Errorf("unable to initialize the configuration: %w", err)
+		}
+
+		// declared by the create commands and apply only
+		if webhook := cmd.Flags().Lookup("notify-webhook"); webhook != nil {
+			notify.SetWebhook(webhook.Value.String())
+		}
+		if cmd.Flags().Lookup("catalog-offline") != nil {
+			catalogOffline, err := cmd.Flags().GetBool("catalog-offline")
+			if err != nil {
+				return fmt.Errorf("unable to read the catalog-offline flag: %w", err)
+			}
+			catalog.SetOffline(catalogOffline)
+		}
+		return nil
 	},
 	Run: func(_ *cobra.Command, _ []string) {
 		fmt.Println("To learn more about kubefirst, run:")
@@ -40,18 +59,44 @@ var rootCmd = &cobra.Command{
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() {
+// The exit code for the outcome of the command is returned.
+func Execute() int {
 	// This will allow all child commands to have informUser available for free.
 	// Refers: https://github.com/konstructio/runtime/issues/525
 	// Before removing next line, please read ticket above.
 	common.CheckForVersionUpdate()
 	progressPrinter.GetInstance()
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println("Error occurred during command execution:", err)
-		fmt.Println("If a detailed error message was available, please make the necessary corrections before retrying.")
-		fmt.Println("You can re-run the last command to try the operation again.")
-		progress.Progress.Quit()
+		progress.ReportFailure(err.Error())
+
+		out := progress.MessageWriter()
+		fmt.Fprintln(out, "Error occurred during command execution:", err)
+		if remediation := cluster.Remediation(err); remediation != "" {
+			fmt.Fprintln(out, remediation)
+		} else {
+			fmt.Fprintln(out, "If a detailed error message was available, please make the necessary corrections before retrying.")
+		}
+		fmt.Fprintln(out, "You can re-run the last command to try the operation again.")
+
+		if progress.Progress != nil {
+			progress.Progress.Quit()
+		}
+		return exitCodeFor(err)
 	}
+	return ExitCodeSuccess
+}
+
+// CommandPath resolves the command args run, such as "launch cluster get", the
+// way Execute does. Flags and their values are skipped, so a flag value is
+// never mistaken for a command.
+func CommandPath(args []string) string {
+	// the help and completion commands are otherwise only added by Execute
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+
+	// the command is found even when its arguments are invalid, Execute reports those
+	command, _, _ := rootCmd.Find(args)
+	return strings.TrimPrefix(strings.TrimPrefix(command.CommandPath(), rootCmd.Name()), " ")
 }
 
 func init() {
@@ -0,0 +1,70 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/konstructio/kubefirst-api/pkg/configs"
+	"github.com/konstructio/kubefirst/internal/support"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	supportBundleFileFlag    string
+	supportBundleClusterFlag string
+)
+
+// supportBundleCmd collects what is needed to troubleshoot kubefirst in an archive
+var supportBundleCmd = &cobra.Command{
+	Use:   "support-bundle",
+	Short: "collect kubefirst diagnostics in an archive",
+	Long: `Collect kubefirst diagnostics in a tar.gz archive to attach to an issue. The archive holds
+the CLI logs, the kubefirst config and the cluster records of the console API with their
+secrets redacted, the output of kubefirst info, and the events, pod statuses, kubefirst-api
+logs and helm releases of the local console cluster. The known secrets and token formats
+are masked in the logs, review them for anything else sensitive before sharing. Its
+manifest.json lists what was collected and why anything couldn't be.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		path := supportBundleFileFlag
+		if path == "" {
+			path = fmt.Sprintf("kubefirst-support-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+
+		clusterName := supportBundleClusterFlag
+		if clusterName == "" {
+			clusterName = viper.GetString("flags.cluster-name")
+		}
+
+		manifest, err := support.WriteBundle(context.Background(), path, support.Options{
+			Info:             infoSummary(),
+			ClusterName:      clusterName,
+			KubefirstVersion: configs.K1Version,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to write support bundle: %w", err)
+		}
+
+		for _, file := range manifest.Errors() {
+			fmt.Fprintf(os.Stderr, "Skipped %s: %s\n", file.Path, file.Error)
+		}
+		fmt.Printf("Support bundle written to %s with %d files\n", path, len(manifest.Files)-len(manifest.Errors()))
+		fmt.Println("Review it before sharing: secrets are redacted from the config and cluster records, logs have the known secrets and token formats masked but may still hold other sensitive values")
+		return nil
+	},
+}
+
+func init() {
+	supportBundleCmd.Flags().StringVar(&supportBundleFileFlag, "file", "", "Path of the archive to write (defaults to kubefirst-support-<timestamp>.tar.gz in the current directory)")
+	supportBundleCmd.Flags().StringVar(&supportBundleClusterFlag, "cluster-name", "", "Cluster whose console API record is collected (defaults to the cluster of the kubefirst config, every cluster when there is none)")
+	rootCmd.AddCommand(supportBundleCmd)
+}
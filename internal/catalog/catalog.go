@@ -7,15 +7,23 @@ See the LICENSE file for more details.
 package catalog
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	git "github.com/google/go-github/v52/github"
 
 	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/contexts"
+	"github.com/konstructio/kubefirst/internal/progress"
 
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v2"
@@ -25,28 +33,46 @@ const (
 	KubefirstGitHubOrganization      = "kubefirst"
 	KubefirstGitopsCatalogRepository = "gitops-catalog"
 	basePath                         = "/"
+	indexFile                        = "index.yaml"
+
+	// indexTimeout bounds the request for the index, the cache is used past it
+	indexTimeout = 15 * time.Second
 )
 
 type GitHubClient struct {
 	Client *git.Client
 }
 
-// NewGitHub instantiates an unauthenticated GitHub client
-func NewGitHub() *git.Client {
-	return git.NewClient(nil)
+// offline is set with --catalog-offline
+var offline bool
+
+// SetOffline reads the gitops catalog from the cache only, without contacting GitHub
+func SetOffline(catalogOffline bool) {
+	offline = catalogOffline
 }
 
-func ReadActiveApplications() (apiTypes.GitopsCatalogApps, error) {
-	gh := GitHubClient{
-		Client: NewGitHub(),
+// NewGitHub instantiates a GitHub client, authenticated with GITHUB_TOKEN when
+// it is set to raise the rate limit of the API
+func NewGitHub() *git.Client {
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return git.NewClient(nil)
 	}
+	return git.NewClient(&http.Client{Transport: &tokenTransport{token: token}})
+}
 
-	activeContent, err := gh.ReadGitopsCatalogRepoContents()
-	if err != nil {
-		return apiTypes.GitopsCatalogApps{}, fmt.Errorf("error retrieving gitops catalog repository content: %w", err)
-	}
+type tokenTransport struct {
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return http.DefaultTransport.RoundTrip(req) //nolint:wrapcheck // errors are wrapped by the GitHub client
+}
 
-	index, err := gh.ReadGitopsCatalogIndex(activeContent)
+func ReadActiveApplications() (apiTypes.GitopsCatalogApps, error) {
+	index, err := readIndex(context.Background(), NewGitHub())
 	if err != nil {
 		return apiTypes.GitopsCatalogApps{}, fmt.Errorf("error retrieving gitops catalog index content: %w", err)
 	}
@@ -116,6 +142,140 @@ func ValidateCatalogApps(catalogApps string) (bool, []apiTypes.GitopsCatalogApp,
 	return true, gitopsCatalogapps, nil
 }
 
+// readIndex returns the gitops catalog index. The cached index is revalidated
+// with its ETag, and used as is when offline, when GitHub can't be reached or
+// when it rate limits the request.
+func readIndex(ctx context.Context, client *git.Client) ([]byte, error) {
+	cached, cacheErr := readCachedIndex()
+	if offline {
+		if cacheErr != nil {
+			return nil, fmt.Errorf("--catalog-offline requires a cached gitops catalog index, validate catalog apps once while online: %w", cacheErr)
+		}
+		log.Info().Msg("using the cached gitops catalog index, --catalog-offline is set")
+		return cached.content, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, indexTimeout)
+	defer cancel()
+
+	gh := GitHubClient{
+		Client: client,
+	}
+	content, etag, err := gh.ReadGitopsCatalogIndexIfModified(ctx, cached.etag)
+	if err != nil {
+		if cacheErr != nil || !unavailable(err) {
+			return nil, err
+		}
+		log.Warn().Msgf("unable to read the gitops catalog from GitHub, using the cached index: %v", err)
+		progress.AddStep("GitHub is unavailable, using the cached gitops catalog")
+		progress.CompleteStep("GitHub is unavailable, using the cached gitops catalog")
+		return cached.content, nil
+	}
+
+	if content == nil {
+		log.Info().Msg("the cached gitops catalog index is up to date")
+		return cached.content, nil
+	}
+
+	if err := writeCachedIndex(cachedIndex{content: content, etag: etag}); err != nil {
+		log.Warn().Msgf("unable to cache the gitops catalog index: %v", err)
+	}
+	return content, nil
+}
+
+// unavailable reports whether err is a network error or a rate limit of GitHub,
+// the errors the cached index is used for. The others, such as an invalid
+// GITHUB_TOKEN, are returned to the user.
+func unavailable(err error) bool {
+	var (
+		netErr      net.Error
+		rateErr     *git.RateLimitError
+		abuseErr    *git.AbuseRateLimitError
+		responseErr *git.ErrorResponse
+	)
+	switch {
+	case errors.As(err, &netErr), errors.As(err, &rateErr), errors.As(err, &abuseErr):
+		return true
+	case errors.As(err, &responseErr):
+		return responseErr.Response != nil && responseErr.Response.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// ReadGitopsCatalogIndexIfModified downloads the gitops catalog index unless
+// it still matches etag, in which case no content is returned
+func (gh *GitHubClient) ReadGitopsCatalogIndexIfModified(ctx context.Context, etag string) ([]byte, string, error) {
+	u := fmt.Sprintf("repos/%s/%s/contents/%s", KubefirstGitHubOrganization, KubefirstGitopsCatalogRepository, indexFile)
+	req, err := gh.Client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating gitops catalog index request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github.raw")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	var buf bytes.Buffer
+	res, err := gh.Client.Do(ctx, req, &buf)
+	if res != nil && res.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading %s: %w", indexFile, err)
+	}
+
+	return buf.Bytes(), res.Header.Get("ETag"), nil
+}
+
+// cachedIndex is the gitops catalog index kept in the cache directory
+type cachedIndex struct {
+	content []byte
+	etag    string
+}
+
+func cacheDir() (string, error) {
+	k1Dir, err := contexts.CurrentK1Dir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get the active context directory: %w", err)
+	}
+	return filepath.Join(k1Dir, "cache", KubefirstGitopsCatalogRepository), nil
+}
+
+func readCachedIndex() (cachedIndex, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return cachedIndex{}, err
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		return cachedIndex{}, fmt.Errorf("unable to read the cached gitops catalog index: %w", err)
+	}
+	etag, err := os.ReadFile(filepath.Join(dir, indexFile+".etag"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cachedIndex{}, fmt.Errorf("unable to read the ETag of the cached gitops catalog index: %w", err)
+	}
+	return cachedIndex{content: content, etag: strings.TrimSpace(string(etag))}, nil
+}
+
+func writeCachedIndex(index cachedIndex) error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("unable to create cache directory %q: %w", dir, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, indexFile), index.content, 0o600); err != nil {
+		return fmt.Errorf("unable to write the cached gitops catalog index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, indexFile+".etag"), []byte(index.etag), 0o600); err != nil {
+		return fmt.Errorf("unable to write the ETag of the cached gitops catalog index: %w", err)
+	}
+	return nil
+}
+
 func (gh *GitHubClient) ReadGitopsCatalogRepoContents() ([]*git.RepositoryContent, error) {
 	_, directoryContent, _, err := gh.Client.Repositories.GetContents(
 		context.Background(),
@@ -0,0 +1,232 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	git "github.com/google/go-github/v52/github"
+	"github.com/konstructio/kubefirst/internal/progress"
+)
+
+const (
+	cachedContent = "apps:\n- name: cached\n"
+	githubContent = "apps:\n- name: datadog\n"
+)
+
+// useHome gives the test an empty home directory, holding a cached index when cached is set
+func useHome(t *testing.T, cached bool) {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("K1_CONTEXT", "")
+	if cached {
+		if err := writeCachedIndex(cachedIndex{content: []byte(cachedContent), etag: `"cached"`}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// useProgress runs a progress terminal for the steps reported when the cache is used
+func useProgress(t *testing.T) {
+	t.Helper()
+
+	progress.InitializeProgressTerminal(progress.OutputJSON)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := progress.Progress.Run(); err != nil {
+			t.Errorf("progress terminal failed: %v", err)
+		}
+	}()
+	// Quit is received once the terminal runs, Kill could come before and be missed
+	t.Cleanup(func() {
+		progress.Progress.Quit()
+		<-done
+	})
+}
+
+// newGitHub returns a client of a fake GitHub API answering requests with handler
+func newGitHub(t *testing.T, handler http.HandlerFunc) *git.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := git.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	return client
+}
+
+// respond answers with status and header, or not modified when the ETag of
+// header is the one the index is revalidated with
+func respond(status int, header map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if etag := r.Header.Get("If-None-Match"); status == http.StatusOK && etag != "" && etag == header["ETag"] {
+			status = http.StatusNotModified
+		}
+		for key, value := range header {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			fmt.Fprint(w, githubContent)
+		}
+	}
+}
+
+func TestReadIndex(t *testing.T) {
+	rateLimited := map[string]string{"X-RateLimit-Limit": "60", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1714564800"}
+
+	tests := []struct {
+		name    string
+		cached  bool
+		handler http.HandlerFunc
+		want    string
+		wantErr string
+	}{
+		{name: "index modified", cached: true, handler: respond(http.StatusOK, map[string]string{"ETag": `"latest"`}), want: githubContent},
+		{name: "nothing cached", handler: respond(http.StatusOK, map[string]string{"ETag": `"latest"`}), want: githubContent},
+		{name: "index not modified", cached: true, handler: respond(http.StatusOK, map[string]string{"ETag": `"cached"`}), want: cachedContent},
+		{name: "rate limited", cached: true, handler: respond(http.StatusForbidden, rateLimited), want: cachedContent},
+		{name: "too many requests", cached: true, handler: respond(http.StatusTooManyRequests, nil), want: cachedContent},
+		{name: "invalid token", cached: true, handler: respond(http.StatusUnauthorized, nil), wantErr: "401"},
+		{name: "index not found", cached: true, handler: respond(http.StatusNotFound, nil), wantErr: "404"},
+		{name: "forbidden", cached: true, handler: respond(http.StatusForbidden, nil), wantErr: "403"},
+		{name: "rate limited without cache", handler: respond(http.StatusForbidden, rateLimited), wantErr: "rate limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHome(t, tt.cached)
+			useProgress(t)
+
+			got, err := readIndex(context.Background(), newGitHub(t, tt.handler))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readIndex() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readIndex() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readIndex() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadIndexCachesTheIndex(t *testing.T) {
+	useHome(t, true)
+
+	if _, err := readIndex(context.Background(), newGitHub(t, respond(http.StatusOK, map[string]string{"ETag": `"latest"`}))); err != nil {
+		t.Fatalf("readIndex() error = %v", err)
+	}
+
+	cached, err := readCachedIndex()
+	if err != nil {
+		t.Fatalf("readCachedIndex() error = %v", err)
+	}
+	if string(cached.content) != githubContent || cached.etag != `"latest"` {
+		t.Errorf("cached index = %q with ETag %q, want the index of GitHub", cached.content, cached.etag)
+	}
+
+	dir, _ := cacheDir()
+	if info, err := os.Stat(filepath.Join(dir, indexFile)); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("cached index mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+}
+
+func TestReadIndexOfUnreachableGitHub(t *testing.T) {
+	client := newGitHub(t, respond(http.StatusOK, nil))
+	client.BaseURL, _ = url.Parse("http://127.0.0.1:1/")
+
+	t.Run("cached", func(t *testing.T) {
+		useHome(t, true)
+		useProgress(t)
+
+		got, err := readIndex(context.Background(), client)
+		if err != nil || string(got) != cachedContent {
+			t.Errorf("readIndex() = %q, %v, want the cached index", got, err)
+		}
+	})
+
+	t.Run("nothing cached", func(t *testing.T) {
+		useHome(t, false)
+
+		if _, err := readIndex(context.Background(), client); err == nil {
+			t.Error("readIndex() error = nil, want the network error")
+		}
+	})
+}
+
+func TestReadIndexOffline(t *testing.T) {
+	SetOffline(true)
+	t.Cleanup(func() { SetOffline(false) })
+
+	var requested atomic.Bool
+	client := newGitHub(t, func(w http.ResponseWriter, r *http.Request) {
+		requested.Store(true)
+		respond(http.StatusOK, nil)(w, r)
+	})
+
+	useHome(t, true)
+	got, err := readIndex(context.Background(), client)
+	if err != nil || string(got) != cachedContent {
+		t.Errorf("readIndex() = %q, %v, want the cached index", got, err)
+	}
+	if requested.Load() {
+		t.Error("readIndex() requested GitHub with --catalog-offline")
+	}
+
+	useHome(t, false)
+	if _, err := readIndex(context.Background(), client); err == nil || !strings.Contains(err.Error(), "--catalog-offline requires a cached gitops catalog index") {
+		t.Errorf("readIndex() without cache error = %v, want the cache required", err)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	response := func(status int) *http.Response {
+		return &http.Response{StatusCode: status, Request: &http.Request{Method: http.MethodGet, URL: &url.URL{}}}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network", err: &url.Error{Op: "Get", URL: "https://api.github.com", Err: errors.New("connection refused")}, want: true},
+		{name: "timeout", err: context.DeadlineExceeded, want: true},
+		{name: "rate limit", err: &git.RateLimitError{Response: response(http.StatusForbidden)}, want: true},
+		{name: "secondary rate limit", err: &git.AbuseRateLimitError{Response: response(http.StatusForbidden)}, want: true},
+		{name: "too many requests", err: &git.ErrorResponse{Response: response(http.StatusTooManyRequests)}, want: true},
+		{name: "unauthorized", err: &git.ErrorResponse{Response: response(http.StatusUnauthorized)}, want: false},
+		{name: "not found", err: &git.ErrorResponse{Response: response(http.StatusNotFound)}, want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.Join(errors.New("error downloading index.yaml"), tt.err)
+			if got := unavailable(err); got != tt.want {
+				t.Errorf("unavailable(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+)
+
+// Check is a boolean *_check field of a cluster record. Checks are kept as sent
+// by the console so steps it adds are seen without a new release of kubefirst.
+type Check struct {
+	Name string
+	Done bool
+}
+
+// decodeCluster decodes a cluster record and its checks, in the order the console sent them
+func decodeCluster(data []byte) (apiTypes.Cluster, []Check, error) {
+	var cl apiTypes.Cluster
+	if err := json.Unmarshal(data, &cl); err != nil {
+		return cl, nil, fmt.Errorf("failed to unmarshal cluster object: %w", err)
+	}
+
+	checks, err := decodeChecks(data)
+	if err != nil {
+		return cl, nil, err
+	}
+	return cl, checks, nil
+}
+
+func decodeChecks(data []byte) ([]Check, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to read cluster object: %w", err)
+	}
+
+	checks := []Check{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cluster object: %w", err)
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("failed to read cluster object: %w", err)
+		}
+
+		name, ok := token.(string)
+		if !ok || !strings.HasSuffix(name, "_check") {
+			continue
+		}
+		var done bool
+		if err := json.Unmarshal(value, &done); err != nil {
+			continue
+		}
+		checks = append(checks, Check{Name: name, Done: done})
+	}
+
+	return checks, nil
+}
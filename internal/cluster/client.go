@@ -0,0 +1,245 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultTimeout bounds a single attempt of a console API request
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxRetries is the number of additional attempts made on 5xx or connection errors
+	DefaultMaxRetries = 3
+	// DefaultRetryWait is the initial wait between attempts, doubled after each failure
+	DefaultRetryWait = 2 * time.Second
+
+	proxyPath = "/api/proxy"
+)
+
+// Client talks to the kubefirst console API through its /api/proxy routes
+type Client struct {
+	// BaseURL is the console address, e.g. https://console.kubefirst.dev
+	BaseURL string
+	// HTTPClient is used to execute requests, http.DefaultClient is used when nil
+	HTTPClient *http.Client
+	// Timeout bounds each individual attempt, zero disables the per-request timeout
+	Timeout time.Duration
+	// MaxRetries is the number of retries on 5xx responses or connection errors,
+	// requests that are not idempotent are only retried when they were never sent
+	MaxRetries int
+	// RetryWait is the initial backoff between retries
+	RetryWait time.Duration
+	// Token is sent as a bearer token in the Authorization header when set
+	Token string
+	// APIKey is sent in the X-API-Key header when set
+	APIKey string
+}
+
+// NewClient returns a console API client for baseURL with the default timeout and retry policy
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		Timeout:    DefaultTimeout,
+		MaxRetries: DefaultMaxRetries,
+		RetryWait:  DefaultRetryWait,
+	}
+}
+
+// DefaultClient is used by the package-level helpers. When nil, a client
+// targeting GetConsoleIngressURL is created for each call.
+var DefaultClient *Client
+
+func defaultClient() *Client {
+	if DefaultClient != nil {
+		return DefaultClient
+	}
+
+	c := NewClient(GetConsoleIngressURL())
+	creds, err := LoadCredentials(c.BaseURL)
+	if err != nil {
+		log.Warn().Msgf("unable to load console credentials, continuing without authentication: %v", err)
+		return c
+	}
+	c.Token = creds.Token
+	c.APIKey = creds.APIKey
+
+	return c
+}
+
+// response is the raw result of a console API call
+type response struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+// proxyURL builds the /api/proxy address, optionally carrying the upstream path as the url query parameter
+func (c *Client) proxyURL(upstreamPath string) string {
+	if upstreamPath == "" {
+		return c.BaseURL + proxyPath
+	}
+	return fmt.Sprintf("%s%s?%s", c.BaseURL, proxyPath, url.Values{"url": {upstreamPath}}.Encode())
+}
+
+// do executes a request against the console API, retrying on connection errors
+// and 5xx responses until MaxRetries is exhausted or ctx is done. Requests that
+// are not idempotent, such as the POST creating a cluster, are only retried
+// when they never reached the console. A 5xx response that is not retried is
+// returned as an ErrUnavailable error.
+func (c *Client) do(ctx context.Context, method, target string, payload interface{}) (*response, error) {
+	var body []byte
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request object: %w", err)
+		}
+		body = b
+	}
+
+	wait := c.RetryWait
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Info().Msgf("retrying %s %s in %s (attempt %d of %d): %v", method, target, wait, attempt, c.MaxRetries, lastErr)
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("request cancelled while waiting to retry: %w", errors.Join(ctx.Err(), lastErr))
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+
+		res, err := c.attempt(ctx, method, target, body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to execute request: %w", err)
+			}
+			lastErr = err
+			if !idempotent(method) && !neverSent(err) {
+				return nil, fmt.Errorf("%w: not retrying %s as the console may have received it: %w", ErrUnavailable, method, err)
+			}
+			continue
+		}
+
+		if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+			return nil, c.newAPIError(res, method)
+		}
+
+		if res.StatusCode >= http.StatusInternalServerError {
+			lastErr = fmt.Errorf("API returned status %q: %s", res.Status, res.Body)
+			if attempt < c.MaxRetries && idempotent(method) {
+				continue
+			}
+
+			// the console kept failing, whatever the status it failed with
+			apiErr := c.newAPIError(res, method)
+			apiErr.Kind = ErrUnavailable
+			return nil, apiErr
+		}
+
+		return res, nil
+	}
+
+	return nil, fmt.Errorf("%w: giving up after %d attempts: %w", ErrUnavailable, c.MaxRetries+1, lastErr)
+}
+
+// idempotent reports whether sending a request with method twice has the same effect as sending it once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// neverSent reports whether err happened before the request reached the
+// console, when the console could not be resolved or connected to
+func neverSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// newRequest builds a console API request carrying the JSON and authentication headers
+func (c *Client) newRequest(ctx context.Context, method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Add("Authorization", "Bearer "+c.Token)
+	}
+	if c.APIKey != "" {
+		req.Header.Add("X-API-Key", c.APIKey)
+	}
+
+	return req, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// attempt performs a single request bounded by the client's per-request timeout
+func (c *Client) attempt(ctx context.Context, method, target string, body []byte) (*response, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := c.newRequest(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		log.Printf("error executing request: %v", err)
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Printf("unable to read response body: %v", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return &response{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+		Body:       resBody,
+	}, nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+)
+
+// newTestClient returns a client of handler that retries without waiting
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL)
+	client.Timeout = 5 * time.Second
+	client.RetryWait = time.Millisecond
+	return client
+}
+
+func testDefinition(name string) apiTypes.ClusterDefinition {
+	return apiTypes.ClusterDefinition{
+		AdminEmail:    "admin@example.com",
+		CloudProvider: "civo",
+		ClusterName:   name,
+		DomainName:    "example.com",
+	}
+}
+
+func countRequests(console *clustertest.Server, method, path string) int {
+	count := 0
+	for _, req := range console.Requests() {
+		if req.Method == method && req.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.Seed(apiTypes.Cluster{ClusterName: "demo"})
+	console.FailRequests(clustertest.RequestFailure{Method: http.MethodGet, Path: "/cluster/demo", StatusCode: http.StatusServiceUnavailable, Count: 2})
+	client := newTestClient(t, console)
+
+	cl, err := client.GetCluster(context.Background(), "demo")
+	if err != nil {
+		t.Fatalf("GetCluster() error = %v, want the third attempt to succeed", err)
+	}
+	if cl.Status != "provisioned" {
+		t.Errorf("GetCluster() status = %q, want %q", cl.Status, "provisioned")
+	}
+	if got := countRequests(console, http.MethodGet, "/cluster/demo"); got != 3 {
+		t.Errorf("console received %d requests, want 3", got)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.FailRequests(clustertest.RequestFailure{StatusCode: http.StatusBadGateway})
+	client := newTestClient(t, console)
+	client.MaxRetries = 2
+
+	_, err := client.GetClusters(context.Background())
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("GetClusters() error = %v, want ErrUnavailable", err)
+	}
+	if got := countRequests(console, http.MethodGet, "/cluster"); got != 3 {
+		t.Errorf("console received %d requests, want 3", got)
+	}
+}
+
+func TestClientRetriesConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := NewClient(server.URL)
+	client.RetryWait = time.Millisecond
+	client.MaxRetries = 1
+
+	// nothing listens on the address, the create never reached a console
+	err := client.CreateCluster(context.Background(), testDefinition("demo"))
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("CreateCluster() error = %v, want ErrUnavailable", err)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		t.Errorf("CreateCluster() error = %v, want a connection error", err)
+	}
+}
+
+func TestClientDoesNotResendCreates(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+	}{
+		{
+			name:    "server error",
+			handler: func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+		},
+		{
+			name: "response lost",
+			handler: func(w http.ResponseWriter) {
+				conn, _, err := w.(http.Hijacker).Hijack()
+				if err == nil {
+					conn.Close()
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var creates atomic.Int32
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				creates.Add(1)
+				tt.handler(w)
+			}))
+
+			if err := client.CreateCluster(context.Background(), testDefinition("demo")); err == nil {
+				t.Fatal("CreateCluster() error = nil, want the failure")
+			}
+			if got := creates.Load(); got != 1 {
+				t.Errorf("the create was sent %d times, want once", got)
+			}
+		})
+	}
+}
+
+func TestClientTimesOutHungRequests(t *testing.T) {
+	release := make(chan struct{})
+	client := newTestClient(t, http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() { close(release) })
+	client.Timeout = 50 * time.Millisecond
+	client.MaxRetries = 0
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.GetCluster(context.Background(), "demo")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrUnavailable) {
+			t.Errorf("GetCluster() error = %v, want ErrUnavailable", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetCluster() did not time out")
+	}
+}
+
+func TestClientStopsRetryingWithContext(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.FailRequests(clustertest.RequestFailure{StatusCode: http.StatusServiceUnavailable})
+	client := newTestClient(t, console)
+	client.RetryWait = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetClusters(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetClusters() error = %v, want the context deadline", err)
+	}
+	if got := len(console.Requests()); got != 1 {
+		t.Errorf("console received %d requests, want 1", got)
+	}
+}
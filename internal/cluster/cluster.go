@@ -7,10 +7,9 @@ See the LICENSE file for more details.
 package cluster
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"strings"
@@ -18,6 +17,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/types"
 )
 
@@ -26,213 +26,192 @@ func GetConsoleIngressURL() string {
 		return os.Getenv("K1_CONSOLE_REMOTE_URL")
 	}
 
+	// use the console of the active context
+	if activeContext, err := contexts.Current(); err == nil && activeContext.ConsoleURL != "" {
+		return activeContext.ConsoleURL
+	}
+
+	// use the console selected with `kubefirst login`
+	if current := currentConsoleURL(); current != "" {
+		return current
+	}
+
 	return "https://console.kubefirst.dev"
 }
 
-func CreateCluster(cluster apiTypes.ClusterDefinition) error {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
+// CreateCluster submits a cluster definition to the console API for provisioning
+func (c *Client) CreateCluster(ctx context.Context, cluster apiTypes.ClusterDefinition) error {
+	registerSecrets(definitionSecrets(&cluster))
 
 	requestObject := types.ProxyCreateClusterRequest{
 		Body: cluster,
 		URL:  fmt.Sprintf("/cluster/%s", cluster.ClusterName),
 	}
 
-	payload, err := json.Marshal(requestObject)
+	res, err := c.do(ctx, http.MethodPost, c.proxyURL(""), requestObject)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request object: %w", err)
+		log.Printf("unable to create cluster: %v", err)
+		return fmt.Errorf("unable to create cluster: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/proxy", GetConsoleIngressURL()), bytes.NewReader(payload))
-	if err != nil {
-		log.Printf("error creating request: %s", err)
-		return fmt.Errorf("failed to create request: %w", err)
+	if res.StatusCode != http.StatusAccepted {
+		log.Printf("unable to create cluster: %q %q", res.Status, res.Body)
+		return fmt.Errorf("unable to create cluster: %w", c.newAPIError(res, http.MethodPost))
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
 
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("error executing request: %s", err)
-		return fmt.Errorf("failed to execute request: %w", err)
+	log.Printf("Created cluster: %q", string(res.Body))
+
+	return nil
+}
+
+// CreateWorkloadCluster submits a workload cluster to be provisioned by the
+// management cluster named parentName
+func (c *Client) CreateWorkloadCluster(ctx context.Context, parentName string, workload apiTypes.WorkloadCluster) error {
+	registerSecrets(gitAuthSecrets(&workload.GitAuth))
+
+	requestObject := types.ProxyCreateWorkloadClusterRequest{
+		Body: workload,
+		URL:  fmt.Sprintf("/cluster/%s/workload", parentName),
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
+	res, err := c.do(ctx, http.MethodPost, c.proxyURL(""), requestObject)
 	if err != nil {
-		log.Printf("unable to create cluster: %v", err)
-		return fmt.Errorf("failed to read response body: %w", err)
+		log.Printf("unable to create workload cluster: %v", err)
+		return fmt.Errorf("unable to create workload cluster: %w", err)
 	}
 
-	if res.StatusCode != http.StatusAccepted {
-		log.Printf("unable to create cluster: %q %q", res.Status, body)
-		return fmt.Errorf("unable to create cluster: API returned unexpected status code %q: %s", res.Status, body)
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		// continue with the rest
+	default:
+		log.Printf("unable to create workload cluster: %q %q", res.Status, res.Body)
+		return fmt.Errorf("unable to create workload cluster in %q: %w", parentName, c.newAPIError(res, http.MethodPost))
 	}
 
-	log.Printf("Created cluster: %q", string(body))
+	log.Printf("Created workload cluster: %q", string(res.Body))
 
 	return nil
 }
 
-func ResetClusterProgress(clusterName string) error {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
+// ResetClusterProgress clears the provisioning checks of a cluster so it can be re-submitted
+func (c *Client) ResetClusterProgress(ctx context.Context, clusterName string) error {
 	requestObject := types.ProxyResetClusterRequest{
 		URL: fmt.Sprintf("/cluster/%s/reset_progress", clusterName),
 	}
 
-	payload, err := json.Marshal(requestObject)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request object: %w", err)
-	}
-
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/proxy", GetConsoleIngressURL()), bytes.NewReader(payload))
-	if err != nil {
-		log.Printf("error creating request: %v", err)
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
-
-	res, err := httpClient.Do(req)
+	res, err := c.do(ctx, http.MethodPost, c.proxyURL(""), requestObject)
 	if err != nil {
-		log.Printf("error executing request: %v", err)
-		return fmt.Errorf("failed to execute request: %w", err)
+		log.Printf("unable to reset cluster progress: %v", err)
+		return fmt.Errorf("unable to reset cluster progress: %w", err)
 	}
-	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
 		log.Printf("unable to reset cluster progress: %q", res.Status)
-		return fmt.Errorf("unable to reset cluster progress: API returned unexpected status %q", res.Status)
+		return fmt.Errorf("unable to reset cluster progress: %w", c.newAPIError(res, http.MethodPost))
 	}
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Printf("unable to read response body: %v", err)
-		return fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	log.Info().Msgf("Import: %s", string(body))
+	log.Info().Msgf("Import: %s", string(res.Body))
 	return nil
 }
 
-var ErrNotFound = fmt.Errorf("cluster not found")
-
-func GetCluster(clusterName string) (apiTypes.Cluster, error) {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
-	cluster := apiTypes.Cluster{}
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/proxy?url=/cluster/%s", GetConsoleIngressURL(), clusterName), nil)
-	if err != nil {
-		log.Printf("error creating request: %v", err)
-		return cluster, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
+// GetCluster returns a single cluster record, or ErrNotFound when it does not exist
+func (c *Client) GetCluster(ctx context.Context, clusterName string) (apiTypes.Cluster, error) {
+	cluster, _, err := c.getCluster(ctx, clusterName)
+	return cluster, err
+}
 
-	res, err := httpClient.Do(req)
+// getCluster returns a single cluster record along with the checks the console reported
+func (c *Client) getCluster(ctx context.Context, clusterName string) (apiTypes.Cluster, []Check, error) {
+	res, err := c.do(ctx, http.MethodGet, c.proxyURL(fmt.Sprintf("/cluster/%s", clusterName)), nil)
 	if err != nil {
-		log.Printf("error executing request: %v", err)
-		return cluster, fmt.Errorf("failed to execute request: %w", err)
+		log.Printf("unable to get cluster: %v", err)
+		return apiTypes.Cluster{}, nil, fmt.Errorf("unable to get cluster: %w", err)
 	}
-	defer res.Body.Close()
 
-	switch res.StatusCode {
-	case http.StatusNotFound:
-		return cluster, ErrNotFound
-	case http.StatusOK:
-		// continue with the rest
-	default:
+	if res.StatusCode != http.StatusOK {
 		log.Printf("unable to get cluster: %q", res.Status)
-		return cluster, fmt.Errorf("unable to get cluster: %q", res.Status)
+		return apiTypes.Cluster{}, nil, c.newAPIError(res, http.MethodGet)
 	}
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Printf("unable to read response body: %v", err)
-		return cluster, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	err = json.Unmarshal(body, &cluster)
+	cluster, checks, err := decodeCluster(res.Body)
 	if err != nil {
 		log.Printf("unable to unmarshal cluster object: %v", err)
-		return cluster, fmt.Errorf("failed to unmarshal cluster object: %w", err)
+		return cluster, nil, err
 	}
+	registerSecrets(clusterSecrets(&cluster))
 
-	return cluster, nil
+	return cluster, checks, nil
 }
 
-func GetClusters() ([]apiTypes.Cluster, error) {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
+// GetClusters returns every cluster known to the console API
+func (c *Client) GetClusters(ctx context.Context) ([]apiTypes.Cluster, error) {
 	clusters := []apiTypes.Cluster{}
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/proxy?url=/cluster", GetConsoleIngressURL()), nil)
-	if err != nil {
-		log.Printf("error creating request: %v", err)
-		return clusters, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
 
-	res, err := httpClient.Do(req)
+	res, err := c.do(ctx, http.MethodGet, c.proxyURL("/cluster"), nil)
 	if err != nil {
-		log.Printf("error executing request: %v", err)
-		return clusters, fmt.Errorf("failed to execute request: %w", err)
+		log.Printf("unable to get clusters: %v", err)
+		return clusters, fmt.Errorf("unable to get clusters: %w", err)
 	}
-	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
 		log.Printf("unable to get clusters: %q", res.Status)
-		return clusters, fmt.Errorf("unable to get clusters: API returned unexpected status code %q", res.Status)
-	}
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		log.Printf("unable to read response body: %v", err)
-		return clusters, fmt.Errorf("failed to read response body: %w", err)
+		return clusters, fmt.Errorf("unable to get clusters: %w", c.newAPIError(res, http.MethodGet))
 	}
 
-	err = json.Unmarshal(body, &clusters)
+	err = json.Unmarshal(res.Body, &clusters)
 	if err != nil {
 		log.Printf("unable to unmarshal clusters object: %v", err)
 		return clusters, fmt.Errorf("failed to unmarshal clusters object: %w", err)
 	}
+	for i := range clusters {
+		registerSecrets(clusterSecrets(&clusters[i]))
+	}
 
 	return clusters, nil
 }
 
-func DeleteCluster(clusterName string) error {
-	customTransport := http.DefaultTransport.(*http.Transport).Clone()
-	httpClient := http.Client{Transport: customTransport}
-
-	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/api/proxy?url=/cluster/%s", GetConsoleIngressURL(), clusterName), nil)
+// DeleteCluster requests deprovisioning of a single cluster
+func (c *Client) DeleteCluster(ctx context.Context, clusterName string) error {
+	res, err := c.do(ctx, http.MethodDelete, c.proxyURL(fmt.Sprintf("/cluster/%s", clusterName)), nil)
 	if err != nil {
-		log.Printf("error creating request: %v", err)
-		return fmt.Errorf("failed to create request: %w", err)
+		log.Printf("unable to delete cluster: %v", err)
+		return fmt.Errorf("unable to delete cluster: %w", err)
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("Accept", "application/json")
-
-	res, err := httpClient.Do(req)
-	if err != nil {
-		log.Printf("error executing request: %v", err)
-		return fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
 		log.Printf("unable to delete cluster: %q, continuing", res.Status)
-		return fmt.Errorf("unable to delete cluster: API returned unexpected status code %q", res.Status)
-	}
-
-	_, err = io.ReadAll(res.Body)
-	if err != nil {
-		log.Printf("unable to read response body: %v", err)
-		return fmt.Errorf("failed to read response body: %w", err)
+		return fmt.Errorf("unable to delete cluster: %w", c.newAPIError(res, http.MethodDelete))
 	}
 
 	return nil
 }
+
+// CreateCluster submits a cluster definition using the default client
+func CreateCluster(ctx context.Context, cluster apiTypes.ClusterDefinition) error {
+	return defaultClient().CreateCluster(ctx, cluster)
+}
+
+// CreateWorkloadCluster submits a workload cluster using the default client
+func CreateWorkloadCluster(ctx context.Context, parentName string, workload apiTypes.WorkloadCluster) error {
+	return defaultClient().CreateWorkloadCluster(ctx, parentName, workload)
+}
+
+// ResetClusterProgress resets cluster progress using the default client
+func ResetClusterProgress(ctx context.Context, clusterName string) error {
+	return defaultClient().ResetClusterProgress(ctx, clusterName)
+}
+
+// GetCluster returns a single cluster using the default client
+func GetCluster(ctx context.Context, clusterName string) (apiTypes.Cluster, error) {
+	return defaultClient().GetCluster(ctx, clusterName)
+}
+
+// GetClusters returns every cluster using the default client
+func GetClusters(ctx context.Context) ([]apiTypes.Cluster, error) {
+	return defaultClient().GetClusters(ctx)
+}
+
+// DeleteCluster requests cluster deletion using the default client
+func DeleteCluster(ctx context.Context, clusterName string) error {
+	return defaultClient().DeleteCluster(ctx, clusterName)
+}
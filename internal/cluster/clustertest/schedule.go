@@ -0,0 +1,72 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package clustertest
+
+import (
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+)
+
+// Step is a provisioning check the fake console completes, in order, while a
+// cluster is provisioning
+type Step struct {
+	Name     string
+	Complete func(cl *apiTypes.Cluster)
+}
+
+// DefaultSteps completes the checks in the order the console API reports them
+var DefaultSteps = []Step{
+	{Name: "install-tools", Complete: func(cl *apiTypes.Cluster) { cl.InstallToolsCheck = true }},
+	{Name: "domain-liveness", Complete: func(cl *apiTypes.Cluster) { cl.DomainLivenessCheck = true }},
+	{Name: "kbot-setup", Complete: func(cl *apiTypes.Cluster) { cl.KbotSetupCheck = true }},
+	{Name: "git-init", Complete: func(cl *apiTypes.Cluster) { cl.GitInitCheck = true }},
+	{Name: "gitops-ready", Complete: func(cl *apiTypes.Cluster) { cl.GitopsReadyCheck = true }},
+	{Name: "git-terraform-apply", Complete: func(cl *apiTypes.Cluster) { cl.GitTerraformApplyCheck = true }},
+	{Name: "gitops-pushed", Complete: func(cl *apiTypes.Cluster) { cl.GitopsPushedCheck = true }},
+	{Name: "cloud-terraform-apply", Complete: func(cl *apiTypes.Cluster) { cl.CloudTerraformApplyCheck = true }},
+	{Name: "cluster-secrets-created", Complete: func(cl *apiTypes.Cluster) { cl.ClusterSecretsCreatedCheck = true }},
+	{Name: "argocd-install", Complete: func(cl *apiTypes.Cluster) { cl.ArgoCDInstallCheck = true }},
+	{Name: "argocd-initialize", Complete: func(cl *apiTypes.Cluster) { cl.ArgoCDInitializeCheck = true }},
+	{Name: "vault-initialized", Complete: func(cl *apiTypes.Cluster) { cl.VaultInitializedCheck = true }},
+	{Name: "vault-terraform-apply", Complete: func(cl *apiTypes.Cluster) { cl.VaultTerraformApplyCheck = true }},
+	{Name: "users-terraform-apply", Complete: func(cl *apiTypes.Cluster) { cl.UsersTerraformApplyCheck = true }},
+}
+
+// deleteSteps clear the checks of the infrastructure the console destroys, in
+// order, before it forgets the cluster
+var deleteSteps = []func(cl *apiTypes.Cluster){
+	func(cl *apiTypes.Cluster) { cl.CloudTerraformApplyCheck = false },
+	func(cl *apiTypes.Cluster) { cl.GitTerraformApplyCheck = false },
+}
+
+// clusterFromDefinition builds the record the console stores when a cluster is submitted
+func clusterFromDefinition(def apiTypes.ClusterDefinition) apiTypes.Cluster {
+	clusterType := def.Type
+	if clusterType == "" {
+		clusterType = "mgmt"
+	}
+
+	return apiTypes.Cluster{
+		AlertsEmail:            def.AdminEmail,
+		CloudProvider:          def.CloudProvider,
+		CloudRegion:            def.CloudRegion,
+		ClusterName:            def.ClusterName,
+		ClusterType:            clusterType,
+		DomainName:             def.DomainName,
+		SubdomainName:          def.SubdomainName,
+		DnsProvider:            def.DnsProvider,
+		PostInstallCatalogApps: def.PostInstallCatalogApps,
+		GitopsTemplateURL:      def.GitopsTemplateURL,
+		GitopsTemplateBranch:   def.GitopsTemplateBranch,
+		GitProvider:            def.GitProvider,
+		GitProtocol:            def.GitProtocol,
+		GitAuth:                apiTypes.GitAuth{Owner: def.GitAuth.Owner, User: def.GitAuth.User},
+		NodeType:               def.NodeType,
+		NodeCount:              def.NodeCount,
+		ECR:                    def.ECR,
+		LogFileName:            def.LogFileName,
+	}
+}
@@ -0,0 +1,583 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+
+// Package clustertest provides an in-memory console API. It serves the
+// /api/proxy and /api/proxyHealth routes used by the cluster package, moves
+// submitted clusters through their provisioning checks on a schedule and can
+// be told to fail requests or provisioning, so commands can be exercised
+// end to end without a console.
+package clustertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	proxyPath       = "/api/proxy"
+	proxyHealthPath = "/api/proxyHealth"
+)
+
+// Options configure a Server
+type Options struct {
+	// StepInterval is the time a provisioning step takes. When zero, clusters
+	// only move forward when Advance is called.
+	StepInterval time.Duration
+	// Steps are completed in order while a cluster provisions, DefaultSteps when nil
+	Steps []Step
+	// Clock returns the current time, time.Now when nil
+	Clock func() time.Time
+}
+
+// RequestFailure makes matching requests fail with StatusCode
+type RequestFailure struct {
+	// Method matches the HTTP method, empty matches every method
+	Method string
+	// Path matches the upstream path of /api/proxy requests, e.g. /cluster/kubefirst,
+	// or /api/proxyHealth. Empty matches every path.
+	Path string
+	// StatusCode is returned instead of the regular response
+	StatusCode int
+	// Body is returned with the failure, a JSON error naming the status when empty
+	Body string
+	// Count is the number of requests to fail, zero fails every matching request
+	Count int
+}
+
+// Request is a request received by the Server
+type Request struct {
+	Method string
+	Path   string
+}
+
+type provisionFailure struct {
+	afterStep int
+	condition string
+}
+
+type record struct {
+	base            apiTypes.Cluster
+	startedAt       time.Time
+	advanced        int
+	failure         *provisionFailure
+	static          bool
+	deleting        bool
+	deleteStartedAt time.Time
+	deleteAdvanced  int
+	deleteFailure   *string
+	workloads       []*workloadRecord
+}
+
+type workloadRecord struct {
+	base      apiTypes.WorkloadCluster
+	startedAt time.Time
+	advanced  int
+	static    bool
+}
+
+// Server is an in-memory console API, it implements http.Handler
+type Server struct {
+	opts Options
+
+	mu                sync.Mutex
+	clusters          map[string]*record
+	requestFailures   []*RequestFailure
+	provisionFailures map[string]provisionFailure
+	deleteFailures    map[string]string
+	requests          []Request
+}
+
+// NewServer returns an empty fake console
+func NewServer(opts Options) *Server {
+	if opts.Steps == nil {
+		opts.Steps = DefaultSteps
+	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	return &Server{
+		opts:              opts,
+		clusters:          map[string]*record{},
+		provisionFailures: map[string]provisionFailure{},
+		deleteFailures:    map[string]string{},
+	}
+}
+
+// Seed stores cl as is, e.g. to start from an already provisioned management cluster
+func (s *Server) Seed(cl apiTypes.Cluster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cl.Status == "" {
+		cl.Status = "provisioned"
+	}
+	if cl.Status == "provisioned" {
+		for _, step := range s.opts.Steps {
+			step.Complete(&cl)
+		}
+	}
+
+	// seeded clusters keep their status until they are reset or submitted again
+	rec := &record{base: cl, static: true}
+	for _, workload := range cl.WorkloadClusters {
+		rec.workloads = append(rec.workloads, &workloadRecord{base: workload, static: true})
+	}
+	s.clusters[cl.ClusterName] = rec
+}
+
+// FailRequests makes requests matching f fail
+func (s *Server) FailRequests(f RequestFailure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requestFailures = append(s.requestFailures, &f)
+}
+
+// FailProvisioning makes the next provisioning of clusterName stop with an
+// error once afterStep steps are complete, reporting condition as the last condition
+func (s *Server) FailProvisioning(clusterName string, afterStep int, condition string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.provisionFailures[clusterName] = provisionFailure{afterStep: afterStep, condition: condition}
+}
+
+// FailDeletion makes the next deletion of clusterName stop with an error once
+// its first step is complete, reporting condition as the last condition
+func (s *Server) FailDeletion(clusterName, condition string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteFailures[clusterName] = condition
+}
+
+// Advance completes n more steps of the named management or workload cluster
+func (s *Server) Advance(clusterName string, n int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, r := range s.clusters {
+		if name == clusterName {
+			if r.deleting {
+				r.deleteAdvanced += n
+				s.collectDeleted()
+				return true
+			}
+			r.advanced += n
+			return true
+		}
+		for _, w := range r.workloads {
+			if w.base.ClusterName == clusterName {
+				w.advanced += n
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Cluster returns the current state of a cluster as the API would report it
+func (s *Server) Cluster(clusterName string) (apiTypes.Cluster, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collectDeleted()
+	r, ok := s.clusters[clusterName]
+	if !ok {
+		return apiTypes.Cluster{}, false
+	}
+	return s.view(r), true
+}
+
+// Clusters returns the current state of every cluster sorted by name
+func (s *Server) Clusters() []apiTypes.Cluster {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collectDeleted()
+	return s.list()
+}
+
+func (s *Server) list() []apiTypes.Cluster {
+	clusters := make([]apiTypes.Cluster, 0, len(s.clusters))
+	for _, rec := range s.clusters {
+		clusters = append(clusters, s.view(rec))
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].ClusterName < clusters[j].ClusterName })
+
+	return clusters
+}
+
+// Requests returns every request received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+	case proxyHealthPath:
+		s.requests = append(s.requests, Request{Method: r.Method, Path: proxyHealthPath})
+		if s.injectFailure(w, r.Method, proxyHealthPath) {
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+
+	case proxyPath:
+		s.serveProxy(w, r)
+
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("route %s not found", r.URL.Path))
+	}
+}
+
+// serveProxy unwraps the upstream path and body the console forwards to the API
+func (s *Server) serveProxy(w http.ResponseWriter, r *http.Request) {
+	upstream := r.URL.Query().Get("url")
+	var body json.RawMessage
+
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		var proxied struct {
+			URL  string          `json:"url"`
+			Body json.RawMessage `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&proxied); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid proxy request: %s", err))
+			return
+		}
+		upstream = proxied.URL
+		body = proxied.Body
+	}
+
+	s.requests = append(s.requests, Request{Method: r.Method, Path: upstream})
+	if s.injectFailure(w, r.Method, upstream) {
+		return
+	}
+	s.collectDeleted()
+
+	segments := strings.Split(strings.Trim(upstream, "/"), "/")
+	switch {
+	case len(segments) == 1 && segments[0] == "cluster" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.list())
+
+	case len(segments) == 2 && segments[0] == "cluster":
+		switch r.Method {
+		case http.MethodGet:
+			s.getCluster(w, segments[1])
+		case http.MethodPost:
+			s.createCluster(w, segments[1], body)
+		case http.MethodDelete:
+			s.deleteCluster(w, segments[1])
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+		}
+
+	case len(segments) == 3 && segments[0] == "cluster" && segments[2] == "reset_progress" && r.Method == http.MethodPost:
+		s.resetProgress(w, segments[1])
+
+	case len(segments) == 3 && segments[0] == "cluster" && segments[2] == "workload" && r.Method == http.MethodPost:
+		s.createWorkloadCluster(w, segments[1], body)
+
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("route %s %s not found", r.Method, upstream))
+	}
+}
+
+func (s *Server) getCluster(w http.ResponseWriter, name string) {
+	rec, ok := s.clusters[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("cluster %q not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.view(rec))
+}
+
+func (s *Server) createCluster(w http.ResponseWriter, name string, body json.RawMessage) {
+	var def apiTypes.ClusterDefinition
+	if err := json.Unmarshal(body, &def); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid cluster definition: %s", err))
+		return
+	}
+	if def.ClusterName == "" {
+		def.ClusterName = name
+	}
+	if message := validateDefinition(def); message != "" {
+		writeError(w, http.StatusBadRequest, message)
+		return
+	}
+
+	// like the API, a cluster can only be submitted again once it failed or was reset
+	if existing, ok := s.clusters[name]; ok {
+		switch status := s.view(existing).Status; status {
+		case "error", "":
+		case "provisioned":
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("cluster %q already exists", name))
+			return
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("%s of cluster %q is already in progress", status, name))
+			return
+		}
+	}
+
+	cl := clusterFromDefinition(def)
+	cl.ID = primitive.NewObjectID()
+	cl.ClusterID = cl.ID.Hex()[len(cl.ID.Hex())-6:]
+	cl.CreationTimestamp = s.opts.Clock().UTC().String()
+	if existing, ok := s.clusters[name]; ok {
+		cl.ID = existing.base.ID
+		cl.ClusterID = existing.base.ClusterID
+		cl.CreationTimestamp = existing.base.CreationTimestamp
+	}
+
+	rec := &record{base: cl, startedAt: s.opts.Clock()}
+	if failure, ok := s.provisionFailures[name]; ok {
+		rec.failure = &failure
+		delete(s.provisionFailures, name)
+	}
+	s.clusters[name] = rec
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": fmt.Sprintf("cluster %q submitted for provisioning", name)})
+}
+
+func (s *Server) resetProgress(w http.ResponseWriter, name string) {
+	rec, ok := s.clusters[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("cluster %q not found", name))
+		return
+	}
+
+	rec.advanced = 0
+	rec.failure = nil
+	rec.static = false
+	rec.startedAt = time.Time{}
+	writeJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("progress of cluster %q reset", name)})
+}
+
+func (s *Server) deleteCluster(w http.ResponseWriter, name string) {
+	rec, ok := s.clusters[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("cluster %q not found", name))
+		return
+	}
+
+	if !rec.deleting || s.deleteStepsDone(rec) == -1 {
+		rec.deleting = true
+		rec.deleteStartedAt = s.opts.Clock()
+		rec.deleteAdvanced = 0
+		rec.deleteFailure = nil
+		if condition, ok := s.deleteFailures[name]; ok {
+			rec.deleteFailure = &condition
+			delete(s.deleteFailures, name)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("cluster %q is being deleted", name)})
+}
+
+func (s *Server) createWorkloadCluster(w http.ResponseWriter, parentName string, body json.RawMessage) {
+	parent, ok := s.clusters[parentName]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("cluster %q not found", parentName))
+		return
+	}
+
+	var workload apiTypes.WorkloadCluster
+	if err := json.Unmarshal(body, &workload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid workload cluster: %s", err))
+		return
+	}
+	if workload.ClusterName == "" {
+		writeError(w, http.StatusBadRequest, "Key: 'WorkloadCluster.ClusterName' Error:Field validation for 'ClusterName' failed on the 'required' tag")
+		return
+	}
+	if status := s.view(parent).Status; status != "provisioned" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("cluster %q is %s, workload clusters require a provisioned management cluster", parentName, status))
+		return
+	}
+	for _, existing := range parent.workloads {
+		if existing.base.ClusterName == workload.ClusterName {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("workload cluster %q already exists", workload.ClusterName))
+			return
+		}
+	}
+
+	workload.ClusterID = primitive.NewObjectID().Hex()
+	workload.ClusterType = "workload"
+	workload.CreationTimestamp = s.opts.Clock().UTC().String()
+	parent.workloads = append(parent.workloads, &workloadRecord{base: workload, startedAt: s.opts.Clock()})
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": fmt.Sprintf("workload cluster %q submitted for provisioning", workload.ClusterName)})
+}
+
+// validateDefinition reports missing required fields the way the API's request binding does
+func validateDefinition(def apiTypes.ClusterDefinition) string {
+	var problems []string
+	for field, value := range map[string]string{
+		"AdminEmail":    def.AdminEmail,
+		"CloudProvider": def.CloudProvider,
+		"ClusterName":   def.ClusterName,
+	} {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("Key: 'ClusterDefinition.%s' Error:Field validation for '%s' failed on the 'required' tag", field, field))
+		}
+	}
+	sort.Strings(problems)
+
+	return strings.Join(problems, "\n")
+}
+
+// stepsDone is the number of steps completed since startedAt, a zero startedAt
+// means the cluster is waiting to be submitted again
+func (s *Server) stepsDone(startedAt time.Time, advanced int) int {
+	done := advanced
+	if s.opts.StepInterval > 0 && !startedAt.IsZero() {
+		done += int(s.opts.Clock().Sub(startedAt) / s.opts.StepInterval)
+	}
+	return min(done, len(s.opts.Steps))
+}
+
+// view renders a cluster record at the current point of its schedule
+func (s *Server) view(rec *record) apiTypes.Cluster {
+	cl := rec.base
+	cl.WorkloadClusters = nil
+
+	switch {
+	case rec.static:
+		// seeded clusters report the status they were stored with
+	case rec.startedAt.IsZero():
+		// reset and waiting to be submitted again
+		cl.Status = ""
+	default:
+		done := s.stepsDone(rec.startedAt, rec.advanced)
+		failed := rec.failure != nil && done > rec.failure.afterStep
+		if failed {
+			done = rec.failure.afterStep
+		}
+		for _, step := range s.opts.Steps[:done] {
+			step.Complete(&cl)
+		}
+
+		switch {
+		case failed:
+			cl.Status = "error"
+			cl.LastCondition = rec.failure.condition
+		case done == len(s.opts.Steps):
+			cl.Status = "provisioned"
+		default:
+			cl.Status = "provisioning"
+			cl.InProgress = true
+		}
+	}
+
+	if rec.deleting {
+		done := s.deleteStepsDone(rec)
+		if done == -1 {
+			done = 1
+		}
+		for _, step := range deleteSteps[:min(done, len(deleteSteps))] {
+			step(&cl)
+		}
+
+		cl.Status = "deleting"
+		cl.InProgress = true
+		if s.deleteStepsDone(rec) == -1 {
+			cl.Status = "error"
+			cl.InProgress = false
+			cl.LastCondition = *rec.deleteFailure
+		}
+	}
+
+	for _, w := range rec.workloads {
+		workload := w.base
+		if !w.static {
+			workload.Status = "provisioning"
+			if s.stepsDone(w.startedAt, w.advanced) == len(s.opts.Steps) {
+				workload.Status = "provisioned"
+			}
+		}
+		cl.WorkloadClusters = append(cl.WorkloadClusters, workload)
+	}
+
+	return cl
+}
+
+// deleteStepsDone is the number of deletion steps completed, or -1 once an
+// injected deletion failure has been reached
+func (s *Server) deleteStepsDone(rec *record) int {
+	done := rec.deleteAdvanced
+	if s.opts.StepInterval > 0 {
+		done += int(s.opts.Clock().Sub(rec.deleteStartedAt) / s.opts.StepInterval)
+	}
+	if rec.deleteFailure != nil && done > 1 {
+		return -1
+	}
+	return done
+}
+
+// collectDeleted forgets clusters whose deletion has completed
+func (s *Server) collectDeleted() {
+	for name, rec := range s.clusters {
+		if rec.deleting && s.deleteStepsDone(rec) > len(deleteSteps) {
+			delete(s.clusters, name)
+		}
+	}
+}
+
+// injectFailure writes the first matching failure and reports whether it did
+func (s *Server) injectFailure(w http.ResponseWriter, method, path string) bool {
+	for i, f := range s.requestFailures {
+		if (f.Method != "" && f.Method != method) || (f.Path != "" && f.Path != path) {
+			continue
+		}
+
+		if f.Count > 0 {
+			f.Count--
+			if f.Count == 0 {
+				s.requestFailures = append(s.requestFailures[:i], s.requestFailures[i+1:]...)
+			}
+		}
+
+		if f.Body == "" {
+			writeError(w, f.StatusCode, http.StatusText(f.StatusCode))
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(f.StatusCode)
+		fmt.Fprint(w, f.Body)
+		return true
+	}
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Warn().Msgf("unable to write fake console response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
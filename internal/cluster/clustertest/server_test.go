@@ -0,0 +1,206 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package clustertest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+)
+
+// clock is a time source the tests move forward by hand
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newClient(t *testing.T, console *clustertest.Server) *cluster.Client {
+	t.Helper()
+
+	server := httptest.NewServer(console)
+	t.Cleanup(server.Close)
+
+	client := cluster.NewClient(server.URL)
+	client.RetryWait = time.Millisecond
+	return client
+}
+
+func definition(name string) apiTypes.ClusterDefinition {
+	return apiTypes.ClusterDefinition{
+		AdminEmail:    "admin@example.com",
+		CloudProvider: "civo",
+		ClusterName:   name,
+		DomainName:    "example.com",
+	}
+}
+
+func mustGetCluster(t *testing.T, client *cluster.Client, name string) apiTypes.Cluster {
+	t.Helper()
+
+	cl, err := client.GetCluster(context.Background(), name)
+	if err != nil {
+		t.Fatalf("GetCluster(%q) error = %v", name, err)
+	}
+	return cl
+}
+
+func TestServerProvisionsOnSchedule(t *testing.T) {
+	now := &clock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	console := clustertest.NewServer(clustertest.Options{StepInterval: time.Minute, Clock: now.Now})
+	client := newClient(t, console)
+
+	if err := client.CreateCluster(context.Background(), definition("demo")); err != nil {
+		t.Fatalf("CreateCluster() error = %v", err)
+	}
+	if cl := mustGetCluster(t, client, "demo"); cl.Status != "provisioning" || cl.InstallToolsCheck {
+		t.Fatalf("submitted cluster = %q with install tools check %t, want provisioning without checks", cl.Status, cl.InstallToolsCheck)
+	}
+
+	now.Add(2 * time.Minute)
+	cl := mustGetCluster(t, client, "demo")
+	if !cl.InstallToolsCheck || !cl.DomainLivenessCheck || cl.KbotSetupCheck {
+		t.Errorf("checks after two steps = install tools %t, domain liveness %t, kbot setup %t, want the first two",
+			cl.InstallToolsCheck, cl.DomainLivenessCheck, cl.KbotSetupCheck)
+	}
+
+	now.Add(time.Duration(len(clustertest.DefaultSteps)) * time.Minute)
+	if cl := mustGetCluster(t, client, "demo"); cl.Status != "provisioned" || !cl.UsersTerraformApplyCheck {
+		t.Errorf("cluster after every step = %q, want provisioned with every check", cl.Status)
+	}
+}
+
+func TestServerFailsProvisioning(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.FailProvisioning("demo", 2, "quota exceeded")
+	client := newClient(t, console)
+
+	if err := client.CreateCluster(context.Background(), definition("demo")); err != nil {
+		t.Fatalf("CreateCluster() error = %v", err)
+	}
+	console.Advance("demo", len(clustertest.DefaultSteps))
+
+	cl := mustGetCluster(t, client, "demo")
+	if cl.Status != "error" || cl.LastCondition != "quota exceeded" {
+		t.Fatalf("cluster = %q (%q), want error (quota exceeded)", cl.Status, cl.LastCondition)
+	}
+	if !cl.DomainLivenessCheck || cl.KbotSetupCheck {
+		t.Errorf("failed cluster checks = domain liveness %t, kbot setup %t, want the steps before the failure only", cl.DomainLivenessCheck, cl.KbotSetupCheck)
+	}
+
+	// the failure only applies to one provisioning
+	if err := client.ResetClusterProgress(context.Background(), "demo"); err != nil {
+		t.Fatalf("ResetClusterProgress() error = %v", err)
+	}
+	if err := client.CreateCluster(context.Background(), definition("demo")); err != nil {
+		t.Fatalf("CreateCluster() after reset error = %v", err)
+	}
+	console.Advance("demo", len(clustertest.DefaultSteps))
+	if cl := mustGetCluster(t, client, "demo"); cl.Status != "provisioned" {
+		t.Errorf("retried cluster = %q, want provisioned", cl.Status)
+	}
+}
+
+func TestServerDeletesClusters(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.Seed(apiTypes.Cluster{ClusterName: "demo"})
+	client := newClient(t, console)
+
+	if err := client.DeleteCluster(context.Background(), "demo"); err != nil {
+		t.Fatalf("DeleteCluster() error = %v", err)
+	}
+	if cl := mustGetCluster(t, client, "demo"); cl.Status != "deleting" {
+		t.Fatalf("deleted cluster = %q, want deleting", cl.Status)
+	}
+
+	console.Advance("demo", 1)
+	if cl := mustGetCluster(t, client, "demo"); cl.CloudTerraformApplyCheck || !cl.GitTerraformApplyCheck {
+		t.Errorf("checks after the first deletion step = cloud %t, git %t, want the cloud infrastructure destroyed first",
+			cl.CloudTerraformApplyCheck, cl.GitTerraformApplyCheck)
+	}
+
+	console.Advance("demo", 2)
+	if _, err := client.GetCluster(context.Background(), "demo"); !errors.Is(err, cluster.ErrNotFound) {
+		t.Errorf("GetCluster() of a deleted cluster error = %v, want ErrNotFound", err)
+	}
+	if clusters := console.Clusters(); len(clusters) != 0 {
+		t.Errorf("Clusters() = %d clusters, want none", len(clusters))
+	}
+}
+
+func TestServerFailsDeletion(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.Seed(apiTypes.Cluster{ClusterName: "demo"})
+	console.FailDeletion("demo", "unable to destroy the vpc")
+	client := newClient(t, console)
+
+	if err := client.DeleteCluster(context.Background(), "demo"); err != nil {
+		t.Fatalf("DeleteCluster() error = %v", err)
+	}
+	console.Advance("demo", 2)
+
+	cl := mustGetCluster(t, client, "demo")
+	if cl.Status != "error" || cl.LastCondition != "unable to destroy the vpc" {
+		t.Errorf("cluster = %q (%q), want error (unable to destroy the vpc)", cl.Status, cl.LastCondition)
+	}
+}
+
+func TestServerProvisionsWorkloadClusters(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.Seed(apiTypes.Cluster{ClusterName: "mgmt"})
+	client := newClient(t, console)
+
+	workload := apiTypes.WorkloadCluster{ClusterName: "dev", CloudProvider: "civo"}
+	if err := client.CreateWorkloadCluster(context.Background(), "mgmt", workload); err != nil {
+		t.Fatalf("CreateWorkloadCluster() error = %v", err)
+	}
+	if err := client.CreateWorkloadCluster(context.Background(), "mgmt", workload); !errors.Is(err, cluster.ErrConflict) {
+		t.Errorf("CreateWorkloadCluster() of an existing workload cluster error = %v, want ErrConflict", err)
+	}
+
+	console.Advance("dev", len(clustertest.DefaultSteps))
+	parent := mustGetCluster(t, client, "mgmt")
+	if len(parent.WorkloadClusters) != 1 || parent.WorkloadClusters[0].Status != "provisioned" {
+		t.Errorf("workload clusters = %+v, want dev provisioned", parent.WorkloadClusters)
+	}
+}
+
+func TestServerFailsRequests(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.FailRequests(clustertest.RequestFailure{Path: "/cluster", StatusCode: http.StatusServiceUnavailable, Count: 1})
+	client := newClient(t, console)
+	client.MaxRetries = 0
+
+	if _, err := client.GetClusters(context.Background()); !errors.Is(err, cluster.ErrUnavailable) {
+		t.Fatalf("first GetClusters() error = %v, want ErrUnavailable", err)
+	}
+	if _, err := client.GetClusters(context.Background()); err != nil {
+		t.Errorf("second GetClusters() error = %v, want the failure to apply once", err)
+	}
+	if got := len(console.Requests()); got != 2 {
+		t.Errorf("Requests() = %d, want 2", got)
+	}
+}
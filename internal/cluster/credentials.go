@@ -0,0 +1,159 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/konstructio/kubefirst/internal/contexts"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	credentialsFileName = "console-credentials.yaml"
+
+	// EnvConsoleToken and EnvConsoleAPIKey pass credentials to `kubefirst login`
+	// without leaving them in the shell history
+	EnvConsoleToken  = "K1_CONSOLE_TOKEN"
+	EnvConsoleAPIKey = "K1_CONSOLE_API_KEY"
+)
+
+// ConsoleCredentials holds the authentication material stored for a single console
+type ConsoleCredentials struct {
+	Token  string `yaml:"token,omitempty"`
+	APIKey string `yaml:"api-key,omitempty"`
+}
+
+// credentialsFile is the on-disk layout of ~/.k1/console-credentials.yaml
+type credentialsFile struct {
+	Current  string                        `yaml:"current,omitempty"`
+	Consoles map[string]ConsoleCredentials `yaml:"consoles"`
+}
+
+func credentialsFilePath() (string, error) {
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get user home directory: %w", err)
+	}
+
+	return filepath.Join(homePath, ".k1", credentialsFileName), nil
+}
+
+func readCredentialsFile() (credentialsFile, error) {
+	file := credentialsFile{Consoles: map[string]ConsoleCredentials{}}
+
+	path, err := credentialsFilePath()
+	if err != nil {
+		return file, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("unable to read %q: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return file, fmt.Errorf("unable to parse %q: %w", path, err)
+	}
+	if file.Consoles == nil {
+		file.Consoles = map[string]ConsoleCredentials{}
+	}
+
+	return file, nil
+}
+
+// normalizeConsoleURL makes equivalent console addresses share one credentials entry
+func normalizeConsoleURL(consoleURL string) string {
+	return strings.TrimRight(strings.TrimSpace(consoleURL), "/")
+}
+
+// LoadCredentials returns the credentials stored for consoleURL, if any
+func LoadCredentials(consoleURL string) (ConsoleCredentials, error) {
+	file, err := readCredentialsFile()
+	if err != nil {
+		return ConsoleCredentials{}, err
+	}
+
+	return file.Consoles[normalizeConsoleURL(consoleURL)], nil
+}
+
+// SaveCredentials stores credentials for consoleURL and makes it the active console
+func SaveCredentials(consoleURL string, creds ConsoleCredentials) error {
+	file, err := readCredentialsFile()
+	if err != nil {
+		return err
+	}
+
+	consoleURL = normalizeConsoleURL(consoleURL)
+	file.Consoles[consoleURL] = creds
+	file.Current = consoleURL
+
+	path, err := credentialsFilePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("unable to create directory for %q: %w", path, err)
+	}
+
+	content, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("unable to marshal console credentials: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("unable to write %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// LoginConsoleURL returns the console `kubefirst login` stores credentials for,
+// consoleURL or the active console when empty. A console other than the one
+// pinned by the active context is refused: commands would keep talking to the
+// console of the context and never use the stored credentials.
+func LoginConsoleURL(consoleURL string) (string, error) {
+	if consoleURL == "" {
+		return normalizeConsoleURL(GetConsoleIngressURL()), nil
+	}
+	consoleURL = normalizeConsoleURL(consoleURL)
+
+	if strings.ToLower(os.Getenv("K1_LOCAL_DEBUG")) == "true" {
+		if pinned := normalizeConsoleURL(os.Getenv("K1_CONSOLE_REMOTE_URL")); pinned != consoleURL {
+			return "", fmt.Errorf("K1_LOCAL_DEBUG pins the console %s, not %s - unset it to log in to another console", pinned, consoleURL)
+		}
+		return consoleURL, nil
+	}
+
+	activeContext, err := contexts.Current()
+	if err != nil {
+		return "", fmt.Errorf("unable to read the active context: %w", err)
+	}
+	if pinned := normalizeConsoleURL(activeContext.ConsoleURL); pinned != "" && pinned != consoleURL {
+		return "", fmt.Errorf("context %q uses the console %s, not %s - switch with `kubefirst context use` or run `kubefirst context create --console-url %s` first", activeContext.Name, pinned, consoleURL, consoleURL)
+	}
+
+	return consoleURL, nil
+}
+
+// currentConsoleURL returns the console selected by the last `kubefirst login`
+func currentConsoleURL() string {
+	file, err := readCredentialsFile()
+	if err != nil {
+		return ""
+	}
+
+	return file.Current
+}
@@ -0,0 +1,162 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+	"github.com/konstructio/kubefirst/internal/contexts"
+)
+
+// useHome gives the test an empty home directory with no context override
+func useHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(contexts.EnvContext, "")
+	t.Setenv("K1_LOCAL_DEBUG", "")
+	t.Setenv("K1_CONSOLE_REMOTE_URL", "")
+	return home
+}
+
+func TestClientSendsCredentials(t *testing.T) {
+	var authorization, apiKey string
+	console := clustertest.NewServer(clustertest.Options{})
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		apiKey = r.Header.Get("X-API-Key")
+		console.ServeHTTP(w, r)
+	}))
+	client.Token = "token"
+	client.APIKey = "key"
+
+	if _, err := client.GetClusters(context.Background()); err != nil {
+		t.Fatalf("GetClusters() error = %v", err)
+	}
+	if authorization != "Bearer token" {
+		t.Errorf("Authorization header = %q, want %q", authorization, "Bearer token")
+	}
+	if apiKey != "key" {
+		t.Errorf("X-API-Key header = %q, want %q", apiKey, "key")
+	}
+}
+
+func TestSaveCredentials(t *testing.T) {
+	home := useHome(t)
+
+	if err := SaveCredentials("https://one.example.com/", ConsoleCredentials{Token: "one"}); err != nil {
+		t.Fatalf("SaveCredentials() error = %v", err)
+	}
+	if err := SaveCredentials("https://two.example.com", ConsoleCredentials{APIKey: "two"}); err != nil {
+		t.Fatalf("SaveCredentials() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".k1", credentialsFileName))
+	if err != nil {
+		t.Fatalf("credentials file not written: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("credentials file mode = %v, want 0600", mode)
+	}
+
+	one, err := LoadCredentials("https://one.example.com")
+	if err != nil {
+		t.Fatalf("LoadCredentials() error = %v", err)
+	}
+	if one.Token != "one" {
+		t.Errorf("LoadCredentials() token = %q, want the credentials stored with a trailing slash", one.Token)
+	}
+	if got := currentConsoleURL(); got != "https://two.example.com" {
+		t.Errorf("currentConsoleURL() = %q, want the console logged into last", got)
+	}
+	if got := GetConsoleIngressURL(); got != "https://two.example.com" {
+		t.Errorf("GetConsoleIngressURL() = %q, want the console logged into last", got)
+	}
+}
+
+func TestLoginConsoleURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		contextURL    string
+		localDebugURL string
+		consoleURL    string
+		want          string
+		wantErr       string
+	}{
+		{
+			name: "active console",
+			want: "https://console.kubefirst.dev",
+		},
+		{
+			name:       "any console without a pinned one",
+			consoleURL: "https://other.example.com/",
+			want:       "https://other.example.com",
+		},
+		{
+			name:       "console of the context",
+			contextURL: "https://pinned.example.com",
+			consoleURL: "https://pinned.example.com/",
+			want:       "https://pinned.example.com",
+		},
+		{
+			name:       "console of the context by default",
+			contextURL: "https://pinned.example.com",
+			want:       "https://pinned.example.com",
+		},
+		{
+			name:       "console other than the context's",
+			contextURL: "https://pinned.example.com",
+			consoleURL: "https://other.example.com",
+			wantErr:    `context "work" uses the console https://pinned.example.com`,
+		},
+		{
+			name:          "console other than the local debug one",
+			localDebugURL: "http://localhost:3000",
+			consoleURL:    "https://other.example.com",
+			wantErr:       "K1_LOCAL_DEBUG pins the console http://localhost:3000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHome(t)
+			if tt.contextURL != "" {
+				if _, err := contexts.Create("work", tt.contextURL); err != nil {
+					t.Fatalf("contexts.Create() error = %v", err)
+				}
+				if _, err := contexts.Use("work"); err != nil {
+					t.Fatalf("contexts.Use() error = %v", err)
+				}
+			}
+			if tt.localDebugURL != "" {
+				t.Setenv("K1_LOCAL_DEBUG", "true")
+				t.Setenv("K1_CONSOLE_REMOTE_URL", tt.localDebugURL)
+			}
+
+			got, err := LoginConsoleURL(tt.consoleURL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoginConsoleURL() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoginConsoleURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("LoginConsoleURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+)
+
+// DefinitionFromCluster rebuilds the definition a cluster was created from using
+// the record stored by the console, so a failed provision can be submitted again
+// without the original create flags. The record does not keep whether Kubefirst
+// Pro was requested, so the caller provides it.
+func DefinitionFromCluster(cl apiTypes.Cluster, installKubefirstPro bool) apiTypes.ClusterDefinition {
+	return apiTypes.ClusterDefinition{
+		AdminEmail:             cl.AlertsEmail,
+		CloudProvider:          cl.CloudProvider,
+		CloudRegion:            cl.CloudRegion,
+		ClusterName:            cl.ClusterName,
+		DomainName:             cl.DomainName,
+		SubdomainName:          cl.SubdomainName,
+		DnsProvider:            cl.DnsProvider,
+		Type:                   cl.ClusterType,
+		NodeType:               cl.NodeType,
+		NodeCount:              cl.NodeCount,
+		PostInstallCatalogApps: cl.PostInstallCatalogApps,
+		InstallKubefirstPro:    installKubefirstPro,
+		GitopsTemplateURL:      cl.GitopsTemplateURL,
+		GitopsTemplateBranch:   cl.GitopsTemplateBranch,
+		GitProvider:            cl.GitProvider,
+		GitProtocol:            cl.GitProtocol,
+		ECR:                    cl.ECR,
+		AkamaiAuth:             cl.AkamaiAuth,
+		AWSAuth:                cl.AWSAuth,
+		CivoAuth:               cl.CivoAuth,
+		DigitaloceanAuth:       cl.DigitaloceanAuth,
+		VultrAuth:              cl.VultrAuth,
+		CloudflareAuth:         cl.CloudflareAuth,
+		GoogleAuth:             cl.GoogleAuth,
+		K3sAuth:                cl.K3sAuth,
+		GitAuth:                cl.GitAuth,
+		LogFileName:            cl.LogFileName,
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Classes of console API errors, match them with errors.Is
+var (
+	ErrNotFound           = fmt.Errorf("cluster not found")
+	ErrConflict           = fmt.Errorf("console API rejected the request as conflicting with an existing cluster")
+	ErrValidation         = fmt.Errorf("console API rejected the request as invalid")
+	ErrUnauthorized       = fmt.Errorf("console API rejected the request as unauthenticated")
+	ErrForbidden          = fmt.Errorf("console API rejected the request as forbidden")
+	ErrUnavailable        = fmt.Errorf("console API is unavailable")
+	ErrUnexpectedResponse = fmt.Errorf("console API returned an unexpected response")
+)
+
+// validatorPattern matches the field errors the API's request binding reports, e.g.
+// Key: 'ClusterDefinition.AdminEmail' Error:Field validation for 'AdminEmail' failed on the 'required' tag
+var validatorPattern = regexp.MustCompile(`Key: '(?:[^'.]+\.)?([^']+)' Error:Field validation for '[^']+' failed on the '([^']+)' tag`)
+
+// FieldError is a single invalid field reported by the console API
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError describes a console API response that could not be fulfilled.
+// It unwraps to one of the error classes above.
+type APIError struct {
+	Kind       error
+	StatusCode int
+	Method     string
+	Console    string
+	Message    string
+	Fields     []FieldError
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (%s returned %d %s)", e.Method, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	for _, field := range e.Fields {
+		fmt.Fprintf(&b, "\n  - %s: %s", field.Field, field.Message)
+	}
+	return b.String()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// failureBody is the shape of error responses returned by the console and its API
+type failureBody struct {
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+	Fields  json.RawMessage `json:"fields"`
+	Errors  json.RawMessage `json:"errors"`
+}
+
+// newAPIError classifies an unsuccessful response and extracts the details the API sent with it
+func (c *Client) newAPIError(res *response, method string) *APIError {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		Method:     method,
+		Console:    c.BaseURL,
+	}
+	apiErr.Message, apiErr.Fields = parseFailureBody(res.Body)
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		apiErr.Kind = ErrNotFound
+	case res.StatusCode == http.StatusConflict:
+		apiErr.Kind = ErrConflict
+	case res.StatusCode == http.StatusUnauthorized:
+		apiErr.Kind = ErrUnauthorized
+	case res.StatusCode == http.StatusForbidden:
+		apiErr.Kind = ErrForbidden
+	case res.StatusCode == http.StatusBadRequest && isConflictMessage(apiErr.Message):
+		// the API answers 400 when a cluster with the same name is already being provisioned
+		apiErr.Kind = ErrConflict
+	case res.StatusCode == http.StatusBadRequest, res.StatusCode == http.StatusUnprocessableEntity:
+		apiErr.Kind = ErrValidation
+	case res.StatusCode == http.StatusBadGateway,
+		res.StatusCode == http.StatusServiceUnavailable,
+		res.StatusCode == http.StatusGatewayTimeout:
+		apiErr.Kind = ErrUnavailable
+	default:
+		apiErr.Kind = ErrUnexpectedResponse
+	}
+
+	return apiErr
+}
+
+func isConflictMessage(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "already exists") || strings.Contains(message, "in progress")
+}
+
+// parseFailureBody extracts the message and field errors of a response, falling
+// back to the raw body for responses that are not JSON
+func parseFailureBody(body []byte) (string, []FieldError) {
+	raw := strings.TrimSpace(string(body))
+	if raw == "" {
+		return "", nil
+	}
+
+	var failure failureBody
+	if err := json.Unmarshal(body, &failure); err != nil {
+		return messageOrValidatorFields(raw)
+	}
+
+	message := failure.Error
+	if message == "" {
+		message = failure.Message
+	}
+
+	fields := parseFields(failure.Fields)
+	if fields == nil {
+		fields = parseFields(failure.Errors)
+	}
+	if fields == nil {
+		return messageOrValidatorFields(message)
+	}
+
+	return message, fields
+}
+
+// messageOrValidatorFields turns request binding failures into field errors,
+// dropping the message they were parsed from as it only repeats them
+func messageOrValidatorFields(message string) (string, []FieldError) {
+	if fields := fieldsFromValidator(message); fields != nil {
+		return "", fields
+	}
+	return message, nil
+}
+
+// parseFields accepts field errors as a list of objects or as a field to message map
+func parseFields(raw json.RawMessage) []FieldError {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var list []FieldError
+	if err := json.Unmarshal(raw, &list); err == nil && len(list) > 0 {
+		return list
+	}
+
+	var byField map[string]string
+	if err := json.Unmarshal(raw, &byField); err == nil && len(byField) > 0 {
+		for field, message := range byField {
+			list = append(list, FieldError{Field: field, Message: message})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Field < list[j].Field })
+		return list
+	}
+
+	return nil
+}
+
+func fieldsFromValidator(message string) []FieldError {
+	var fields []FieldError
+	for _, match := range validatorPattern.FindAllStringSubmatch(message, -1) {
+		fields = append(fields, FieldError{
+			Field:   match[1],
+			Message: fmt.Sprintf("failed the %q check", match[2]),
+		})
+	}
+	return fields
+}
+
+// Remediation suggests how to recover from a console API error. It returns an
+// empty string when err is not a console API error.
+func Remediation(err error) string {
+	console := GetConsoleIngressURL()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Console != "" {
+		console = apiErr.Console
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return "Run `kubefirst launch cluster list` to see the clusters known to the console."
+	case errors.Is(err, ErrConflict):
+		return "A cluster with this name already exists or is being provisioned. Choose another --cluster-name, or run `kubefirst launch cluster retry <name>` if it failed."
+	case errors.Is(err, ErrValidation):
+		return "Correct the fields reported above and re-run the command."
+	case errors.Is(err, ErrUnauthorized):
+		return fmt.Sprintf("Run `kubefirst login --console-url %s` to store valid credentials.", console)
+	case errors.Is(err, ErrForbidden):
+		return fmt.Sprintf("The credentials stored for %s are valid but do not allow this operation. Ask a console administrator for access or log in with another token.", console)
+	case errors.Is(err, ErrUnavailable):
+		return fmt.Sprintf("Check that the console at %s is running and reachable. For a local console run `kubefirst launch up`.", console)
+	}
+
+	return ""
+}
@@ -0,0 +1,180 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+)
+
+func TestClientClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     func(*Client) error
+		failure  *clustertest.RequestFailure
+		want     error
+		requests int
+	}{
+		{
+			name:     "missing cluster",
+			call:     func(c *Client) error { _, err := c.GetCluster(context.Background(), "missing"); return err },
+			want:     ErrNotFound,
+			requests: 1,
+		},
+		{
+			name: "invalid definition",
+			call: func(c *Client) error {
+				return c.CreateCluster(context.Background(), apiTypes.ClusterDefinition{ClusterName: "demo"})
+			},
+			want:     ErrValidation,
+			requests: 1,
+		},
+		{
+			name:     "unauthenticated",
+			call:     func(c *Client) error { _, err := c.GetClusters(context.Background()); return err },
+			failure:  &clustertest.RequestFailure{StatusCode: http.StatusUnauthorized},
+			want:     ErrUnauthorized,
+			requests: 1,
+		},
+		{
+			name:     "forbidden",
+			call:     func(c *Client) error { return c.DeleteCluster(context.Background(), "demo") },
+			failure:  &clustertest.RequestFailure{StatusCode: http.StatusForbidden},
+			want:     ErrForbidden,
+			requests: 1,
+		},
+		{
+			name:     "server error after retries",
+			call:     func(c *Client) error { _, err := c.GetClusters(context.Background()); return err },
+			failure:  &clustertest.RequestFailure{StatusCode: http.StatusInternalServerError},
+			want:     ErrUnavailable,
+			requests: 2,
+		},
+		{
+			name: "server error on create",
+			call: func(c *Client) error {
+				return c.CreateCluster(context.Background(), testDefinition("demo"))
+			},
+			failure:  &clustertest.RequestFailure{StatusCode: http.StatusInternalServerError},
+			want:     ErrUnavailable,
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			console := clustertest.NewServer(clustertest.Options{})
+			if tt.failure != nil {
+				console.FailRequests(*tt.failure)
+			}
+			client := newTestClient(t, console)
+			client.MaxRetries = 1
+
+			err := tt.call(client)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+			if got := len(console.Requests()); got != tt.requests {
+				t.Errorf("console received %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestClientReportsInvalidFields(t *testing.T) {
+	client := newTestClient(t, clustertest.NewServer(clustertest.Options{}))
+
+	err := client.CreateCluster(context.Background(), apiTypes.ClusterDefinition{ClusterName: "demo"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("CreateCluster() error = %v, want an APIError", err)
+	}
+	fields := map[string]bool{}
+	for _, field := range apiErr.Fields {
+		fields[field.Field] = true
+	}
+	for _, want := range []string{"AdminEmail", "CloudProvider"} {
+		if !fields[want] {
+			t.Errorf("APIError fields = %v, want %s", apiErr.Fields, want)
+		}
+	}
+}
+
+func TestClientReportsConflicts(t *testing.T) {
+	client := newTestClient(t, clustertest.NewServer(clustertest.Options{}))
+
+	if err := client.CreateCluster(context.Background(), testDefinition("demo")); err != nil {
+		t.Fatalf("CreateCluster() error = %v", err)
+	}
+	err := client.CreateCluster(context.Background(), testDefinition("demo"))
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("CreateCluster() of a provisioning cluster error = %v, want ErrConflict", err)
+	}
+	if Remediation(err) == "" {
+		t.Error("Remediation() of a conflict is empty")
+	}
+}
+
+func TestParseFailureBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantFields  []FieldError
+	}{
+		{name: "empty", body: ""},
+		{name: "plain text", body: "upstream unavailable\n", wantMessage: "upstream unavailable"},
+		{name: "error", body: `{"error":"cluster not found"}`, wantMessage: "cluster not found"},
+		{name: "message", body: `{"message":"cluster not found"}`, wantMessage: "cluster not found"},
+		{
+			name:        "field list",
+			body:        `{"error":"invalid","fields":[{"field":"domain_name","message":"required"}]}`,
+			wantMessage: "invalid",
+			wantFields:  []FieldError{{Field: "domain_name", Message: "required"}},
+		},
+		{
+			name:        "field map",
+			body:        `{"error":"invalid","errors":{"b":"second","a":"first"}}`,
+			wantMessage: "invalid",
+			wantFields:  []FieldError{{Field: "a", Message: "first"}, {Field: "b", Message: "second"}},
+		},
+		{
+			name:       "request binding",
+			body:       `{"error":"Key: 'ClusterDefinition.AdminEmail' Error:Field validation for 'AdminEmail' failed on the 'required' tag"}`,
+			wantFields: []FieldError{{Field: "AdminEmail", Message: `failed the "required" check`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, fields := parseFailureBody([]byte(tt.body))
+			if message != tt.wantMessage {
+				t.Errorf("parseFailureBody() message = %q, want %q", message, tt.wantMessage)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("parseFailureBody() fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestRemediation(t *testing.T) {
+	unavailable := &APIError{Kind: ErrUnavailable, StatusCode: http.StatusInternalServerError, Console: "https://console.example.com"}
+	if got := Remediation(unavailable); got == "" {
+		t.Error("Remediation() of an unavailable console is empty")
+	}
+	if got := Remediation(errors.New("unable to read the config")); got != "" {
+		t.Errorf("Remediation() of a local error = %q, want empty", got)
+	}
+}
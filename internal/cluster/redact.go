@@ -0,0 +1,135 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/redact"
+)
+
+// RedactedValue replaces secret values in records shown to users
+const RedactedValue = redact.Placeholder
+
+func redactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
+
+func gitAuthSecrets(auth *apiTypes.GitAuth) []*string {
+	return []*string{&auth.Token, &auth.PrivateKey}
+}
+
+func catalogAppsSecrets(apps []apiTypes.GitopsCatalogApp) []*string {
+	secrets := []*string{}
+	for i := range apps {
+		for j := range apps[i].SecretKeys {
+			secrets = append(secrets, &apps[i].SecretKeys[j].Value)
+		}
+	}
+	return secrets
+}
+
+// copyCatalogApps copies the secret keys of apps so they can be redacted
+// without changing the original record
+func copyCatalogApps(apps []apiTypes.GitopsCatalogApp) []apiTypes.GitopsCatalogApp {
+	if apps == nil {
+		return nil
+	}
+
+	copied := make([]apiTypes.GitopsCatalogApp, len(apps))
+	for i, app := range apps {
+		if app.SecretKeys != nil {
+			app.SecretKeys = append(make([]apiTypes.GitopsCatalogAppKeys, 0, len(app.SecretKeys)), app.SecretKeys...)
+		}
+		copied[i] = app
+	}
+	return copied
+}
+
+// clusterSecrets points to every credential of cl
+func clusterSecrets(cl *apiTypes.Cluster) []*string {
+	secrets := []*string{
+		&cl.AkamaiAuth.Token,
+		&cl.AWSAuth.SecretAccessKey,
+		&cl.AWSAuth.SessionToken,
+		&cl.CivoAuth.Token,
+		&cl.DigitaloceanAuth.Token,
+		&cl.DigitaloceanAuth.SpacesSecret,
+		&cl.VultrAuth.Token,
+		&cl.CloudflareAuth.Token,
+		&cl.CloudflareAuth.APIToken,
+		&cl.CloudflareAuth.OriginCaIssuerKey,
+		&cl.VaultAuth.RootToken,
+		&cl.VaultAuth.KbotPassword,
+		&cl.GoogleAuth.KeyFile,
+		&cl.K3sAuth.K3sSshPrivateKey,
+		&cl.StateStoreCredentials.SecretAccessKey,
+		&cl.StateStoreCredentials.SessionToken,
+		&cl.AtlantisWebhookSecret,
+		&cl.ArgoCDPassword,
+		&cl.ArgoCDAuthToken,
+	}
+	secrets = append(secrets, gitAuthSecrets(&cl.GitAuth)...)
+	secrets = append(secrets, catalogAppsSecrets(cl.PostInstallCatalogApps)...)
+	for i := range cl.WorkloadClusters {
+		secrets = append(secrets, gitAuthSecrets(&cl.WorkloadClusters[i].GitAuth)...)
+	}
+	return secrets
+}
+
+// definitionSecrets points to every credential of def
+func definitionSecrets(def *apiTypes.ClusterDefinition) []*string {
+	secrets := []*string{
+		&def.AkamaiAuth.Token,
+		&def.AWSAuth.SecretAccessKey,
+		&def.AWSAuth.SessionToken,
+		&def.CivoAuth.Token,
+		&def.DigitaloceanAuth.Token,
+		&def.DigitaloceanAuth.SpacesSecret,
+		&def.VultrAuth.Token,
+		&def.CloudflareAuth.Token,
+		&def.CloudflareAuth.APIToken,
+		&def.CloudflareAuth.OriginCaIssuerKey,
+		&def.GoogleAuth.KeyFile,
+		&def.K3sAuth.K3sSshPrivateKey,
+	}
+	secrets = append(secrets, gitAuthSecrets(&def.GitAuth)...)
+	return append(secrets, catalogAppsSecrets(def.PostInstallCatalogApps)...)
+}
+
+// RedactCluster returns a copy of cl with every credential replaced by RedactedValue
+func RedactCluster(cl apiTypes.Cluster) apiTypes.Cluster {
+	cl.PostInstallCatalogApps = copyCatalogApps(cl.PostInstallCatalogApps)
+	if cl.WorkloadClusters != nil {
+		cl.WorkloadClusters = append(make([]apiTypes.WorkloadCluster, 0, len(cl.WorkloadClusters)), cl.WorkloadClusters...)
+	}
+
+	for _, secret := range clusterSecrets(&cl) {
+		*secret = redactValue(*secret)
+	}
+	return cl
+}
+
+// RedactClusterDefinition returns a copy of def with every credential replaced by RedactedValue
+func RedactClusterDefinition(def apiTypes.ClusterDefinition) apiTypes.ClusterDefinition {
+	def.PostInstallCatalogApps = copyCatalogApps(def.PostInstallCatalogApps)
+
+	for _, secret := range definitionSecrets(&def) {
+		*secret = redactValue(*secret)
+	}
+	return def
+}
+
+// registerSecrets masks the credentials sent to or received from the console
+// API in the logs
+func registerSecrets(secrets []*string) {
+	for _, secret := range secrets {
+		redact.Register(*secret)
+	}
+}
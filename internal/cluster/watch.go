@@ -0,0 +1,184 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// maxWatchBackoff caps the wait between reconnection attempts
+const maxWatchBackoff = 2 * time.Minute
+
+// WatchEvent is a single observation made by WatchCluster. When Err is set the
+// console could not be reached and the watcher will try again after RetryIn.
+type WatchEvent struct {
+	Cluster apiTypes.Cluster
+	// Checks of the record as sent by the console, including ones unknown to Cluster
+	Checks  []Check
+	Err     error
+	RetryIn time.Duration
+}
+
+// WatchCluster follows the state of a cluster until ctx is done. Server-sent
+// events are used when the console supports them, otherwise the cluster is
+// polled every interval. Failures are reported as events and retried with
+// exponential backoff. The returned channel is closed once ctx is done.
+func (c *Client) WatchCluster(ctx context.Context, clusterName string, interval time.Duration) <-chan WatchEvent {
+	events := make(chan WatchEvent)
+	go c.watch(ctx, clusterName, interval, events)
+	return events
+}
+
+func (c *Client) watch(ctx context.Context, clusterName string, interval time.Duration, events chan<- WatchEvent) {
+	defer close(events)
+
+	// the watcher owns the retry policy, so single requests are not retried
+	poller := *c
+	poller.MaxRetries = 0
+
+	streaming := true
+	backoff := interval
+	for {
+		var err error
+		if streaming {
+			streaming, err = poller.streamCluster(ctx, clusterName, events)
+		} else {
+			var cl apiTypes.Cluster
+			var checks []Check
+			cl, checks, err = poller.getCluster(ctx, clusterName)
+			if err == nil && !sendWatchEvent(ctx, events, WatchEvent{Cluster: cl, Checks: checks}) {
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := interval
+		if err != nil {
+			wait = backoff
+			log.Warn().Msgf("unable to watch cluster %q, retrying in %s: %v", clusterName, wait, err)
+			if !sendWatchEvent(ctx, events, WatchEvent{Err: err, RetryIn: wait}) {
+				return
+			}
+			backoff = min(backoff*2, maxWatchBackoff)
+		} else {
+			backoff = interval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// streamCluster follows server-sent cluster events until the stream ends. It
+// reports whether the console supports streaming; a console that answers with
+// a plain JSON document has its snapshot forwarded and is polled from then on.
+// A stream closed cleanly by the console is not an error, it is reopened
+// after the watch interval without reporting a lost connection.
+func (c *Client) streamCluster(ctx context.Context, clusterName string, events chan<- WatchEvent) (bool, error) {
+	target := c.proxyURL(fmt.Sprintf("/cluster/%s", clusterName))
+
+	// the timeout only applies until the console answers, an open stream may stay idle
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var timer *time.Timer
+	if c.Timeout > 0 {
+		timer = time.AfterFunc(c.Timeout, cancel)
+	}
+
+	req, err := c.newRequest(streamCtx, http.MethodGet, target, nil)
+	if err != nil {
+		return true, err
+	}
+	req.Header.Set("Accept", "text/event-stream, application/json")
+
+	res, err := c.httpClient().Do(req)
+	if timer != nil {
+		timer.Stop()
+	}
+	if err != nil {
+		return true, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		// the body only carries error details, a truncated read is still useful
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+		return true, c.newAPIError(&response{StatusCode: res.StatusCode, Status: res.Status, Body: body}, http.MethodGet)
+	}
+
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return false, fmt.Errorf("failed to read cluster object: %w", err)
+		}
+		cl, checks, err := decodeCluster(body)
+		if err != nil {
+			return false, err
+		}
+		sendWatchEvent(ctx, events, WatchEvent{Cluster: cl, Checks: checks})
+		return false, nil
+	}
+
+	var data strings.Builder
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+
+		// a blank line terminates an event
+		if line == "" && data.Len() > 0 {
+			cl, checks, err := decodeCluster([]byte(data.String()))
+			if err != nil {
+				log.Warn().Msgf("ignoring malformed cluster event: %v", err)
+			} else if !sendWatchEvent(ctx, events, WatchEvent{Cluster: cl, Checks: checks}) {
+				return true, nil
+			}
+			data.Reset()
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, fmt.Errorf("failed to read cluster event stream: %w", err)
+	}
+
+	log.Debug().Msgf("cluster event stream of %q closed by the console, reconnecting", clusterName)
+	return true, nil
+}
+
+func sendWatchEvent(ctx context.Context, events chan<- WatchEvent, event WatchEvent) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case events <- event:
+		return true
+	}
+}
+
+// WatchCluster follows a cluster using the default client
+func WatchCluster(ctx context.Context, clusterName string, interval time.Duration) <-chan WatchEvent {
+	return defaultClient().WatchCluster(ctx, clusterName, interval)
+}
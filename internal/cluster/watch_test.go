@@ -0,0 +1,163 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+)
+
+const testWatchInterval = 10 * time.Millisecond
+
+// nextEvent returns the next event of events, failing the test when none comes
+func nextEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("watch ended before the expected event")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a watch event")
+	}
+	return WatchEvent{}
+}
+
+func TestWatchClusterPollsConsolesWithoutStreaming(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	client := newTestClient(t, console)
+	if err := client.CreateCluster(context.Background(), testDefinition("demo")); err != nil {
+		t.Fatalf("CreateCluster() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := client.WatchCluster(ctx, "demo", testWatchInterval)
+
+	event := nextEvent(t, events)
+	if event.Err != nil || event.Cluster.Status != "provisioning" {
+		t.Fatalf("first event = %+v, want the provisioning cluster", event)
+	}
+
+	console.Advance("demo", len(clustertest.DefaultSteps))
+	for event.Cluster.Status != "provisioned" {
+		event = nextEvent(t, events)
+		if event.Err != nil {
+			t.Fatalf("watch reported %v, want the cluster to be polled", event.Err)
+		}
+	}
+
+	var checked bool
+	for _, check := range event.Checks {
+		if check.Name == "users_terraform_apply_check" {
+			checked = check.Done
+		}
+	}
+	if !checked {
+		t.Errorf("checks of the provisioned cluster = %v, want users_terraform_apply_check done", event.Checks)
+	}
+}
+
+func TestWatchClusterReconnectsClosedStreams(t *testing.T) {
+	var streams atomic.Int32
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := streams.Add(1)
+		record, _ := json.Marshal(apiTypes.Cluster{ClusterName: "demo", Status: "provisioning", InstallToolsCheck: n > 1})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": keep-alive\n\ndata: %s\n\n", record)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := client.WatchCluster(ctx, "demo", testWatchInterval)
+
+	// the console closes the stream after every event, each one is reopened silently
+	for i := 0; i < 3; i++ {
+		event := nextEvent(t, events)
+		if event.Err != nil {
+			t.Fatalf("event %d reported %v, want closed streams to be reopened without an error", i, event.Err)
+		}
+		if want := i > 0; event.Cluster.InstallToolsCheck != want {
+			t.Errorf("event %d install tools check = %t, want %t", i, event.Cluster.InstallToolsCheck, want)
+		}
+	}
+	if got := streams.Load(); got < 3 {
+		t.Errorf("console served %d streams, want at least 3", got)
+	}
+}
+
+func TestWatchClusterBacksOffOnFailures(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.Seed(apiTypes.Cluster{ClusterName: "demo"})
+	console.FailRequests(clustertest.RequestFailure{Path: "/cluster/demo", StatusCode: http.StatusServiceUnavailable, Count: 2})
+	client := newTestClient(t, console)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := client.WatchCluster(ctx, "demo", testWatchInterval)
+
+	for _, wantRetryIn := range []time.Duration{testWatchInterval, 2 * testWatchInterval} {
+		event := nextEvent(t, events)
+		if !errors.Is(event.Err, ErrUnavailable) {
+			t.Fatalf("event error = %v, want ErrUnavailable", event.Err)
+		}
+		if event.RetryIn != wantRetryIn {
+			t.Errorf("event retry in = %s, want %s", event.RetryIn, wantRetryIn)
+		}
+	}
+
+	if event := nextEvent(t, events); event.Err != nil || event.Cluster.Status != "provisioned" {
+		t.Errorf("event after the failures = %+v, want the provisioned cluster", event)
+	}
+}
+
+func TestWatchClusterReportsMissingClusters(t *testing.T) {
+	client := newTestClient(t, clustertest.NewServer(clustertest.Options{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := client.WatchCluster(ctx, "missing", testWatchInterval)
+
+	if event := nextEvent(t, events); !errors.Is(event.Err, ErrNotFound) {
+		t.Errorf("event error = %v, want ErrNotFound", event.Err)
+	}
+}
+
+func TestWatchClusterStopsWithContext(t *testing.T) {
+	console := clustertest.NewServer(clustertest.Options{})
+	console.Seed(apiTypes.Cluster{ClusterName: "demo"})
+	client := newTestClient(t, console)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := client.WatchCluster(ctx, "demo", testWatchInterval)
+	nextEvent(t, events)
+	cancel()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("events were not closed once the context was done")
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/configs"
 	"github.com/konstructio/kubefirst-api/pkg/providerConfigs"
 	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/launch"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/rs/zerolog/log"
@@ -101,10 +102,10 @@ func versionCheck() (*CheckResponse, bool) {
 	}, false
 }
 
-func GetRootCredentials(_ *cobra.Command, _ []string) error {
+func GetRootCredentials(cmd *cobra.Command, _ []string) error {
 	clusterName := viper.GetString("flags.cluster-name")
 
-	cluster, err := cluster.GetCluster(clusterName)
+	cluster, err := cluster.GetCluster(cmd.Context(), clusterName)
 	if err != nil {
 		progress.Error(err.Error())
 		return fmt.Errorf("failed to get cluster: %w", err)
@@ -148,6 +149,10 @@ func Destroy(_ *cobra.Command, _ []string) error {
 		os.Getenv("CF_API_TOKEN"),
 		os.Getenv("CF_ORIGIN_CA_ISSUER_API_TOKEN"),
 	)
+	if err := contexts.RebaseClusterPaths(config); err != nil {
+		progress.Error(err.Error())
+		return fmt.Errorf("failed to locate the cluster directory: %w", err)
+	}
 
 	progress.AddStep("Destroying k3d")
 
@@ -0,0 +1,421 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package contexts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultContextName is the context backed by the original single-platform
+	// layout, $HOME/.kubefirst and $HOME/.k1. It always exists.
+	DefaultContextName = "default"
+
+	// EnvContext selects a context for a single invocation, overriding the active one
+	EnvContext = "K1_CONTEXT"
+
+	contextsFileName = "contexts.yaml"
+	contextsDirName  = "contexts"
+	configFileName   = "kubefirst.yaml"
+
+	// consoleClusterName is the k3d cluster `kubefirst launch up` runs the
+	// console in, suffixed with the context name outside the default context
+	consoleClusterName = "kubefirst-console"
+)
+
+var (
+	ErrNotFound      = errors.New("context not found")
+	ErrAlreadyExists = errors.New("context already exists")
+
+	namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// Context is a set of local state: the console commands talk to, the config
+// file holding flags and checks, and the directory holding logs and cluster files
+type Context struct {
+	Name       string `yaml:"-"`
+	ConsoleURL string `yaml:"console-url,omitempty"`
+	ConfigFile string `yaml:"config-file"`
+	K1Dir      string `yaml:"k1-dir"`
+}
+
+// ConsoleClusterName returns the name of the k3d cluster running the local
+// console of the context, each context has its own
+func (c Context) ConsoleClusterName() string {
+	if c.Name == "" || c.Name == DefaultContextName {
+		return consoleClusterName
+	}
+	return consoleClusterName + "-" + c.Name
+}
+
+// contextsFile is the on-disk layout of ~/.k1/contexts.yaml
+type contextsFile struct {
+	Current  string             `yaml:"current,omitempty"`
+	Contexts map[string]Context `yaml:"contexts"`
+}
+
+func homeDir() (string, error) {
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get user home directory: %w", err)
+	}
+	return homePath, nil
+}
+
+func contextsFilePath() (string, error) {
+	homePath, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(homePath, ".k1", contextsFileName), nil
+}
+
+// defaultContext keeps the paths used before contexts existed so existing
+// installations keep working untouched
+func defaultContext() (Context, error) {
+	homePath, err := homeDir()
+	if err != nil {
+		return Context{}, err
+	}
+
+	return Context{
+		Name:       DefaultContextName,
+		ConfigFile: filepath.Join(homePath, ".kubefirst"),
+		K1Dir:      filepath.Join(homePath, ".k1"),
+	}, nil
+}
+
+func readContextsFile() (contextsFile, error) {
+	file := contextsFile{Contexts: map[string]Context{}}
+
+	path, err := contextsFilePath()
+	if err != nil {
+		return file, err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, fmt.Errorf("unable to read %q: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return file, fmt.Errorf("unable to parse %q: %w", path, err)
+	}
+	if file.Contexts == nil {
+		file.Contexts = map[string]Context{}
+	}
+
+	return file, nil
+}
+
+func writeContextsFile(file contextsFile) error {
+	path, err := contextsFilePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("unable to create directory for %q: %w", path, err)
+	}
+
+	content, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("unable to marshal contexts: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("unable to write %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// lookup returns the named context from file, the default context is always found
+func (f contextsFile) lookup(name string) (Context, error) {
+	if name == "" || name == DefaultContextName {
+		return defaultContext()
+	}
+
+	ctx, ok := f.Contexts[name]
+	if !ok {
+		return Context{}, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	ctx.Name = name
+
+	return ctx, nil
+}
+
+// Current returns the active context. K1_CONTEXT takes precedence over the
+// context selected with `kubefirst context use`.
+func Current() (Context, error) {
+	file, err := readContextsFile()
+	if err != nil {
+		return Context{}, err
+	}
+
+	name := file.Current
+	if override := os.Getenv(EnvContext); override != "" {
+		name = override
+	}
+
+	return file.lookup(name)
+}
+
+// CurrentK1Dir returns the directory holding the local files of the active context
+func CurrentK1Dir() (string, error) {
+	ctx, err := Current()
+	if err != nil {
+		return "", err
+	}
+	return ctx.K1Dir, nil
+}
+
+// ClusterDirName returns the name to give kubefirst-api helpers, such as
+// k3d.DownloadTools, that only use the name of a cluster to place its files in
+// $HOME/.k1/<name>. The returned name is relative to $HOME/.k1 so the files are
+// kept in the directory of the active context instead. Helpers that use the
+// name for anything else get the cluster name and RebaseClusterPaths.
+func ClusterDirName(clusterName string) (string, error) {
+	homePath, err := homeDir()
+	if err != nil {
+		return "", err
+	}
+	k1Dir, err := CurrentK1Dir()
+	if err != nil {
+		return "", err
+	}
+
+	name, err := filepath.Rel(filepath.Join(homePath, ".k1"), filepath.Join(k1Dir, clusterName))
+	if err != nil {
+		return "", fmt.Errorf("unable to locate the files of cluster %q in %q: %w", clusterName, k1Dir, err)
+	}
+	return name, nil
+}
+
+// RebaseClusterPaths moves the paths of config, as returned by the kubefirst-api
+// config helpers, into the directory of the active context. The helpers keep
+// the files of a cluster in $HOME/.k1/<cluster name>, every string field of
+// config under $HOME/.k1 is moved to the same place under the context's K1Dir.
+func RebaseClusterPaths[T any](config *T) error {
+	homePath, err := homeDir()
+	if err != nil {
+		return err
+	}
+	k1Dir, err := CurrentK1Dir()
+	if err != nil {
+		return err
+	}
+
+	base := filepath.Join(homePath, ".k1")
+	if filepath.Clean(k1Dir) == base {
+		return nil
+	}
+
+	fields := reflect.ValueOf(config).Elem()
+	if fields.Kind() != reflect.Struct {
+		return fmt.Errorf("unable to rebase the paths of %T, it is not a struct", config)
+	}
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		if field.Kind() != reflect.String || !field.CanSet() || !isUnder(base, filepath.Clean(field.String())) {
+			continue
+		}
+		rel, err := filepath.Rel(base, field.String())
+		if err != nil {
+			return fmt.Errorf("unable to rebase %q onto %q: %w", field.String(), k1Dir, err)
+		}
+		field.SetString(filepath.Join(k1Dir, rel))
+	}
+
+	return nil
+}
+
+// List returns every context sorted by name, including the default context
+func List() ([]Context, error) {
+	file, err := readContextsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	def, err := file.lookup(DefaultContextName)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []Context{def}
+	for name := range file.Contexts {
+		ctx, err := file.lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, ctx)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list, nil
+}
+
+// Create registers a context with its own config file and directory under
+// ~/.k1/contexts and returns it
+func Create(name, consoleURL string) (Context, error) {
+	if !namePattern.MatchString(name) {
+		return Context{}, fmt.Errorf("invalid context name %q: use lowercase letters, digits and dashes", name)
+	}
+
+	file, err := readContextsFile()
+	if err != nil {
+		return Context{}, err
+	}
+	if _, ok := file.Contexts[name]; ok || name == DefaultContextName {
+		return Context{}, fmt.Errorf("%w: %q", ErrAlreadyExists, name)
+	}
+
+	homePath, err := homeDir()
+	if err != nil {
+		return Context{}, err
+	}
+
+	k1Dir := filepath.Join(homePath, ".k1", contextsDirName, name)
+	ctx := Context{
+		Name:       name,
+		ConsoleURL: strings.TrimRight(strings.TrimSpace(consoleURL), "/"),
+		ConfigFile: filepath.Join(k1Dir, configFileName),
+		K1Dir:      k1Dir,
+	}
+
+	if err := os.MkdirAll(ctx.K1Dir, 0o700); err != nil {
+		return Context{}, fmt.Errorf("unable to create context directory %q: %w", ctx.K1Dir, err)
+	}
+	if _, err := os.Stat(ctx.ConfigFile); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(ctx.ConfigFile, []byte(""), 0o600); err != nil {
+			return Context{}, fmt.Errorf("unable to create context config file %q: %w", ctx.ConfigFile, err)
+		}
+	}
+
+	file.Contexts[name] = ctx
+	if err := writeContextsFile(file); err != nil {
+		return Context{}, err
+	}
+
+	return ctx, nil
+}
+
+// Use makes the named context the active one
+func Use(name string) (Context, error) {
+	file, err := readContextsFile()
+	if err != nil {
+		return Context{}, err
+	}
+
+	ctx, err := file.lookup(name)
+	if err != nil {
+		return Context{}, err
+	}
+
+	file.Current = ctx.Name
+	if ctx.Name == DefaultContextName {
+		file.Current = ""
+	}
+	if err := writeContextsFile(file); err != nil {
+		return Context{}, err
+	}
+
+	return ctx, nil
+}
+
+// CheckDelete returns the named context if it can be deleted. The default
+// context, the active context and contexts whose directory is not under
+// ~/.k1/contexts cannot be deleted.
+func CheckDelete(name string) (Context, error) {
+	if name == DefaultContextName {
+		return Context{}, fmt.Errorf("the %q context cannot be deleted", DefaultContextName)
+	}
+
+	file, err := readContextsFile()
+	if err != nil {
+		return Context{}, err
+	}
+
+	ctx, err := file.lookup(name)
+	if err != nil {
+		return Context{}, err
+	}
+
+	if file.Current == name || os.Getenv(EnvContext) == name {
+		return Context{}, fmt.Errorf("context %q is in use, switch to another context with `kubefirst context use` or unset %s before deleting it", name, EnvContext)
+	}
+
+	homePath, err := homeDir()
+	if err != nil {
+		return Context{}, err
+	}
+	contextsDir := filepath.Join(homePath, ".k1", contextsDirName)
+	if !isUnder(resolvePath(contextsDir), resolvePath(ctx.K1Dir)) {
+		return Context{}, fmt.Errorf("context %q keeps its files in %q, outside of %q - remove it from %s by hand", name, ctx.K1Dir, contextsDir, contextsFileName)
+	}
+
+	return ctx, nil
+}
+
+// Delete unregisters the named context and removes its directory, once
+// CheckDelete allows it
+func Delete(name string) (Context, error) {
+	ctx, err := CheckDelete(name)
+	if err != nil {
+		return Context{}, err
+	}
+
+	file, err := readContextsFile()
+	if err != nil {
+		return Context{}, err
+	}
+
+	delete(file.Contexts, name)
+	if err := writeContextsFile(file); err != nil {
+		return Context{}, err
+	}
+
+	if err := os.RemoveAll(ctx.K1Dir); err != nil {
+		return ctx, fmt.Errorf("unable to remove context directory %q: %w", ctx.K1Dir, err)
+	}
+
+	return ctx, nil
+}
+
+// isUnder reports whether path is a descendant of dir, both absolute and clean
+func isUnder(dir, path string) bool {
+	if !filepath.IsAbs(dir) || !filepath.IsAbs(path) {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath follows the symbolic links of path, paths that do not exist
+// are only cleaned
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
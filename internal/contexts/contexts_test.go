@@ -0,0 +1,288 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package contexts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useHome gives the test an empty home directory with no context override
+func useHome(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvContext, "")
+	return home
+}
+
+func mustCreate(t *testing.T, name, consoleURL string) Context {
+	t.Helper()
+
+	ctx, err := Create(name, consoleURL)
+	if err != nil {
+		t.Fatalf("Create(%q) error = %v", name, err)
+	}
+	return ctx
+}
+
+func TestCreate(t *testing.T) {
+	home := useHome(t)
+
+	ctx := mustCreate(t, "work", "https://console.example.com/")
+	want := Context{
+		Name:       "work",
+		ConsoleURL: "https://console.example.com",
+		ConfigFile: filepath.Join(home, ".k1", "contexts", "work", "kubefirst.yaml"),
+		K1Dir:      filepath.Join(home, ".k1", "contexts", "work"),
+	}
+	if ctx != want {
+		t.Errorf("Create() = %+v, want %+v", ctx, want)
+	}
+	if _, err := os.Stat(ctx.ConfigFile); err != nil {
+		t.Errorf("config file of the context was not created: %v", err)
+	}
+
+	list, err := List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 2 || list[0].Name != DefaultContextName || list[1].Name != "work" {
+		t.Errorf("List() = %+v, want the default and work contexts", list)
+	}
+}
+
+func TestCreateRejects(t *testing.T) {
+	useHome(t)
+	mustCreate(t, "work", "")
+
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{name: "work", wantErr: ErrAlreadyExists},
+		{name: DefaultContextName, wantErr: ErrAlreadyExists},
+		{name: "Work"},
+		{name: "../escape"},
+		{name: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Create(tt.name, "")
+			if err == nil {
+				t.Fatalf("Create(%q) error = nil, want it rejected", tt.name)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Create(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUseAndCurrent(t *testing.T) {
+	home := useHome(t)
+	mustCreate(t, "work", "")
+	mustCreate(t, "lab", "")
+
+	current, err := Current()
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+	if current.Name != DefaultContextName || current.K1Dir != filepath.Join(home, ".k1") || current.ConfigFile != filepath.Join(home, ".kubefirst") {
+		t.Errorf("Current() = %+v, want the default context", current)
+	}
+
+	if _, err := Use("work"); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if current, _ := Current(); current.Name != "work" {
+		t.Errorf("Current() = %q, want work once it is used", current.Name)
+	}
+
+	t.Setenv(EnvContext, "lab")
+	if current, _ := Current(); current.Name != "lab" {
+		t.Errorf("Current() = %q, want %s to take precedence", current.Name, EnvContext)
+	}
+
+	t.Setenv(EnvContext, "missing")
+	if _, err := Current(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Current() error = %v, want ErrNotFound for an unknown %s", err, EnvContext)
+	}
+	t.Setenv(EnvContext, "")
+
+	if _, err := Use("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Use() error = %v, want ErrNotFound", err)
+	}
+	if _, err := Use(DefaultContextName); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if current, _ := Current(); current.Name != DefaultContextName {
+		t.Errorf("Current() = %q, want the default context once it is used", current.Name)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	useHome(t)
+	ctx := mustCreate(t, "work", "")
+
+	deleted, err := Delete("work")
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if deleted.Name != "work" {
+		t.Errorf("Delete() = %+v, want the work context", deleted)
+	}
+	if _, err := os.Stat(ctx.K1Dir); !os.IsNotExist(err) {
+		t.Errorf("directory of the deleted context still exists: %v", err)
+	}
+	if _, err := Use("work"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Use() of the deleted context error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDeleteRefuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		context string
+		setup   func(t *testing.T, home string)
+		wantErr string
+	}{
+		{
+			name:    "default context",
+			context: DefaultContextName,
+			setup:   func(*testing.T, string) {},
+			wantErr: "cannot be deleted",
+		},
+		{
+			name: "active context",
+			setup: func(t *testing.T, _ string) {
+				if _, err := Use("work"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "is in use",
+		},
+		{
+			name:    "context of the environment",
+			setup:   func(t *testing.T, _ string) { t.Setenv(EnvContext, "work") },
+			wantErr: "is in use",
+		},
+		{
+			name: "directory outside of the contexts",
+			setup: func(t *testing.T, home string) {
+				file, err := readContextsFile()
+				if err != nil {
+					t.Fatal(err)
+				}
+				ctx := file.Contexts["work"]
+				ctx.K1Dir = filepath.Join(home, ".k1")
+				file.Contexts["work"] = ctx
+				if err := writeContextsFile(file); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "outside of",
+		},
+		{
+			name: "directory linked outside of the contexts",
+			setup: func(t *testing.T, home string) {
+				dir := filepath.Join(home, ".k1", "contexts", "work")
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(home, dir); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "outside of",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := useHome(t)
+			mustCreate(t, "work", "")
+			tt.setup(t, home)
+
+			name := tt.context
+			if name == "" {
+				name = "work"
+			}
+			_, err := Delete(name)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Delete(%q) error = %v, want %q", name, err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(home, ".k1")); err != nil {
+				t.Errorf("$HOME/.k1 was removed: %v", err)
+			}
+		})
+	}
+}
+
+// clusterConfig mimics the configs returned by the kubefirst-api config helpers
+type clusterConfig struct {
+	K1Dir        string
+	RegistryYaml string
+	Kubeconfig   string
+	GitopsRepo   string
+	RegistryApp  string
+	NodeCount    int
+}
+
+func newClusterConfig(home, clusterName string) clusterConfig {
+	return clusterConfig{
+		K1Dir:        filepath.Join(home, ".k1", clusterName),
+		RegistryYaml: filepath.Join(home, ".k1", clusterName, "gitops", "registry", clusterName, "registry.yaml"),
+		Kubeconfig:   filepath.Join(home, ".k1", clusterName, "kubeconfig"),
+		GitopsRepo:   "https://github.com/example/gitops.git",
+		RegistryApp:  "registry",
+		NodeCount:    3,
+	}
+}
+
+func TestRebaseClusterPaths(t *testing.T) {
+	home := useHome(t)
+
+	config := newClusterConfig(home, "demo")
+	if err := RebaseClusterPaths(&config); err != nil {
+		t.Fatalf("RebaseClusterPaths() error = %v", err)
+	}
+	if config != newClusterConfig(home, "demo") {
+		t.Errorf("RebaseClusterPaths() in the default context = %+v, want the paths unchanged", config)
+	}
+
+	ctx := mustCreate(t, "work", "")
+	t.Setenv(EnvContext, "work")
+
+	if err := RebaseClusterPaths(&config); err != nil {
+		t.Fatalf("RebaseClusterPaths() error = %v", err)
+	}
+	want := clusterConfig{
+		K1Dir:        filepath.Join(ctx.K1Dir, "demo"),
+		RegistryYaml: filepath.Join(ctx.K1Dir, "demo", "gitops", "registry", "demo", "registry.yaml"),
+		Kubeconfig:   filepath.Join(ctx.K1Dir, "demo", "kubeconfig"),
+		GitopsRepo:   "https://github.com/example/gitops.git",
+		RegistryApp:  "registry",
+		NodeCount:    3,
+	}
+	if config != want {
+		t.Errorf("RebaseClusterPaths() = %+v, want %+v", config, want)
+	}
+
+	dirName, err := ClusterDirName("demo")
+	if err != nil {
+		t.Fatalf("ClusterDirName() error = %v", err)
+	}
+	if got := filepath.Join(home, ".k1", dirName); got != want.K1Dir {
+		t.Errorf("ClusterDirName() resolves to %q, want %q", got, want.K1Dir)
+	}
+}
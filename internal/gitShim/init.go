@@ -18,6 +18,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/services"
 	"github.com/konstructio/kubefirst-api/pkg/types"
 	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/redact"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 )
@@ -137,6 +138,7 @@ func ValidateGitCredentials(gitProviderFlag, githubOrgFlag, gitlabGroupFlag stri
 
 		gitAuth.Owner = githubOrgFlag
 		gitAuth.Token = os.Getenv("GITHUB_TOKEN")
+		redact.Register(gitAuth.Token)
 
 		err := github.VerifyTokenPermissions(gitAuth.Token)
 		if err != nil {
@@ -174,6 +176,7 @@ func ValidateGitCredentials(gitProviderFlag, githubOrgFlag, gitlabGroupFlag stri
 		}
 
 		gitAuth.Token = os.Getenv("GITLAB_TOKEN")
+		redact.Register(gitAuth.Token)
 
 		err := gitlab.VerifyTokenPermissions(gitAuth.Token)
 		if err != nil {
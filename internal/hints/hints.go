@@ -0,0 +1,154 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package hints
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed hints.yaml
+var catalogYAML []byte
+
+// rule recognizes a known provisioning failure from the last condition of a cluster
+type rule struct {
+	ID           string   `yaml:"id"`
+	Title        string   `yaml:"title"`
+	Patterns     []string `yaml:"patterns"`
+	Providers    []string `yaml:"providers"`
+	GitProviders []string `yaml:"git_providers"`
+	Explanation  string   `yaml:"explanation"`
+	Docs         string   `yaml:"docs"`
+	Command      string   `yaml:"command"`
+
+	patterns []*regexp.Regexp
+}
+
+// Hint is a rule that matched a cluster, its command and docs filled in for the cluster
+type Hint struct {
+	ID          string
+	Title       string
+	Explanation string
+	Docs        string
+	Command     string
+}
+
+var (
+	catalog     []rule
+	catalogOnce sync.Once
+)
+
+// parse reads a catalog of rules, compiling their patterns
+func parse(content []byte) ([]rule, error) {
+	var rules []rule
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		return nil, fmt.Errorf("unable to parse remediation hints: %w", err)
+	}
+
+	for i := range rules {
+		if len(rules[i].Patterns) == 0 {
+			return nil, fmt.Errorf("remediation hint %q has no pattern", rules[i].ID)
+		}
+		for _, pattern := range rules[i].Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of remediation hint %q: %w", rules[i].ID, err)
+			}
+			rules[i].patterns = append(rules[i].patterns, re)
+		}
+	}
+	return rules, nil
+}
+
+func catalogRules() []rule {
+	catalogOnce.Do(func() {
+		var err error
+		catalog, err = parse(catalogYAML)
+		if err != nil {
+			log.Error().Msgf("remediation hints are disabled: %v", err)
+		}
+	})
+	return catalog
+}
+
+// Match returns the hints of the rules matching the last condition of a cluster, in catalog order
+func Match(cl apiTypes.Cluster) []Hint {
+	if cl.LastCondition == "" {
+		return nil
+	}
+	return matchRules(catalogRules(), cl)
+}
+
+func matchRules(rules []rule, cl apiTypes.Cluster) []Hint {
+	placeholders := strings.NewReplacer(
+		"{cluster}", cl.ClusterName,
+		"{provider}", cl.CloudProvider,
+		"{region}", cl.CloudRegion,
+		"{domain}", clusterDomain(cl),
+		"{git_owner}", cl.GitAuth.Owner,
+	)
+
+	hints := []Hint{}
+	for _, r := range rules {
+		if !r.applies(cl) {
+			continue
+		}
+		hints = append(hints, Hint{
+			ID:          r.ID,
+			Title:       r.Title,
+			Explanation: r.Explanation,
+			Docs:        placeholders.Replace(r.Docs),
+			Command:     placeholders.Replace(r.Command),
+		})
+	}
+	return hints
+}
+
+func (r rule) applies(cl apiTypes.Cluster) bool {
+	if len(r.Providers) > 0 && !slices.Contains(r.Providers, cl.CloudProvider) {
+		return false
+	}
+	if len(r.GitProviders) > 0 && !slices.Contains(r.GitProviders, cl.GitProvider) {
+		return false
+	}
+	for _, pattern := range r.patterns {
+		if pattern.MatchString(cl.LastCondition) {
+			return true
+		}
+	}
+	return false
+}
+
+// Markdown formats hints for the progress terminal
+func Markdown(hints []Hint) string {
+	var b strings.Builder
+	for _, hint := range hints {
+		fmt.Fprintf(&b, "\n### :bulb: %s\n%s\n\n", hint.Title, hint.Explanation)
+		if hint.Command != "" {
+			fmt.Fprintf(&b, "Next, run: `%s`\n\n", hint.Command)
+		}
+		if hint.Docs != "" {
+			fmt.Fprintf(&b, "Docs: %s\n", hint.Docs)
+		}
+	}
+	return b.String()
+}
+
+func clusterDomain(cl apiTypes.Cluster) string {
+	if cl.SubdomainName != "" {
+		return fmt.Sprintf("%s.%s", cl.SubdomainName, cl.DomainName)
+	}
+	return cl.DomainName
+}
@@ -0,0 +1,279 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package hints
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"golang.org/x/exp/slices"
+)
+
+// sampleErrors are last conditions reported by the console for each rule of
+// the catalog, at least one per pattern
+var sampleErrors = map[string][]string{
+	"dns-not-delegated": {
+		"domain liveness check failed for example.com",
+		"lookup kubefirst-liveness.example.com: NXDOMAIN",
+		"no NS records found for example.com",
+	},
+	"letsencrypt-rate-limit": {
+		"too many certificates (5) already issued for this exact set of domains in the last 168 hours",
+		"urn:ietf:params:acme:error:rateLimited: Error creating new order",
+		"acme: error: 429 rate limit exceeded",
+		"rate limited by Let's Encrypt",
+	},
+	"quota-exceeded-aws": {
+		"VcpuLimitExceeded: You have requested more vCPU capacity than your current vCPU limit allows, quota of 32",
+		"LimitExceeded: cannot exceed the maximum number of VPCs",
+		"InsufficientInstanceCapacity: insufficient capacity in us-east-1a",
+	},
+	"quota-exceeded-civo": {
+		"database_quota_exceeded: the instance count quota has been reached",
+		"the account limit exceeded for networks",
+		"insufficient resources to create 4 nodes",
+	},
+	"quota-exceeded-google": {
+		"Quota 'CPUS' exceeded. Limit: 24.0 in region us-east1",
+		"googleapi: Error 403: limit exceeded for SSD_TOTAL_GB",
+		"insufficient resources in us-east1-b",
+	},
+	"quota-exceeded-digitalocean": {
+		"droplet quota reached",
+		"Droplet limit exceeded, request a higher limit",
+		"insufficient capacity for the s-4vcpu-8gb size",
+	},
+	"quota-exceeded-other": {
+		"the quota of linodes of the account is reached",
+		"instance limit exceeded",
+		"insufficient resources in ewr",
+	},
+	"github-token-scope": {
+		"403 Resource not accessible by personal access token",
+		"missing the required admin:org scope",
+		"the token scopes do not include delete_repo, required",
+		"PUT https://api.github.com/orgs/example-org/teams/admins: 403 Forbidden",
+	},
+	"gitlab-token-scope": {
+		"403 Forbidden - insufficient_scope",
+		"the token is missing the write_repository scope",
+		"POST https://gitlab.com/api/v4/projects: 403 Forbidden",
+	},
+	"git-repository-exists": {
+		"POST https://api.github.com/orgs/example-org/repos: 422 Repository creation failed.",
+		"[{Resource:Repository Field:name Code:custom Message:name already exists on this account}]",
+	},
+	"terraform-state-lock": {
+		"Error: Error acquiring the state lock",
+		"the state is locked by another run",
+		"ConditionalCheckFailedException: The conditional request failed",
+	},
+	"aws-credentials": {
+		"ExpiredToken: The security token included in the request is expired",
+		"InvalidClientTokenId: The security token included in the request is invalid.",
+		"the security token included in the request is invalid",
+	},
+}
+
+// clusterFor is a cluster in error with condition, using the providers rule applies to
+func clusterFor(r rule, condition string) apiTypes.Cluster {
+	cl := apiTypes.Cluster{
+		ClusterName:   "demo",
+		CloudProvider: "civo",
+		CloudRegion:   "nyc1",
+		DomainName:    "example.com",
+		GitProvider:   "github",
+		GitAuth:       apiTypes.GitAuth{Owner: "example-org"},
+		Status:        "error",
+		LastCondition: condition,
+	}
+	if len(r.Providers) > 0 {
+		cl.CloudProvider = r.Providers[0]
+	}
+	if len(r.GitProviders) > 0 {
+		cl.GitProvider = r.GitProviders[0]
+	}
+	return cl
+}
+
+func matchedIDs(hints []Hint) []string {
+	ids := []string{}
+	for _, hint := range hints {
+		ids = append(ids, hint.ID)
+	}
+	return ids
+}
+
+func TestCatalog(t *testing.T) {
+	rules, err := parse(catalogYAML)
+	if err != nil {
+		t.Fatalf("parse() of the catalog error = %v", err)
+	}
+
+	ids := map[string]bool{}
+	for _, r := range rules {
+		t.Run(r.ID, func(t *testing.T) {
+			if r.ID == "" || ids[r.ID] {
+				t.Fatalf("rule id %q is empty or not unique", r.ID)
+			}
+			ids[r.ID] = true
+
+			if r.Title == "" || r.Explanation == "" || r.Command == "" {
+				t.Errorf("rule %+v needs a title, an explanation and a command", r)
+			}
+			if !strings.HasPrefix(r.Docs, "https://") || strings.TrimSuffix(r.Docs, "/") == "https://docs.kubefirst.io" {
+				t.Errorf("docs = %q, want a page about the failure", r.Docs)
+			}
+
+			samples, ok := sampleErrors[r.ID]
+			if !ok {
+				t.Fatalf("no sample errors for rule %q", r.ID)
+			}
+			for i, pattern := range r.patterns {
+				if !slices.ContainsFunc(samples, pattern.MatchString) {
+					t.Errorf("pattern %q matches none of the sample errors", r.Patterns[i])
+				}
+			}
+			for _, sample := range samples {
+				if !slices.Contains(matchedIDs(matchRules(rules, clusterFor(r, sample))), r.ID) {
+					t.Errorf("rule does not match the sample error %q", sample)
+				}
+			}
+		})
+	}
+
+	for id := range sampleErrors {
+		if !ids[id] {
+			t.Errorf("sample errors of rule %q that is not in the catalog", id)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		cluster   apiTypes.Cluster
+		wantIDs   []string
+		wantFirst Hint
+	}{
+		{
+			name:    "no last condition",
+			cluster: apiTypes.Cluster{CloudProvider: "civo"},
+			wantIDs: nil,
+		},
+		{
+			name:    "unknown failure",
+			cluster: apiTypes.Cluster{CloudProvider: "civo", LastCondition: "unable to reach the kubernetes api"},
+			wantIDs: []string{},
+		},
+		{
+			name:    "repository name taken outside of github",
+			cluster: apiTypes.Cluster{CloudProvider: "civo", GitProvider: "github", LastCondition: "a volume with this name already exists"},
+			wantIDs: []string{},
+		},
+		{
+			name:    "gitlab cluster",
+			cluster: apiTypes.Cluster{CloudProvider: "civo", GitProvider: "gitlab", LastCondition: "POST https://gitlab.com/api/v4/groups: 403 Forbidden"},
+			wantIDs: []string{"gitlab-token-scope"},
+		},
+		{
+			name: "placeholders",
+			cluster: apiTypes.Cluster{
+				ClusterName:   "demo",
+				CloudProvider: "vultr",
+				DomainName:    "example.com",
+				SubdomainName: "dev",
+				GitProvider:   "github",
+				GitAuth:       apiTypes.GitAuth{Owner: "example-org"},
+				LastCondition: "domain liveness check failed: instance limit exceeded",
+			},
+			wantIDs: []string{"dns-not-delegated", "quota-exceeded-other"},
+			wantFirst: Hint{
+				ID:      "dns-not-delegated",
+				Title:   "The domain is not delegated to the DNS provider",
+				Docs:    "https://www.cloudflare.com/learning/dns/dns-records/dns-ns-record/",
+				Command: "dig +short NS dev.example.com",
+			},
+		},
+		{
+			name:    "repository exists",
+			cluster: apiTypes.Cluster{CloudProvider: "aws", GitProvider: "github", GitAuth: apiTypes.GitAuth{Owner: "example-org"}, LastCondition: "POST https://api.github.com/orgs/example-org/repos: 422 Repository creation failed."},
+			wantIDs: []string{"git-repository-exists"},
+			wantFirst: Hint{
+				ID:      "git-repository-exists",
+				Title:   "The gitops repositories already exist",
+				Docs:    "https://docs.github.com/en/repositories/creating-and-managing-repositories/deleting-a-repository",
+				Command: "gh repo view example-org/gitops",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := Match(tt.cluster)
+			if tt.wantIDs == nil {
+				if hints != nil {
+					t.Fatalf("Match() = %v, want nil", hints)
+				}
+				return
+			}
+			if got := matchedIDs(hints); fmt.Sprint(got) != fmt.Sprint(tt.wantIDs) {
+				t.Fatalf("Match() = %q, want %q", got, tt.wantIDs)
+			}
+			if tt.wantFirst.ID == "" {
+				return
+			}
+			got := hints[0]
+			got.Explanation = ""
+			if got != tt.wantFirst {
+				t.Errorf("Match()[0] = %+v, want %+v", got, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestMatchFillsTheDocsOfTheProvider(t *testing.T) {
+	hints := Match(apiTypes.Cluster{CloudProvider: "akamai", LastCondition: "quota reached"})
+	if len(hints) != 1 || hints[0].Docs != "https://docs.kubefirst.io/akamai/overview/" {
+		t.Errorf("Match() = %+v, want the docs of the akamai provider", hints)
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no pattern", content: "- id: empty\n", wantErr: `remediation hint "empty" has no pattern`},
+		{name: "invalid pattern", content: "- id: invalid\n  patterns: ['(unclosed']\n", wantErr: `invalid pattern of remediation hint "invalid"`},
+		{name: "not a list", content: "id: single\n", wantErr: "unable to parse remediation hints"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse([]byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	got := Markdown([]Hint{
+		{Title: "Cloud quota exceeded", Explanation: "Free resources.", Docs: "https://example.com/quota", Command: "kubefirst civo quota"},
+		{Title: "No command", Explanation: "Nothing to run."},
+	})
+	want := "\n### :bulb: Cloud quota exceeded\nFree resources.\n\nNext, run: `kubefirst civo quota`\n\nDocs: https://example.com/quota\n" +
+		"\n### :bulb: No command\nNothing to run.\n\n"
+	if got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
@@ -8,6 +8,7 @@ package launch
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -22,6 +23,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/k3d"
 	"github.com/konstructio/kubefirst-api/pkg/k8s"
 	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/helm"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/rs/zerolog/log"
@@ -31,8 +33,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// Describes the local kubefirst console cluster name
-var consoleClusterName = "kubefirst-console"
+// consoleClusterDir is the directory of the local console cluster in the
+// directory of the active context
+const consoleClusterDir = "kubefirst-console"
 
 // Up
 func Up(additionalHelmFlags []string, inCluster, useTelemetry bool) {
@@ -41,15 +44,17 @@ func Up(additionalHelmFlags []string, inCluster, useTelemetry bool) {
 	}
 
 	if !inCluster {
-		progress.DisplayLogHints(10)
+		progress.DisplayLogHints("", 10)
 	}
 
-	homeDir, err := os.UserHomeDir()
+	activeContext, err := contexts.Current()
 	if err != nil {
-		progress.Error(fmt.Sprintf("unable to get user's home directory: %s", err))
+		progress.Error(fmt.Sprintf("unable to get the active context: %s", err))
 		return
 	}
-	dir := fmt.Sprintf("%s/.k1/%s", homeDir, consoleClusterName)
+	k1Dir := activeContext.K1Dir
+	consoleClusterName := activeContext.ConsoleClusterName()
+	dir := fmt.Sprintf("%s/%s", k1Dir, consoleClusterDir)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		err := os.MkdirAll(dir, os.ModePerm)
 		if err != nil {
@@ -161,7 +166,7 @@ func Up(additionalHelmFlags []string, inCluster, useTelemetry bool) {
 	progress.AddStep("Create k3d cluster")
 
 	// Create k3d cluster
-	kubeconfigPath := fmt.Sprintf("%s/.k1/%s/kubeconfig", homeDir, consoleClusterName)
+	kubeconfigPath := fmt.Sprintf("%s/kubeconfig", dir)
 	_, _, err = shell.ExecShellReturnStrings(
 		k3dClient,
 		"cluster",
@@ -173,7 +178,7 @@ func Up(additionalHelmFlags []string, inCluster, useTelemetry bool) {
 		log.Info().Msg("Creating k3d cluster for Kubefirst console and API...")
 		err = k3d.ClusterCreateConsoleAPI(
 			consoleClusterName,
-			fmt.Sprintf("%s/.k1", homeDir),
+			k1Dir,
 			k3dClient,
 			fmt.Sprintf("%s/kubeconfig", dir),
 		)
@@ -496,18 +501,19 @@ func Up(additionalHelmFlags []string, inCluster, useTelemetry bool) {
 // Down destroys a k3d cluster for Kubefirst console and API
 func Down(inCluster bool) {
 	if !inCluster {
-		progress.DisplayLogHints(1)
+		progress.DisplayLogHints("", 1)
 	}
 
-	homeDir, err := os.UserHomeDir()
+	activeContext, err := contexts.Current()
 	if err != nil {
-		progress.Error(fmt.Sprintf("something went wrong getting home path: %s", err))
+		progress.Error(fmt.Sprintf("something went wrong getting the active context: %s", err))
 		return
 	}
+	consoleClusterName := activeContext.ConsoleClusterName()
 
 	log.Info().Msg("Deleting k3d cluster for Kubefirst console and API")
 
-	dir := fmt.Sprintf("%s/.k1/%s", homeDir, consoleClusterName)
+	dir := fmt.Sprintf("%s/%s", activeContext.K1Dir, consoleClusterDir)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		progress.Error(fmt.Sprintf("cluster %q directory does not exist", dir))
 		return
@@ -543,23 +549,203 @@ func Down(inCluster bool) {
 	}
 }
 
+// DeleteConsoleCluster deletes the k3d cluster running the local console of
+// ctx. Contexts that never ran `kubefirst launch up` have none.
+func DeleteConsoleCluster(ctx contexts.Context) error {
+	k3dClient := fmt.Sprintf("%s/%s/tools/k3d", ctx.K1Dir, consoleClusterDir)
+	if _, err := os.Stat(k3dClient); os.IsNotExist(err) {
+		return nil
+	}
+
+	log.Info().Msgf("Deleting k3d cluster %q of context %q", ctx.ConsoleClusterName(), ctx.Name)
+	if _, _, err := shell.ExecShellReturnStrings(k3dClient, "cluster", "delete", ctx.ConsoleClusterName()); err != nil {
+		return fmt.Errorf("error deleting k3d cluster %q: %w", ctx.ConsoleClusterName(), err)
+	}
+
+	return nil
+}
+
 // ListClusters makes a request to the console API to list created clusters
-func ListClusters() {
-	clusters, err := cluster.GetClusters()
+func ListClusters(ctx context.Context) error {
+	clusters, err := cluster.GetClusters(ctx)
 	if err != nil {
 		progress.Error(fmt.Sprintf("error getting clusters: %s", err))
-		return
+		return fmt.Errorf("error getting clusters: %w", err)
 	}
 
 	displayFormattedClusterInfo(clusters)
+	return nil
+}
+
+// GetCluster prints a single cluster created by the console in the requested output format
+func GetCluster(ctx context.Context, managedClusterName, output string) error {
+	managedCluster, err := cluster.GetCluster(ctx, managedClusterName)
+	if err != nil {
+		return fmt.Errorf("error getting cluster %q: %w", managedClusterName, err)
+	}
+
+	if output == OutputTable {
+		writeClusterRow(os.Stdout, managedCluster)
+		return nil
+	}
+
+	return writeClusterRecord(os.Stdout, managedCluster, output)
+}
+
+// DescribeCluster prints every detail of a single cluster created by the console in the requested output format
+func DescribeCluster(ctx context.Context, managedClusterName, output string) error {
+	managedCluster, err := cluster.GetCluster(ctx, managedClusterName)
+	if err != nil {
+		return fmt.Errorf("error getting cluster %q: %w", managedClusterName, err)
+	}
+
+	if output == OutputTable {
+		writeClusterDescription(os.Stdout, managedCluster)
+		return nil
+	}
+
+	return writeClusterRecord(os.Stdout, managedCluster, output)
+}
+
+// SummarizeCluster writes the summary of a provisioned cluster from its record
+// in the console API and the provisioning history kept locally, then prints it
+func SummarizeCluster(ctx context.Context, managedClusterName string) error {
+	managedCluster, err := cluster.GetCluster(ctx, managedClusterName)
+	if err != nil {
+		return fmt.Errorf("error getting cluster %q: %w", managedClusterName, err)
+	}
+
+	if managedCluster.Status != "provisioned" {
+		return fmt.Errorf("cluster %q is %q, a summary is only available once it is provisioned", managedClusterName, managedCluster.Status)
+	}
+
+	steps, err := progress.LastProvisioningSteps(managedClusterName)
+	if err != nil {
+		log.Warn().Msgf("step durations are left out of the summary: %v", err)
+	}
+
+	summary := progress.NewSummary(managedCluster, steps)
+	path, err := progress.WriteSummary(summary)
+	if err != nil {
+		return fmt.Errorf("error writing the summary of cluster %q: %w", managedClusterName, err)
+	}
+
+	fmt.Print(summary.Markdown())
+	fmt.Fprintf(os.Stderr, "\nSummary saved to %s\n", path)
+	return nil
 }
 
+// RetryOptions control how RetryCluster confirms and resubmits a cluster
+type RetryOptions struct {
+	// AssumeYes skips the confirmation prompt
+	AssumeYes bool
+	// InstallKubefirstPro overrides whether Kubefirst Pro is installed, nil
+	// keeps the choice recorded when the cluster was created
+	InstallKubefirstPro *bool
+}
+
+// recordedInstallKubefirstPro returns whether Kubefirst Pro was requested when
+// the cluster was created. The console doesn't record it, only the config of
+// the context that created the cluster does.
+func recordedInstallKubefirstPro(managedClusterName string) (bool, error) {
+	if viper.GetString("flags.cluster-name") != managedClusterName || !viper.IsSet("flags.install-kubefirst-pro") {
+		return false, fmt.Errorf("whether cluster %q was created with Kubefirst Pro is unknown, retry it with `kubefirst launch cluster retry %s --install-kubefirst-pro=<true|false>`", managedClusterName, managedClusterName)
+	}
+	return viper.GetBool("flags.install-kubefirst-pro"), nil
+}
+
+// RetryCluster resets the progress of a cluster that failed to provision,
+// submits it again and follows the new attempt in the progress terminal
+func RetryCluster(ctx context.Context, managedClusterName string, opts RetryOptions) error {
+	managedCluster, err := cluster.GetCluster(ctx, managedClusterName)
+	if err != nil {
+		err = fmt.Errorf("error getting cluster %q: %w", managedClusterName, err)
+		progress.Error(err.Error())
+		return err
+	}
+
+	if managedCluster.Status != "error" {
+		err = fmt.Errorf("cluster %q is %q, only clusters that failed to provision can be retried", managedClusterName, managedCluster.Status)
+		progress.Error(err.Error())
+		return err
+	}
+
+	var installKubefirstPro bool
+	if opts.InstallKubefirstPro != nil {
+		installKubefirstPro = *opts.InstallKubefirstPro
+	} else if installKubefirstPro, err = recordedInstallKubefirstPro(managedClusterName); err != nil {
+		progress.Error(err.Error())
+		return err
+	}
+
+	progress.DisplayRetryHints(managedCluster)
+
+	if !opts.AssumeYes && !progress.Confirm(fmt.Sprintf("Reset the provisioning progress of %s and retry?", managedClusterName)) {
+		progress.Success(fmt.Sprintf("### Retry cancelled, cluster `%s` was left unchanged", managedClusterName))
+		return nil
+	}
+
+	if err := cluster.ResetClusterProgress(ctx, managedClusterName); err != nil {
+		err = fmt.Errorf("error resetting cluster %q: %w", managedClusterName, err)
+		progress.Error(err.Error())
+		return err
+	}
+
+	if err := cluster.CreateCluster(ctx, cluster.DefinitionFromCluster(managedCluster, installKubefirstPro)); err != nil {
+		err = fmt.Errorf("error resubmitting cluster %q: %w", managedClusterName, err)
+		progress.Error(err.Error())
+		return err
+	}
+
+	progress.StartProvisioning(managedClusterName)
+	return nil
+}
+
+// DeleteOptions control how DeleteCluster confirms and follows a deletion
+type DeleteOptions struct {
+	// Wait follows the deletion until the console reports an outcome
+	Wait bool
+	// Timeout bounds Wait, zero waits until the deletion completes
+	Timeout time.Duration
+	// AssumeYes skips typing the cluster name to confirm the deletion
+	AssumeYes bool
+}
+
+// ErrDeletionNotConfirmed is returned when the cluster name typed at the
+// deletion prompt does not match
+var ErrDeletionNotConfirmed = errors.New("deletion not confirmed")
+
 // DeleteCluster makes a request to the console API to delete a single cluster
-func DeleteCluster(managedClusterName string) {
-	err := cluster.DeleteCluster(managedClusterName)
+func DeleteCluster(ctx context.Context, managedClusterName string, opts DeleteOptions) error {
+	managedCluster, err := cluster.GetCluster(ctx, managedClusterName)
 	if err != nil {
-		progress.Error(fmt.Sprintf("error: cluster %q not found", managedClusterName))
-		return
+		err = fmt.Errorf("error getting cluster %q: %w", managedClusterName, err)
+		progress.Error(err.Error())
+		return err
+	}
+
+	progress.DisplayDeleteHints(managedCluster)
+
+	if !opts.AssumeYes {
+		typed := progress.Prompt(fmt.Sprintf("This permanently destroys the cluster and its cloud resources. Type **%s** and press enter to confirm", managedClusterName))
+		if typed != managedClusterName {
+			err := fmt.Errorf("%w, cluster %q was left unchanged", ErrDeletionNotConfirmed, managedClusterName)
+			progress.Error(err.Error())
+			return err
+		}
+	}
+
+	if err := cluster.DeleteCluster(ctx, managedClusterName); err != nil {
+		err = fmt.Errorf("error deleting cluster %q: %w", managedClusterName, err)
+		progress.Error(err.Error())
+		return err
+	}
+
+	if opts.Wait {
+		if err := <-progress.StartDeprovisioning(managedCluster, opts.Timeout); err != nil {
+			return fmt.Errorf("error waiting for cluster %q to be deleted: %w", managedClusterName, err)
+		}
+		return nil
 	}
 
 	deleteMessage := `
@@ -568,4 +754,5 @@ func DeleteCluster(managedClusterName string) {
 ### :bulb: - follow progress with ` + fmt.Sprintf("`%s`", "kubefirst launch cluster list") + `
 `
 	progress.Success(deleteMessage)
+	return nil
 }
@@ -0,0 +1,109 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMockAddress is where `launch up --mock` serves the fake console
+const DefaultMockAddress = "127.0.0.1:8089"
+
+// UpMock serves an in-memory console API on address until interrupted, so the
+// cluster commands can be demonstrated without a k3d cluster or a cloud account
+func UpMock(ctx context.Context, address string, stepInterval time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("unable to listen on %q: %w", address, err)
+	}
+
+	consoleURL := fmt.Sprintf("http://%s", listener.Addr())
+	server := &http.Server{
+		Handler:           logMockRequests(clustertest.NewServer(clustertest.Options{StepInterval: stepInterval})),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Warn().Msgf("unable to stop the mock console: %v", err)
+		}
+	}()
+
+	fmt.Printf("Mock Kubefirst console listening on %s\n", consoleURL)
+	fmt.Printf("Clusters complete a provisioning step every %s. Nothing is provisioned and every cluster is lost on exit.\n\n", stepInterval)
+	fmt.Println("Point kubefirst at it from another terminal with:")
+	fmt.Printf("  export K1_LOCAL_DEBUG=true K1_CONSOLE_REMOTE_URL=%s\n\n", consoleURL)
+	fmt.Println("Press ctrl+c to stop.")
+
+	log.Info().Msgf("mock console listening on %s", consoleURL)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("mock console stopped: %w", err)
+	}
+
+	return nil
+}
+
+// statusRecorder keeps the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logMockRequests prints every request served by the mock console
+func logMockRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Path
+		if upstream := r.URL.Query().Get("url"); upstream != "" {
+			target = upstream
+		}
+
+		// proxied writes carry the upstream path in their body
+		if r.Body != nil && r.Method != http.MethodGet {
+			body, err := io.ReadAll(r.Body)
+			if err == nil {
+				var proxied struct {
+					URL string `json:"url"`
+				}
+				if json.Unmarshal(body, &proxied) == nil && proxied.URL != "" {
+					target = proxied.URL
+				}
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		fmt.Printf("%s %s %s %d\n", time.Now().Format(time.TimeOnly), r.Method, target, recorder.status)
+		log.Info().Msgf("mock console: %s %s %d", r.Method, target, recorder.status)
+	})
+}
@@ -0,0 +1,491 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package launch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/konstructio/kubefirst-api/pkg/types"
+	pkg "github.com/konstructio/kubefirst-api/pkg/utils"
+	"github.com/konstructio/kubefirst/internal/cluster"
+)
+
+// clusterUIRefreshInterval is how often the cluster ui polls the console for changes
+const clusterUIRefreshInterval = 5 * time.Second
+
+const clusterUIHelp = "↑/↓ select • enter/d describe • r retry • x delete • c root credentials • o open console • q quit"
+
+var (
+	uiTitleStyle    = lipgloss.NewStyle().MarginLeft(2).Bold(true).Foreground(lipgloss.Color("39"))
+	uiTableStyle    = lipgloss.NewStyle().MarginLeft(2).BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240"))
+	uiPanelStyle    = lipgloss.NewStyle().MarginLeft(2).Padding(0, 1).BorderStyle(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("63"))
+	uiPromptStyle   = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("170"))
+	uiStatusStyle   = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("252"))
+	uiErrorStyle    = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("196"))
+	uiHelpStyle     = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("241"))
+	uiSelectedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("170"))
+)
+
+// clusterUIMode is the screen the cluster ui is showing
+type clusterUIMode int
+
+const (
+	uiModeTable clusterUIMode = iota
+	uiModeDescribe
+	uiModeCredentials
+	uiModeConfirmRetry
+	uiModeConfirmDelete
+)
+
+// clusterUIModel lists the clusters of the console and runs actions on the selected one
+type clusterUIModel struct {
+	ctx context.Context
+
+	table    table.Model
+	details  viewport.Model
+	input    textinput.Model
+	clusters []types.Cluster
+	loaded   bool
+
+	mode   clusterUIMode
+	target string
+
+	status string
+	err    error
+
+	// refreshID discards polls scheduled before the latest refresh
+	refreshID  int
+	consoleURL string
+	// tableHeight is the most rows the terminal has room for
+	tableHeight int
+}
+
+type clustersMsg struct {
+	clusters []types.Cluster
+	err      error
+}
+
+type refreshClustersMsg struct {
+	id int
+}
+
+type clusterActionMsg struct {
+	status string
+	err    error
+}
+
+// ClusterUI opens an interactive table of every cluster created by the console,
+// refreshed while it is open, from which clusters can be described, retried,
+// deleted and opened
+func ClusterUI(ctx context.Context) error {
+	if _, err := tea.NewProgram(newClusterUIModel(ctx), tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("failed to run the cluster ui: %w", err)
+	}
+	return nil
+}
+
+func newClusterUIModel(ctx context.Context) clusterUIModel {
+	keys := table.DefaultKeyMap()
+	// keep the single letter page keys free for the cluster actions
+	keys.HalfPageDown.SetKeys("ctrl+d")
+	keys.HalfPageUp.SetKeys("ctrl+u")
+	keys.PageDown.SetKeys("pgdown")
+	keys.PageUp.SetKeys("pgup")
+
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240")).BorderBottom(true)
+	styles.Selected = uiSelectedStyle
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "NAME", Width: 24},
+			{Title: "STATUS", Width: 14},
+			{Title: "TYPE", Width: 10},
+			{Title: "PROVIDER", Width: 14},
+			{Title: "REGION", Width: 14},
+			{Title: "AGE", Width: 6},
+		}),
+		table.WithFocused(true),
+		table.WithKeyMap(keys),
+		table.WithStyles(styles),
+	)
+
+	input := textinput.New()
+	input.Prompt = "> "
+	input.CharLimit = 80
+
+	return clusterUIModel{
+		ctx:         ctx,
+		table:       t,
+		details:     viewport.New(80, 20),
+		input:       input,
+		tableHeight: 10,
+		consoleURL:  cluster.GetConsoleIngressURL(),
+	}
+}
+
+func (m clusterUIModel) Init() tea.Cmd {
+	return fetchClusters(m.ctx)
+}
+
+// fetchClusters requests the clusters from the console
+func fetchClusters(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		clusters, err := cluster.GetClusters(ctx)
+		return clustersMsg{clusters: clusters, err: err}
+	}
+}
+
+// scheduleRefresh polls the console again after clusterUIRefreshInterval
+func scheduleRefresh(id int) tea.Cmd {
+	return tea.Tick(clusterUIRefreshInterval, func(time.Time) tea.Msg {
+		return refreshClustersMsg{id: id}
+	})
+}
+
+func (m clusterUIModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.tableHeight = max(msg.Height-12, 1)
+		m.fitTable()
+		m.details.Width = max(msg.Width-6, 20)
+		m.details.Height = max(msg.Height-8, 5)
+		return m, nil
+
+	case clustersMsg:
+		m.refreshID++
+		if msg.err != nil {
+			m.err = fmt.Errorf("error getting clusters: %w", msg.err)
+			return m, scheduleRefresh(m.refreshID)
+		}
+		m.err = nil
+		m.loaded = true
+		m.setClusters(msg.clusters)
+		return m, scheduleRefresh(m.refreshID)
+
+	case refreshClustersMsg:
+		if msg.id != m.refreshID {
+			return m, nil
+		}
+		return m, fetchClusters(m.ctx)
+
+	case clusterActionMsg:
+		m.status, m.err = msg.status, msg.err
+		return m, fetchClusters(m.ctx)
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+
+		switch m.mode {
+		case uiModeDescribe, uiModeCredentials:
+			return m.updateDetails(msg)
+		case uiModeConfirmRetry:
+			return m.updateConfirmRetry(msg)
+		case uiModeConfirmDelete:
+			return m.updateConfirmDelete(msg)
+		}
+		return m.updateTable(msg)
+	}
+
+	return m, nil
+}
+
+// updateTable handles the keys of the cluster table
+func (m clusterUIModel) updateTable(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		return m, tea.Quit
+	case "enter", "d", "r", "x", "c", "o":
+	default:
+		var cmd tea.Cmd
+		m.table, cmd = m.table.Update(msg)
+		return m, cmd
+	}
+
+	selected, ok := m.selectedCluster()
+	if !ok {
+		return m, nil
+	}
+	m.status, m.err = "", nil
+
+	switch msg.String() {
+	case "enter", "d":
+		var buf bytes.Buffer
+		writeClusterDescription(&buf, selected)
+		m.showDetails(uiModeDescribe, selected.ClusterName, buf.String())
+
+	case "c":
+		m.showDetails(uiModeCredentials, selected.ClusterName, rootCredentials(selected))
+
+	case "r":
+		if selected.Status != "error" {
+			m.err = fmt.Errorf("cluster %q is %q, only clusters that failed to provision can be retried", selected.ClusterName, selected.Status)
+			return m, nil
+		}
+		m.mode, m.target = uiModeConfirmRetry, selected.ClusterName
+
+	case "x":
+		m.mode, m.target = uiModeConfirmDelete, selected.ClusterName
+		m.input.Reset()
+		return m, m.input.Focus()
+
+	case "o":
+		url := consoleURLFor(selected)
+		if url == "" {
+			m.err = fmt.Errorf("cluster %q has no domain, its console cannot be opened", selected.ClusterName)
+			return m, nil
+		}
+		return m, openConsole(url)
+	}
+
+	return m, nil
+}
+
+// updateDetails scrolls the describe and credentials panels until they are closed
+func (m clusterUIModel) updateDetails(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc", "enter":
+		m.mode, m.target = uiModeTable, ""
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.details, cmd = m.details.Update(msg)
+	return m, cmd
+}
+
+// updateConfirmRetry retries the target cluster when the user answers y
+func (m clusterUIModel) updateConfirmRetry(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	target := m.target
+	m.mode, m.target = uiModeTable, ""
+
+	if msg.String() != "y" && msg.String() != "Y" {
+		m.status = fmt.Sprintf("Retry cancelled, cluster %q was left unchanged", target)
+		return m, nil
+	}
+
+	for _, cl := range m.clusters {
+		if cl.ClusterName == target {
+			m.status = fmt.Sprintf("Retrying cluster %q...", target)
+			return m, retryClusterCmd(m.ctx, cl)
+		}
+	}
+
+	m.err = fmt.Errorf("cluster %q is no longer listed by the console", target)
+	return m, nil
+}
+
+// updateConfirmDelete deletes the target cluster once its name has been typed
+func (m clusterUIModel) updateConfirmDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.status = fmt.Sprintf("Deletion cancelled, cluster %q was left unchanged", m.target)
+		m.mode, m.target = uiModeTable, ""
+		m.input.Blur()
+		return m, nil
+
+	case "enter":
+		target, typed := m.target, m.input.Value()
+		m.mode, m.target = uiModeTable, ""
+		m.input.Blur()
+
+		if typed != target {
+			m.status = fmt.Sprintf("Deletion cancelled, cluster %q was left unchanged", target)
+			return m, nil
+		}
+		m.status = fmt.Sprintf("Deleting cluster %q...", target)
+		return m, deleteClusterCmd(m.ctx, target)
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// setClusters replaces the table rows and keeps the selected cluster selected
+func (m *clusterUIModel) setClusters(clusters []types.Cluster) {
+	selected, hadSelection := m.selectedCluster()
+
+	now := time.Now()
+	rows := make([]table.Row, 0, len(clusters))
+	cursor := 0
+	for i, cl := range clusters {
+		rows = append(rows, table.Row{
+			cl.ClusterName,
+			cl.Status,
+			cl.ClusterType,
+			cl.CloudProvider,
+			cl.CloudRegion,
+			clusterAge(cl.CreationTimestamp, now),
+		})
+		if hadSelection && cl.ClusterName == selected.ClusterName {
+			cursor = i
+		}
+	}
+
+	m.clusters = clusters
+	m.table.SetRows(rows)
+	m.table.SetCursor(cursor)
+	m.fitTable()
+}
+
+// fitTable shrinks the table to its rows, within the room of the terminal
+func (m *clusterUIModel) fitTable() {
+	m.table.SetHeight(min(max(len(m.clusters), 1), m.tableHeight))
+}
+
+func (m clusterUIModel) selectedCluster() (types.Cluster, bool) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.clusters) {
+		return types.Cluster{}, false
+	}
+	return m.clusters[cursor], true
+}
+
+func (m *clusterUIModel) showDetails(mode clusterUIMode, clusterName, content string) {
+	m.mode, m.target = mode, clusterName
+	m.details.SetContent(content)
+	m.details.GotoTop()
+}
+
+// retryClusterCmd resets the progress of a failed cluster and submits it again
+func retryClusterCmd(ctx context.Context, cl types.Cluster) tea.Cmd {
+	return func() tea.Msg {
+		installKubefirstPro, err := recordedInstallKubefirstPro(cl.ClusterName)
+		if err != nil {
+			return clusterActionMsg{err: err}
+		}
+		if err := cluster.ResetClusterProgress(ctx, cl.ClusterName); err != nil {
+			return clusterActionMsg{err: fmt.Errorf("error resetting cluster %q: %w", cl.ClusterName, err)}
+		}
+		if err := cluster.CreateCluster(ctx, cluster.DefinitionFromCluster(cl, installKubefirstPro)); err != nil {
+			return clusterActionMsg{err: fmt.Errorf("error resubmitting cluster %q: %w", cl.ClusterName, err)}
+		}
+		return clusterActionMsg{status: fmt.Sprintf("Resubmitted cluster %q, follow its progress in the table", cl.ClusterName)}
+	}
+}
+
+// deleteClusterCmd submits the deletion of a cluster to the console
+func deleteClusterCmd(ctx context.Context, clusterName string) tea.Cmd {
+	return func() tea.Msg {
+		if err := cluster.DeleteCluster(ctx, clusterName); err != nil {
+			return clusterActionMsg{err: fmt.Errorf("error deleting cluster %q: %w", clusterName, err)}
+		}
+		return clusterActionMsg{status: fmt.Sprintf("Submitted request to delete cluster %q", clusterName)}
+	}
+}
+
+// openConsole opens the console of a cluster in the browser
+func openConsole(url string) tea.Cmd {
+	return func() tea.Msg {
+		if err := pkg.OpenBrowser(url); err != nil {
+			return clusterActionMsg{err: fmt.Errorf("error opening %s in the browser: %w", url, err)}
+		}
+		return clusterActionMsg{status: fmt.Sprintf("Opening %s", url)}
+	}
+}
+
+// consoleURLFor is the address of the console installed in a cluster
+func consoleURLFor(cl types.Cluster) string {
+	domain := fullDomainName(cl)
+	if domain == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://kubefirst.%s", domain)
+}
+
+// rootCredentials lists the passwords of the platform applications of a cluster
+func rootCredentials(cl types.Cluster) string {
+	if cl.ArgoCDPassword == "" && cl.VaultAuth.KbotPassword == "" && cl.VaultAuth.RootToken == "" {
+		return fmt.Sprintf("The console has not reported root credentials for %s yet.\n\nOnce provisioning completes they can also be retrieved with:\n  kubefirst %s root-credentials\n",
+			cl.ClusterName, cl.CloudProvider)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Root credentials of %s\n\n", cl.ClusterName)
+	fmt.Fprintln(&b, "Keep this data secure. These passwords can be used to access the applications in your platform.")
+	fmt.Fprintf(&b, "\nArgoCD Admin Password\n  %s\n", cl.ArgoCDPassword)
+	fmt.Fprintf(&b, "\nKBot User Password\n  %s\n", cl.VaultAuth.KbotPassword)
+	fmt.Fprintf(&b, "\nVault Root Token\n  %s\n", cl.VaultAuth.RootToken)
+	return b.String()
+}
+
+// clusterAge is the time since the console created a cluster, in its largest unit
+func clusterAge(creationTimestamp string, now time.Time) string {
+	created, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", creationTimestamp)
+	if err != nil {
+		created, err = time.Parse(time.RFC3339, creationTimestamp)
+		if err != nil {
+			return "-"
+		}
+	}
+
+	age := max(now.Sub(created), 0)
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+func (m clusterUIModel) View() string {
+	var b strings.Builder
+	b.WriteString("\n" + uiTitleStyle.Render("Kubefirst clusters - "+m.consoleURL) + "\n\n")
+
+	switch m.mode {
+	case uiModeDescribe, uiModeCredentials:
+		b.WriteString(uiPanelStyle.Render(m.details.View()) + "\n")
+		b.WriteString(uiHelpStyle.Render("↑/↓ scroll • esc back") + "\n")
+		return b.String()
+	}
+
+	switch {
+	case !m.loaded && m.err == nil:
+		b.WriteString(uiStatusStyle.Render("Loading clusters...") + "\n")
+	case m.loaded && len(m.clusters) == 0:
+		b.WriteString(uiStatusStyle.Render("The console has no clusters yet.") + "\n")
+	case m.loaded:
+		b.WriteString(uiTableStyle.Render(m.table.View()) + "\n")
+	}
+
+	switch m.mode {
+	case uiModeConfirmRetry:
+		b.WriteString(uiPromptStyle.Render(fmt.Sprintf("Reset the provisioning progress of %s and retry? [y/N]", m.target)) + "\n")
+	case uiModeConfirmDelete:
+		b.WriteString(uiPromptStyle.Render(fmt.Sprintf("This permanently destroys the cluster and its cloud resources. Type %s and press enter to confirm, esc to cancel", m.target)) + "\n")
+		b.WriteString(uiPromptStyle.Render(m.input.View()) + "\n")
+	}
+
+	if m.status != "" {
+		b.WriteString(uiStatusStyle.Render(m.status) + "\n")
+	}
+	if m.err != nil {
+		b.WriteString(uiErrorStyle.Render(m.err.Error()) + "\n")
+		if hint := cluster.Remediation(m.err); hint != "" {
+			b.WriteString(uiErrorStyle.Render(hint) + "\n")
+		}
+	}
+
+	b.WriteString("\n" + uiHelpStyle.Render(clusterUIHelp) + "\n")
+	return b.String()
+}
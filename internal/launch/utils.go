@@ -8,11 +8,17 @@ package launch
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/hints"
 	"github.com/konstructio/kubefirst/internal/progress"
+	"sigs.k8s.io/yaml"
 )
 
 // displayFormattedClusterInfo uses tabwriter to pretty print information on clusters using
@@ -31,6 +37,179 @@ func displayFormattedClusterInfo(clusters []types.Cluster) {
 			cluster.CloudProvider,
 		)
 	}
+	tw.Flush()
 
 	progress.Success(buf.String())
 }
+
+// Supported output formats for cluster records
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// SupportedOutputFormats lists the values accepted by the --output flag
+var SupportedOutputFormats = []string{OutputTable, OutputJSON, OutputYAML}
+
+// writeClusterRecord prints the full cluster record, with credentials redacted, as json or yaml
+func writeClusterRecord(w io.Writer, cl types.Cluster, output string) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(cluster.RedactCluster(cl)); err != nil {
+		return fmt.Errorf("failed to marshal cluster %q: %w", cl.ClusterName, err)
+	}
+
+	if output == OutputYAML {
+		content, err := yaml.JSONToYAML(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to convert cluster %q to yaml: %w", cl.ClusterName, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			return fmt.Errorf("failed to write cluster %q: %w", cl.ClusterName, err)
+		}
+		return nil
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to write cluster %q: %w", cl.ClusterName, err)
+	}
+	return nil
+}
+
+// writeClusterRow prints a single cluster as a one-row table
+func writeClusterRow(w io.Writer, cl types.Cluster) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprint(tw, "NAME\tCREATED AT\tSTATUS\tTYPE\tPROVIDER\tREGION\tDOMAIN\n")
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		cl.ClusterName,
+		cl.CreationTimestamp,
+		cl.Status,
+		cl.ClusterType,
+		cl.CloudProvider,
+		cl.CloudRegion,
+		fullDomainName(cl),
+	)
+
+	tw.Flush()
+}
+
+// writeClusterDescription prints every non-secret detail of a cluster grouped into sections
+func writeClusterDescription(w io.Writer, cl types.Cluster) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Name:\t%s\n", cl.ClusterName)
+	fmt.Fprintf(tw, "Cluster ID:\t%s\n", cl.ClusterID)
+	fmt.Fprintf(tw, "Type:\t%s\n", cl.ClusterType)
+	fmt.Fprintf(tw, "Status:\t%s\n", cl.Status)
+	fmt.Fprintf(tw, "In Progress:\t%t\n", cl.InProgress)
+	fmt.Fprintf(tw, "Last Condition:\t%s\n", cl.LastCondition)
+	if cl.Status == "error" {
+		for _, hint := range hints.Match(cl) {
+			fmt.Fprintf(tw, "  Hint:\t%s\n", hint.Title)
+			if hint.Command != "" {
+				fmt.Fprintf(tw, "    Next:\t%s\n", hint.Command)
+			}
+			fmt.Fprintf(tw, "    Docs:\t%s\n", hint.Docs)
+		}
+	}
+	fmt.Fprintf(tw, "Created At:\t%s\n", cl.CreationTimestamp)
+	fmt.Fprintf(tw, "Alerts Email:\t%s\n", cl.AlertsEmail)
+
+	fmt.Fprintln(tw, "Provider:\t")
+	fmt.Fprintf(tw, "  Cloud Provider:\t%s\n", cl.CloudProvider)
+	fmt.Fprintf(tw, "  Region:\t%s\n", cl.CloudRegion)
+	fmt.Fprintf(tw, "  Node Type:\t%s\n", cl.NodeType)
+	fmt.Fprintf(tw, "  Node Count:\t%d\n", cl.NodeCount)
+	fmt.Fprintf(tw, "  Domain:\t%s\n", fullDomainName(cl))
+	fmt.Fprintf(tw, "  DNS Provider:\t%s\n", cl.DnsProvider)
+	switch cl.CloudProvider {
+	case "aws":
+		fmt.Fprintf(tw, "  AWS Account ID:\t%s\n", cl.AWSAccountId)
+		fmt.Fprintf(tw, "  AWS KMS Key ID:\t%s\n", cl.AWSKMSKeyId)
+		fmt.Fprintf(tw, "  ECR:\t%t\n", cl.ECR)
+	case "google":
+		fmt.Fprintf(tw, "  Google Project:\t%s\n", cl.GoogleAuth.ProjectId)
+	case "k3s":
+		fmt.Fprintf(tw, "  Servers Public IPs:\t%s\n", strings.Join(cl.K3sAuth.K3sServersPublicIps, ", "))
+		fmt.Fprintf(tw, "  Servers Private IPs:\t%s\n", strings.Join(cl.K3sAuth.K3sServersPrivateIps, ", "))
+		fmt.Fprintf(tw, "  SSH User:\t%s\n", cl.K3sAuth.K3sSshUser)
+	}
+	fmt.Fprintf(tw, "  State Store:\t%s\n", cl.StateStoreDetails.Name)
+
+	fmt.Fprintln(tw, "Git:\t")
+	fmt.Fprintf(tw, "  Provider:\t%s\n", cl.GitProvider)
+	fmt.Fprintf(tw, "  Protocol:\t%s\n", cl.GitProtocol)
+	fmt.Fprintf(tw, "  Host:\t%s\n", cl.GitHost)
+	fmt.Fprintf(tw, "  Owner:\t%s\n", cl.GitAuth.Owner)
+	fmt.Fprintf(tw, "  GitOps Template URL:\t%s\n", cl.GitopsTemplateURL)
+	fmt.Fprintf(tw, "  GitOps Template Branch:\t%s\n", cl.GitopsTemplateBranch)
+
+	fmt.Fprintln(tw, "Checks:\t")
+	for _, check := range clusterChecks(cl) {
+		fmt.Fprintf(tw, "  %s:\t%t\n", check.name, check.value)
+	}
+
+	if len(cl.PostInstallCatalogApps) > 0 {
+		fmt.Fprintln(tw, "Catalog Apps:\t")
+		for _, app := range cl.PostInstallCatalogApps {
+			fmt.Fprintf(tw, "  %s:\t%s\n", app.Name, app.DisplayName)
+		}
+	}
+
+	if len(cl.WorkloadClusters) > 0 {
+		fmt.Fprintln(tw, "Workload Clusters:\t")
+		for _, workload := range cl.WorkloadClusters {
+			fmt.Fprintf(tw, "  %s:\t%s %s/%s created %s\n",
+				workload.ClusterName,
+				workload.Status,
+				workload.CloudProvider,
+				workload.CloudRegion,
+				workload.CreationTimestamp,
+			)
+		}
+	}
+
+	tw.Flush()
+}
+
+type clusterCheck struct {
+	name  string
+	value bool
+}
+
+// clusterChecks lists every provisioning check of a cluster in the order the API runs them
+func clusterChecks(cl types.Cluster) []clusterCheck {
+	return []clusterCheck{
+		{"InstallToolsCheck", cl.InstallToolsCheck},
+		{"DomainLivenessCheck", cl.DomainLivenessCheck},
+		{"StateStoreCredsCheck", cl.StateStoreCredsCheck},
+		{"StateStoreCreateCheck", cl.StateStoreCreateCheck},
+		{"KbotSetupCheck", cl.KbotSetupCheck},
+		{"GitInitCheck", cl.GitInitCheck},
+		{"GitopsReadyCheck", cl.GitopsReadyCheck},
+		{"GitTerraformApplyCheck", cl.GitTerraformApplyCheck},
+		{"GitopsPushedCheck", cl.GitopsPushedCheck},
+		{"CloudTerraformApplyCheck", cl.CloudTerraformApplyCheck},
+		{"CloudTerraformApplyFailedCheck", cl.CloudTerraformApplyFailedCheck},
+		{"ClusterSecretsCreatedCheck", cl.ClusterSecretsCreatedCheck},
+		{"ArgoCDInstallCheck", cl.ArgoCDInstallCheck},
+		{"ArgoCDInitializeCheck", cl.ArgoCDInitializeCheck},
+		{"ArgoCDCreateRegistryCheck", cl.ArgoCDCreateRegistryCheck},
+		{"ArgoCDDeleteRegistryCheck", cl.ArgoCDDeleteRegistryCheck},
+		{"VaultInitializedCheck", cl.VaultInitializedCheck},
+		{"VaultTerraformApplyCheck", cl.VaultTerraformApplyCheck},
+		{"UsersTerraformApplyCheck", cl.UsersTerraformApplyCheck},
+		{"AWSKMSKeyDetokenizedCheck", cl.AWSKMSKeyDetokenizedCheck},
+	}
+}
+
+func fullDomainName(cl types.Cluster) string {
+	if cl.SubdomainName != "" {
+		return fmt.Sprintf("%s.%s", cl.SubdomainName, cl.DomainName)
+	}
+	return cl.DomainName
+}
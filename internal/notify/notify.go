@@ -0,0 +1,197 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// sendTimeout bounds each notification, the terminal waits for them before exiting
+const sendTimeout = 10 * time.Second
+
+// Config selects where notifications are sent. It is read from the notifications
+// section of the kubefirst config:
+//
+//	notifications:
+//	  webhook: https://example.com/hooks/kubefirst
+//	  slack-webhook: https://hooks.slack.com/services/...
+//	  command: ./notify.sh
+type Config struct {
+	// Webhook receives the Notification as JSON
+	Webhook string
+	// SlackWebhook is a Slack-compatible incoming webhook
+	SlackWebhook string
+	// Command is run by the shell with the Notification as JSON on stdin and
+	// in KUBEFIRST_NOTIFY_* environment variables
+	Command string
+}
+
+// Empty reports whether no notification is configured
+func (c Config) Empty() bool {
+	return c.Webhook == "" && c.SlackWebhook == "" && c.Command == ""
+}
+
+// Notification tells that the provisioning of a cluster ended
+type Notification struct {
+	Cluster    string    `json:"cluster"`
+	Provider   string    `json:"provider,omitempty"`
+	Status     string    `json:"status"`
+	Timestamp  time.Time `json:"timestamp"`
+	Duration   float64   `json:"duration_seconds"`
+	ConsoleURL string    `json:"console_url,omitempty"`
+	// LastCondition is the error reported by the console when provisioning failed
+	LastCondition string `json:"last_condition,omitempty"`
+}
+
+// webhookOverride is set with --notify-webhook
+var webhookOverride string
+
+// SetWebhook sends notifications to webhook instead of the one of the kubefirst config
+func SetWebhook(webhook string) {
+	webhookOverride = webhook
+}
+
+// Current is the notification configuration of the kubefirst config, with the
+// webhook given on the command line
+func Current() Config {
+	cfg := Config{
+		Webhook:      viper.GetString("notifications.webhook"),
+		SlackWebhook: viper.GetString("notifications.slack-webhook"),
+		Command:      viper.GetString("notifications.command"),
+	}
+	if webhookOverride != "" {
+		cfg.Webhook = webhookOverride
+	}
+	return cfg
+}
+
+// Send delivers n to every target of cfg, returning the failures of all of them
+func Send(ctx context.Context, cfg Config, n Notification) error {
+	if n.Timestamp.IsZero() {
+		n.Timestamp = time.Now().UTC()
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("unable to encode notification: %w", err)
+	}
+
+	var errs []error
+	if cfg.Webhook != "" {
+		if err := post(ctx, cfg.Webhook, payload); err != nil {
+			errs = append(errs, fmt.Errorf("webhook notification failed: %w", err))
+		}
+	}
+	if cfg.SlackWebhook != "" {
+		if err := postSlack(ctx, cfg.SlackWebhook, n); err != nil {
+			errs = append(errs, fmt.Errorf("slack notification failed: %w", err))
+		}
+	}
+	if cfg.Command != "" {
+		if err := runCommand(ctx, cfg.Command, n, payload); err != nil {
+			errs = append(errs, fmt.Errorf("notification command failed: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func post(ctx context.Context, url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected response %q", res.Status)
+	}
+	return nil
+}
+
+// slackMessage is the payload of a Slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func postSlack(ctx context.Context, url string, n Notification) error {
+	payload, err := json.Marshal(slackMessage{Text: slackText(n)})
+	if err != nil {
+		return fmt.Errorf("unable to encode slack message: %w", err)
+	}
+	return post(ctx, url, payload)
+}
+
+// slackText formats n with Slack mrkdwn
+func slackText(n Notification) string {
+	duration := (time.Duration(n.Duration) * time.Second).Round(time.Second)
+
+	var b strings.Builder
+	if n.Status == "provisioned" {
+		fmt.Fprintf(&b, ":white_check_mark: Cluster *%s* is provisioned after %s", n.Cluster, duration)
+	} else {
+		fmt.Fprintf(&b, ":x: Cluster *%s* failed to provision after %s", n.Cluster, duration)
+	}
+	if n.Provider != "" {
+		fmt.Fprintf(&b, " on %s", n.Provider)
+	}
+	if n.LastCondition != "" {
+		fmt.Fprintf(&b, "\n>%s", n.LastCondition)
+	}
+	if n.ConsoleURL != "" {
+		fmt.Fprintf(&b, "\n<%s|Open the Kubefirst console>", n.ConsoleURL)
+	}
+	return b.String()
+}
+
+func runCommand(ctx context.Context, command string, n Notification, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	//nolint:gosec // the command comes from the kubefirst config of the user
+	cmd := exec.CommandContext(ctx, shell, flag, command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"KUBEFIRST_NOTIFY_CLUSTER="+n.Cluster,
+		"KUBEFIRST_NOTIFY_PROVIDER="+n.Provider,
+		"KUBEFIRST_NOTIFY_STATUS="+n.Status,
+		fmt.Sprintf("KUBEFIRST_NOTIFY_DURATION_SECONDS=%.0f", n.Duration),
+		"KUBEFIRST_NOTIFY_CONSOLE_URL="+n.ConsoleURL,
+		"KUBEFIRST_NOTIFY_LAST_CONDITION="+n.LastCondition,
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
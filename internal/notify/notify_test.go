@@ -0,0 +1,228 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// receiver records the bodies posted to a webhook, answering with status
+func receiver(t *testing.T, status int) (*httptest.Server, <-chan []byte) {
+	t.Helper()
+
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s with content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, bodies
+}
+
+func testNotification() Notification {
+	return Notification{
+		Cluster:       "demo",
+		Provider:      "civo",
+		Status:        "error",
+		Timestamp:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Duration:      754,
+		ConsoleURL:    "https://kubefirst.example.com",
+		LastCondition: "unable to create the vpc",
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	webhook, bodies := receiver(t, http.StatusNoContent)
+
+	n := testNotification()
+	if err := Send(context.Background(), Config{Webhook: webhook.URL}, n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var got Notification
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatalf("webhook payload is not a notification: %v", err)
+	}
+	if got != n {
+		t.Errorf("webhook payload = %+v, want %+v", got, n)
+	}
+}
+
+func TestSendSetsTheTimestamp(t *testing.T) {
+	webhook, bodies := receiver(t, http.StatusOK)
+
+	n := testNotification()
+	n.Timestamp = time.Time{}
+	if err := Send(context.Background(), Config{Webhook: webhook.URL}, n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	var got Notification
+	if err := json.Unmarshal(<-bodies, &got); err != nil {
+		t.Fatalf("webhook payload is not a notification: %v", err)
+	}
+	if got.Timestamp.IsZero() {
+		t.Error("notification timestamp is not set")
+	}
+}
+
+func TestSendSlack(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(n *Notification)
+		want   string
+	}{
+		{
+			name:   "failure",
+			mutate: func(*Notification) {},
+			want:   ":x: Cluster *demo* failed to provision after 12m34s on civo\n>unable to create the vpc\n<https://kubefirst.example.com|Open the Kubefirst console>",
+		},
+		{
+			name: "success",
+			mutate: func(n *Notification) {
+				n.Status = "provisioned"
+				n.LastCondition = ""
+			},
+			want: ":white_check_mark: Cluster *demo* is provisioned after 12m34s on civo\n<https://kubefirst.example.com|Open the Kubefirst console>",
+		},
+		{
+			name: "without provider and console",
+			mutate: func(n *Notification) {
+				n.Status = "provisioned"
+				n.Provider = ""
+				n.ConsoleURL = ""
+				n.LastCondition = ""
+			},
+			want: ":white_check_mark: Cluster *demo* is provisioned after 12m34s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slack, bodies := receiver(t, http.StatusOK)
+
+			n := testNotification()
+			tt.mutate(&n)
+			if err := Send(context.Background(), Config{SlackWebhook: slack.URL}, n); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			var got map[string]string
+			if err := json.Unmarshal(<-bodies, &got); err != nil {
+				t.Fatalf("slack payload is not JSON: %v", err)
+			}
+			if len(got) != 1 || got["text"] != tt.want {
+				t.Errorf("slack payload = %q, want the text %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is run by sh")
+	}
+
+	dir := t.TempDir()
+	command := `cat > payload.json && echo "$KUBEFIRST_NOTIFY_CLUSTER $KUBEFIRST_NOTIFY_STATUS $KUBEFIRST_NOTIFY_DURATION_SECONDS $KUBEFIRST_NOTIFY_LAST_CONDITION" > env`
+	n := testNotification()
+	if err := Send(context.Background(), Config{Command: "cd " + dir + " && " + command}, n); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	payload, err := os.ReadFile(filepath.Join(dir, "payload.json"))
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	var got Notification
+	if err := json.Unmarshal(payload, &got); err != nil || got != n {
+		t.Errorf("command stdin = %s, want the notification as JSON", payload)
+	}
+
+	env, err := os.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatalf("command did not run: %v", err)
+	}
+	if got, want := strings.TrimSpace(string(env)), "demo error 754 unable to create the vpc"; got != want {
+		t.Errorf("command environment = %q, want %q", got, want)
+	}
+}
+
+func TestSendReportsEveryFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command is run by sh")
+	}
+
+	webhook, _ := receiver(t, http.StatusInternalServerError)
+	slack, slackBodies := receiver(t, http.StatusOK)
+
+	err := Send(context.Background(), Config{
+		Webhook:      webhook.URL,
+		SlackWebhook: slack.URL,
+		Command:      "echo unable to page && exit 3",
+	}, testNotification())
+	if err == nil {
+		t.Fatal("Send() error = nil, want the failing webhook and command reported")
+	}
+	for _, want := range []string{`webhook notification failed: unexpected response "500 Internal Server Error"`, "notification command failed: exit status 3: unable to page"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Send() error = %v, want it to report %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "slack") {
+		t.Errorf("Send() error = %v, want the slack notification delivered", err)
+	}
+	select {
+	case <-slackBodies:
+	default:
+		t.Error("a failing webhook stopped the slack notification")
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	viper.Set("notifications.webhook", "https://example.com/config")
+	viper.Set("notifications.slack-webhook", "https://hooks.slack.com/services/config")
+	viper.Set("notifications.command", "./notify.sh")
+	t.Cleanup(func() {
+		viper.Set("notifications.webhook", "")
+		viper.Set("notifications.slack-webhook", "")
+		viper.Set("notifications.command", "")
+		SetWebhook("")
+	})
+
+	want := Config{Webhook: "https://example.com/config", SlackWebhook: "https://hooks.slack.com/services/config", Command: "./notify.sh"}
+	if got := Current(); got != want {
+		t.Errorf("Current() = %+v, want the kubefirst config %+v", got, want)
+	}
+
+	SetWebhook("https://example.com/flag")
+	want.Webhook = "https://example.com/flag"
+	if got := Current(); got != want {
+		t.Errorf("Current() = %+v, want the webhook of the flag %+v", got, want)
+	}
+
+	if !(Config{}).Empty() || want.Empty() {
+		t.Error("Empty() reports whether no notification is configured")
+	}
+}
@@ -7,93 +7,159 @@ See the LICENSE file for more details.
 package progress
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/konstructio/kubefirst-api/pkg/types"
 	"github.com/konstructio/kubefirst/internal/cluster"
+	"golang.org/x/exp/slices"
 )
 
 // Commands
-func GetClusterInterval(clusterName string) tea.Cmd {
-	return tea.Every(time.Second*10, func(_ time.Time) tea.Msg {
-		provisioningCluster, err := cluster.GetCluster(clusterName)
-		if err != nil {
-			log.Printf("failed to get cluster %q: %v", clusterName, err)
+func WaitForClusterEvent(events <-chan cluster.WatchEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
 			return nil
 		}
 
-		return CusterProvisioningMsg(provisioningCluster)
-	})
+		if event.Err != nil {
+			return clusterReconnectingMsg{
+				err:     event.Err,
+				retryIn: event.RetryIn,
+			}
+		}
+
+		return CusterProvisioningMsg{cluster: event.Cluster, checks: event.Checks}
+	}
 }
 
-func AddSuccesMessage(cluster types.Cluster) tea.Cmd {
+func AddSuccesMessage(cluster types.Cluster, steps []StepTiming, summaryPath string) tea.Cmd {
 	return tea.Tick(0, func(_ time.Time) tea.Msg {
 		successMessage := DisplaySuccessMessage(cluster)
+		if len(steps) > 0 {
+			successMessage.text += "\n### :stopwatch: Step durations\n\n" + durationTable(steps)
+		}
+		if summaryPath != "" {
+			successMessage.text += fmt.Sprintf("\n### :page_facing_up: This summary was saved to `%s`\n", summaryPath)
+		}
+		successMessage.message = renderMessage(successMessage.text)
 
 		return successMessage
 	})
 }
 
-func BuildCompletedSteps(cluster types.Cluster) ([]string, string) {
-	completedSteps := []string{}
-	nextStep := ""
-	if cluster.InstallToolsCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.installToolsCheck)
-		nextStep = CompletedStepsLabels.domainLivenessCheck
-	}
-	if cluster.DomainLivenessCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.domainLivenessCheck)
-		nextStep = CompletedStepsLabels.kbotSetupCheck
-	}
-	if cluster.KbotSetupCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.kbotSetupCheck)
-		nextStep = CompletedStepsLabels.gitInitCheck
-	}
-	if cluster.GitInitCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.gitInitCheck)
-		nextStep = CompletedStepsLabels.gitopsReadyCheck
-	}
-	if cluster.GitopsReadyCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.gitopsReadyCheck)
-		nextStep = CompletedStepsLabels.gitTerraformApplyCheck
+func AddWorkloadSuccessMessage(parent types.Cluster, workload types.WorkloadCluster) tea.Cmd {
+	return tea.Tick(0, func(_ time.Time) tea.Msg {
+		return DisplayWorkloadSuccessMessage(parent, workload)
+	})
+}
+
+// ProvisionStepsFor lists the provisioning steps of provider in the order the console runs them
+func ProvisionStepsFor(provider string) []ProvisionStep {
+	overrides := providerStepOverrides[provider]
+
+	steps := make([]ProvisionStep, 0, len(provisionSteps)+len(overrides.Add))
+	for _, step := range provisionSteps {
+		if slices.Contains(overrides.Skip, step.Check) {
+			continue
+		}
+		if label, ok := overrides.Relabel[step.Check]; ok {
+			step.Label = label
+		}
+		steps = append(steps, step)
 	}
-	if cluster.GitTerraformApplyCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.gitTerraformApplyCheck)
-		nextStep = CompletedStepsLabels.gitopsPushedCheck
+
+	for _, added := range overrides.Add {
+		index := len(steps)
+		if after := slices.IndexFunc(steps, func(step ProvisionStep) bool { return step.Check == added.After }); after >= 0 {
+			index = after + 1
+		}
+		steps = slices.Insert(steps, index, added.ProvisionStep)
 	}
-	if cluster.GitopsPushedCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.gitopsPushedCheck)
-		nextStep = CompletedStepsLabels.cloudTerraformApplyCheck
+
+	return steps
+}
+
+// BuildCompletedSteps lists the provisioning steps the console has completed and
+// the one in progress. checks are the checks of the record as sent by the
+// console: those unknown to this release of kubefirst are steps the console
+// runs after the known ones.
+func BuildCompletedSteps(cl types.Cluster, checks []cluster.Check) ([]string, string) {
+	done := checkValues(cl)
+	steps := ProvisionStepsFor(cl.CloudProvider)
+	for _, check := range checks {
+		if _, known := done[check.Name]; !known {
+			steps = append(steps, ProvisionStep{Check: check.Name, Label: checkLabel(check.Name)})
+		}
+		done[check.Name] = check.Done
 	}
-	if cluster.CloudTerraformApplyCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.cloudTerraformApplyCheck)
-		nextStep = CompletedStepsLabels.clusterSecretsCreatedCheck
+
+	completedSteps := []string{}
+	nextStep := ""
+	for i, step := range steps {
+		if !done[step.Check] {
+			continue
+		}
+		completedSteps = append(completedSteps, step.Label)
+		nextStep = finalProvisionStep
+		if i+1 < len(steps) {
+			nextStep = steps[i+1].Label
+		}
 	}
-	if cluster.ClusterSecretsCreatedCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.clusterSecretsCreatedCheck)
-		nextStep = CompletedStepsLabels.argoCDInstallCheck
+
+	return completedSteps, nextStep
+}
+
+// checkValues are the checks of a cluster record by field, as the console names them
+func checkValues(cl types.Cluster) map[string]bool {
+	values := map[string]bool{}
+
+	record, err := json.Marshal(cl)
+	if err != nil {
+		return values
 	}
-	if cluster.ArgoCDInstallCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.argoCDInstallCheck)
-		nextStep = CompletedStepsLabels.argoCDInitializeCheck
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(record, &fields); err != nil {
+		return values
 	}
-	if cluster.ArgoCDInitializeCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.argoCDInitializeCheck)
-		nextStep = CompletedStepsLabels.vaultInitializedCheck
+
+	for name, value := range fields {
+		if done, ok := value.(bool); ok && strings.HasSuffix(name, "_check") {
+			values[name] = done
+		}
 	}
-	if cluster.VaultInitializedCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.vaultInitializedCheck)
-		nextStep = CompletedStepsLabels.vaultTerraformApplyCheck
+	return values
+}
+
+// checkLabel names the step of a check this release of kubefirst does not know, e.g.
+// catalog_apps_installed_check becomes "Catalog apps installed"
+func checkLabel(check string) string {
+	label := strings.ReplaceAll(strings.TrimSuffix(check, "_check"), "_", " ")
+	if label == "" {
+		return check
 	}
-	if cluster.VaultTerraformApplyCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.vaultTerraformApplyCheck)
-		nextStep = CompletedStepsLabels.usersTerraformApplyCheck
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// buildDeprovisionSteps lists the deletion steps the console has completed and the one in progress
+func buildDeprovisionSteps(cluster types.Cluster) ([]string, string) {
+	completedSteps := []string{}
+	nextStep := ""
+	for _, step := range deprovisionSteps {
+		if step.done(cluster) && nextStep == "" {
+			completedSteps = append(completedSteps, step.label)
+			continue
+		}
+		if nextStep == "" {
+			nextStep = step.label
+		}
 	}
-	if cluster.UsersTerraformApplyCheck {
-		completedSteps = append(completedSteps, CompletedStepsLabels.usersTerraformApplyCheck)
-		nextStep = "Wrapping up"
+	if nextStep == "" {
+		nextStep = "Removing cluster record"
 	}
 
 	return completedSteps, nextStep
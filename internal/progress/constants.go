@@ -6,19 +6,53 @@ See the LICENSE file for more details.
 */
 package progress
 
-var CompletedStepsLabels = ProvisionSteps{
-	installToolsCheck:          "Installing tools",
-	domainLivenessCheck:        "Domain liveness check",
-	kbotSetupCheck:             "Kbot setup",
-	gitInitCheck:               "Initializing Git",
-	gitopsReadyCheck:           "Initializing GitOps",
-	gitTerraformApplyCheck:     "Git Terraform apply",
-	gitopsPushedCheck:          "GitOps repos pushed",
-	cloudTerraformApplyCheck:   "Cloud Terraform apply",
-	clusterSecretsCreatedCheck: "Creating cluster secrets",
-	argoCDInstallCheck:         "Installing ArgoCD",
-	argoCDInitializeCheck:      "Initializing ArgoCD",
-	vaultInitializedCheck:      "Initializing Vault",
-	vaultTerraformApplyCheck:   "Vault Terraform apply",
-	usersTerraformApplyCheck:   "Users Terraform apply",
+import (
+	"time"
+
+	"github.com/konstructio/kubefirst-api/pkg/types"
+)
+
+// clusterWatchInterval is the polling interval used when the console does not stream cluster events
+const clusterWatchInterval = 10 * time.Second
+
+// provisionSteps follow the order in which the console provisions a cluster
+var provisionSteps = []ProvisionStep{
+	{Check: "install_tools_check", Label: "Installing tools"},
+	{Check: "domain_liveness_check", Label: "Domain liveness check"},
+	{Check: "kbot_setup_check", Label: "Kbot setup"},
+	{Check: "git_init_check", Label: "Initializing Git"},
+	{Check: "gitops_ready_check", Label: "Initializing GitOps"},
+	{Check: "git_terraform_apply_check", Label: "Git Terraform apply"},
+	{Check: "gitops_pushed_check", Label: "GitOps repos pushed"},
+	{Check: "cloud_terraform_apply_check", Label: "Cloud Terraform apply"},
+	{Check: "cluster_secrets_created_check", Label: "Creating cluster secrets"},
+	{Check: "argocd_install_check", Label: "Installing ArgoCD"},
+	{Check: "argocd_initialize_check", Label: "Initializing ArgoCD"},
+	{Check: "vault_initialized_check", Label: "Initializing Vault"},
+	{Check: "vault_terraform_apply_check", Label: "Vault Terraform apply"},
+	{Check: "users_terraform_apply_check", Label: "Users Terraform apply"},
+}
+
+// providerStepOverrides adapt provisionSteps to what the console runs on each cloud provider
+var providerStepOverrides = map[string]StepOverrides{
+	"aws": {
+		Add: []AddedStep{
+			{ProvisionStep: ProvisionStep{Check: "aws_kms_key_detokenized_check", Label: "Detokenizing KMS key"}, After: "cloud_terraform_apply_check"},
+		},
+	},
+	"google": {
+		Relabel: map[string]string{"cloud_terraform_apply_check": "Creating GKE cluster"},
+	},
+	"k3s": {
+		Relabel: map[string]string{"cloud_terraform_apply_check": "Installing k3s"},
+	},
+}
+
+// finalProvisionStep is in progress once every known check is set, until the console reports the cluster provisioned
+const finalProvisionStep = "Wrapping up"
+
+// deprovisionSteps follow the order in which the console destroys a cluster
+var deprovisionSteps = []deprovisionStep{
+	{label: "Cloud Terraform destroy", done: func(cluster types.Cluster) bool { return !cluster.CloudTerraformApplyCheck }},
+	{label: "Git Terraform destroy", done: func(cluster types.Cluster) bool { return !cluster.GitTerraformApplyCheck }},
 }
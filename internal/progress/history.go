@@ -0,0 +1,261 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/konstructio/kubefirst/internal/contexts"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// historyFileName is kept in ~/.k1/<cluster> next to the other files of the cluster
+	historyFileName = "provisioning-history.json"
+	// maxHistoryRuns bounds the runs kept per cluster
+	maxHistoryRuns = 20
+)
+
+// StepTiming records when a provisioning step started and finished. Steps that
+// had already completed when the progress terminal attached have no start.
+type StepTiming struct {
+	Step     string    `json:"step"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// Duration is how long the step took, zero when its start is unknown
+func (t StepTiming) Duration() time.Duration {
+	if t.Started.IsZero() || t.Finished.IsZero() {
+		return 0
+	}
+	return t.Finished.Sub(t.Started)
+}
+
+// ProvisioningRun is a provisioning attempt of a cluster followed by the progress terminal
+type ProvisioningRun struct {
+	Cluster  string       `json:"cluster"`
+	Provider string       `json:"provider"`
+	Status   string       `json:"status"`
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Steps    []StepTiming `json:"steps"`
+}
+
+// Duration is how long the run was followed for
+func (r ProvisioningRun) Duration() time.Duration {
+	return r.Finished.Sub(r.Started)
+}
+
+func historyFilePath(clusterName string) (string, error) {
+	k1Dir, err := contexts.CurrentK1Dir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate the kubefirst directory: %w", err)
+	}
+	return filepath.Join(k1Dir, clusterName, historyFileName), nil
+}
+
+// LoadHistory returns the recorded provisioning runs of a cluster, oldest first
+func LoadHistory(clusterName string) ([]ProvisioningRun, error) {
+	path, err := historyFilePath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return readHistoryFile(path)
+}
+
+func readHistoryFile(path string) ([]ProvisioningRun, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read provisioning history %q: %w", path, err)
+	}
+
+	var runs []ProvisioningRun
+	if err := json.Unmarshal(content, &runs); err != nil {
+		return nil, fmt.Errorf("unable to parse provisioning history %q: %w", path, err)
+	}
+	return runs, nil
+}
+
+// saveRun appends run to the history of its cluster, dropping the oldest runs
+func saveRun(run ProvisioningRun) error {
+	path, err := historyFilePath(run.Cluster)
+	if err != nil {
+		return err
+	}
+
+	runs, err := readHistoryFile(path)
+	if err != nil {
+		return err
+	}
+	runs = append(runs, run)
+	if len(runs) > maxHistoryRuns {
+		runs = runs[len(runs)-maxHistoryRuns:]
+	}
+
+	content, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode provisioning history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("unable to create %q: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		return fmt.Errorf("unable to write provisioning history %q: %w", path, err)
+	}
+	return nil
+}
+
+// estimate is the typical duration of each provisioning step on a provider
+type estimate struct {
+	runs  int
+	order []string
+	steps map[string]time.Duration
+	total time.Duration
+}
+
+// estimateFor derives the duration of each step from the successful runs of
+// every cluster of the current context on provider
+func estimateFor(provider string) (estimate, bool) {
+	if provider == "" {
+		return estimate{}, false
+	}
+
+	k1Dir, err := contexts.CurrentK1Dir()
+	if err != nil {
+		return estimate{}, false
+	}
+	paths, err := filepath.Glob(filepath.Join(k1Dir, "*", historyFileName))
+	if err != nil {
+		return estimate{}, false
+	}
+
+	est := estimate{steps: map[string]time.Duration{}}
+	samples := map[string][]time.Duration{}
+	totals := []time.Duration{}
+	var latest time.Time
+
+	for _, path := range paths {
+		runs, err := readHistoryFile(path)
+		if err != nil {
+			continue
+		}
+		for _, run := range runs {
+			if run.Provider != provider || run.Status != "provisioned" {
+				continue
+			}
+
+			est.runs++
+			totals = append(totals, run.Duration())
+			if run.Finished.After(latest) {
+				latest = run.Finished
+				est.order = est.order[:0]
+				for _, step := range run.Steps {
+					est.order = append(est.order, step.Step)
+				}
+			}
+			for _, step := range run.Steps {
+				if !step.Started.IsZero() {
+					samples[step.Step] = append(samples[step.Step], step.Duration())
+				}
+			}
+		}
+	}
+
+	if est.runs == 0 {
+		return estimate{}, false
+	}
+
+	for step, durations := range samples {
+		est.steps[step] = median(durations)
+	}
+	est.total = median(totals)
+	return est, true
+}
+
+// remaining is the expected time left once completed steps are done and the
+// current one has been running since currentStarted
+func (e estimate) remaining(completed []string, current string, currentStarted, now time.Time) time.Duration {
+	var left time.Duration
+	for _, step := range e.order {
+		if slices.Contains(completed, step) {
+			continue
+		}
+
+		duration := e.steps[step]
+		if step == current && !currentStarted.IsZero() {
+			duration = max(duration-now.Sub(currentStarted), 0)
+		}
+		left += duration
+	}
+	return left
+}
+
+func median(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	return sorted[len(sorted)/2]
+}
+
+// formatDuration rounds a duration to what is useful for a provisioning step
+func formatDuration(duration time.Duration) string {
+	formatted := duration.Round(time.Second).String()
+	if strings.HasSuffix(formatted, "m0s") {
+		formatted = strings.TrimSuffix(formatted, "0s")
+	}
+	if strings.HasSuffix(formatted, "h0m") {
+		formatted = strings.TrimSuffix(formatted, "0m")
+	}
+	return formatted
+}
+
+// formatEstimate rounds an estimate to the minute
+func formatEstimate(duration time.Duration) string {
+	if duration < time.Minute {
+		return "<1m"
+	}
+	return "~" + formatDuration(duration.Round(time.Minute))
+}
+
+// describe names the runs the estimate comes from
+func (e estimate) describe(provider string) string {
+	if e.runs == 1 {
+		return fmt.Sprintf("1 previous %s run", provider)
+	}
+	return fmt.Sprintf("%d previous %s runs", e.runs, provider)
+}
+
+// durationTable is the markdown table of the time each step took
+func durationTable(steps []StepTiming) string {
+	var b strings.Builder
+	b.WriteString("| Step | Duration |\n| --- | --- |\n")
+
+	var total time.Duration
+	for _, step := range steps {
+		duration := "-"
+		if !step.Started.IsZero() {
+			duration = formatDuration(step.Duration())
+			total += step.Duration()
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", step.Step, duration)
+	}
+	fmt.Fprintf(&b, "| **Total** | **%s** |\n", formatDuration(total))
+
+	return b.String()
+}
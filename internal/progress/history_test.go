@@ -0,0 +1,155 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// useHome keeps the history of the test in a temporary home
+func useHome(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("K1_CONTEXT", "")
+}
+
+// newRun returns a run of cluster on provider that started at started and
+// whose steps took the given durations, one after the other
+func newRun(cluster, provider, status string, started time.Time, steps map[string]time.Duration, order ...string) ProvisioningRun {
+	run := ProvisioningRun{Cluster: cluster, Provider: provider, Status: status, Started: started}
+	at := started
+	for _, step := range order {
+		run.Steps = append(run.Steps, StepTiming{Step: step, Started: at, Finished: at.Add(steps[step])})
+		at = at.Add(steps[step])
+	}
+	run.Finished = at
+	return run
+}
+
+func TestSaveRunKeepsTheLatestRuns(t *testing.T) {
+	useHome(t)
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxHistoryRuns+2; i++ {
+		run := newRun("demo", "civo", "provisioned", started.Add(time.Duration(i)*time.Hour), nil)
+		if err := saveRun(run); err != nil {
+			t.Fatalf("saveRun() error = %v", err)
+		}
+	}
+
+	runs, err := LoadHistory("demo")
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(runs) != maxHistoryRuns {
+		t.Fatalf("LoadHistory() = %d runs, want %d", len(runs), maxHistoryRuns)
+	}
+	if want := started.Add(2 * time.Hour); !runs[0].Started.Equal(want) {
+		t.Errorf("oldest run started at %s, want %s once the oldest runs are dropped", runs[0].Started, want)
+	}
+
+	if runs, err := LoadHistory("unknown"); err != nil || len(runs) != 0 {
+		t.Errorf("LoadHistory() of a cluster without history = %v, %v, want no runs", runs, err)
+	}
+}
+
+func TestEstimateFor(t *testing.T) {
+	useHome(t)
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	runs := []ProvisioningRun{
+		newRun("one", "civo", "provisioned", started, map[string]time.Duration{"tools": time.Minute, "terraform": 10 * time.Minute}, "tools", "terraform"),
+		newRun("one", "civo", "error", started.Add(time.Hour), map[string]time.Duration{"tools": time.Hour}, "tools"),
+		newRun("two", "civo", "provisioned", started.Add(2*time.Hour), map[string]time.Duration{"tools": 3 * time.Minute, "argocd": 2 * time.Minute, "terraform": 20 * time.Minute}, "tools", "argocd", "terraform"),
+		newRun("three", "civo", "provisioned", started.Add(-time.Hour), map[string]time.Duration{"tools": 2 * time.Minute, "terraform": 30 * time.Minute}, "tools", "terraform"),
+		newRun("four", "aws", "provisioned", started, map[string]time.Duration{"tools": time.Hour}, "tools"),
+	}
+	for _, run := range runs {
+		if err := saveRun(run); err != nil {
+			t.Fatalf("saveRun() error = %v", err)
+		}
+	}
+
+	if _, ok := estimateFor("google"); ok {
+		t.Error("estimateFor() of a provider without runs = true, want no estimate")
+	}
+
+	est, ok := estimateFor("civo")
+	if !ok {
+		t.Fatal("estimateFor() = false, want an estimate from the provisioned civo runs")
+	}
+	if est.runs != 3 {
+		t.Errorf("estimate runs = %d, want the 3 provisioned civo runs", est.runs)
+	}
+	if got := strings.Join(est.order, ","); got != "tools,argocd,terraform" {
+		t.Errorf("estimate order = %q, want the steps of the latest run", got)
+	}
+	if est.steps["tools"] != 2*time.Minute || est.steps["terraform"] != 20*time.Minute {
+		t.Errorf("estimate steps = %v, want the median of each step", est.steps)
+	}
+	if est.total != 25*time.Minute {
+		t.Errorf("estimate total = %s, want the median run of 25m", est.total)
+	}
+	if got := est.describe("civo"); got != "3 previous civo runs" {
+		t.Errorf("describe() = %q", got)
+	}
+
+	now := started.Add(time.Minute)
+	if got := est.remaining([]string{"tools"}, "argocd", started, now); got != 21*time.Minute {
+		t.Errorf("remaining() = %s, want the running step shortened by its elapsed time", got)
+	}
+	if got := est.remaining([]string{"tools"}, "argocd", started, started.Add(time.Hour)); got != 20*time.Minute {
+		t.Errorf("remaining() = %s, want an overdue step to count for nothing", got)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		duration     time.Duration
+		wantDuration string
+		wantEstimate string
+	}{
+		{duration: 12 * time.Second, wantDuration: "12s", wantEstimate: "<1m"},
+		{duration: 90*time.Second + 400*time.Millisecond, wantDuration: "1m30s", wantEstimate: "~2m"},
+		{duration: 15 * time.Minute, wantDuration: "15m", wantEstimate: "~15m"},
+		{duration: 2 * time.Hour, wantDuration: "2h", wantEstimate: "~2h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.duration.String(), func(t *testing.T) {
+			if got := formatDuration(tt.duration); got != tt.wantDuration {
+				t.Errorf("formatDuration() = %q, want %q", got, tt.wantDuration)
+			}
+			if got := formatEstimate(tt.duration); got != tt.wantEstimate {
+				t.Errorf("formatEstimate() = %q, want %q", got, tt.wantEstimate)
+			}
+		})
+	}
+}
+
+func TestDurationTable(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	steps := []StepTiming{
+		{Step: "Installing tools", Finished: started},
+		{Step: "Cloud Terraform apply", Started: started, Finished: started.Add(12 * time.Minute)},
+		{Step: "Argo CD install", Started: started.Add(12 * time.Minute), Finished: started.Add(15 * time.Minute)},
+	}
+
+	want := `| Step | Duration |
+| --- | --- |
+| Installing tools | - |
+| Cloud Terraform apply | 12m |
+| Argo CD install | 3m |
+| **Total** | **15m** |
+`
+	if got := durationTable(steps); got != want {
+		t.Errorf("durationTable() = %q, want %q", got, want)
+	}
+}
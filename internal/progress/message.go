@@ -13,9 +13,11 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"time"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/hints"
 	"github.com/spf13/viper"
 )
 
@@ -38,6 +40,7 @@ func createStep(message string) addStep {
 
 	return addStep{
 		message: out,
+		text:    message,
 	}
 }
 
@@ -46,13 +49,25 @@ func createErrorLog(message string) errorMsg {
 
 	return errorMsg{
 		message: out,
+		text:    message,
 	}
 }
 
 // Public Progress Functions
-func DisplayLogHints(estimatedTime int) {
+
+// DisplayLogHints shows where to follow the logs and how long provisioning is
+// expected to take on cloudProvider, from its past runs when there are any and
+// estimatedTime minutes otherwise
+func DisplayLogHints(cloudProvider string, estimatedTime int) {
 	logFile := viper.GetString("k1-paths.log-file")
-	cloudProvider := viper.GetString("kubefirst.cloud-provider")
+	if cloudProvider == "" {
+		cloudProvider = viper.GetString("kubefirst.cloud-provider")
+	}
+
+	estimatedTimeText := fmt.Sprintf("`%s minutes`", strconv.Itoa(estimatedTime))
+	if est, ok := estimateFor(cloudProvider); ok {
+		estimatedTimeText = fmt.Sprintf("`%s` (from %s)", formatEstimate(est.total), est.describe(cloudProvider))
+	}
 
 	documentationLink := "https://docs.kubefirst.io/"
 	if cloudProvider != "" {
@@ -67,45 +82,87 @@ func DisplayLogHints(estimatedTime int) {
 ` + fmt.Sprintf("##### **tail -f -n +1 %s**", logFile) + `
 ### :blue_book: Documentation: ` + documentationLink + `
 
-### :alarm_clock: Estimated time:` + fmt.Sprintf("`%s minutes` \n\n", strconv.Itoa(estimatedTime))
+### :alarm_clock: Estimated time: ` + estimatedTimeText + "\n\n"
 
 	headerMessage := renderMessage(header)
 
 	Progress.Send(headerMsg{
 		message: headerMessage,
+		text:    header,
 	})
 }
 
-//nolint:revive // will be fixed in the future
-func DisplaySuccessMessage(cluster types.Cluster) successMsg {
-	cloudCliKubeconfig := ""
+// DisplayRetryHints shows why a cluster failed before its provisioning is retried
+func DisplayRetryHints(cluster types.Cluster) {
+	logFile := viper.GetString("k1-paths.log-file")
 
-	gitProviderLabel := "GitHub"
-	if cluster.GitProvider == "gitlab" {
-		gitProviderLabel = "GitLab"
+	lastCondition := cluster.LastCondition
+	if lastCondition == "" {
+		lastCondition = "no condition was reported by the console"
 	}
 
-	switch cluster.CloudProvider {
-	case "aws":
-		cloudCliKubeconfig = fmt.Sprintf("aws eks update-kubeconfig --name %q --region %q", cluster.ClusterName, cluster.CloudRegion)
-	case "civo":
-		cloudCliKubeconfig = fmt.Sprintf("civo kubernetes config %q --save", cluster.ClusterName)
-	case "digitalocean":
-		cloudCliKubeconfig = "doctl kubernetes cluster kubeconfig save " + cluster.ClusterName
-	case "google":
-		cloudCliKubeconfig = fmt.Sprintf("gcloud container clusters get-credentials %q --region=%q", cluster.ClusterName, cluster.CloudRegion)
-	case "vultr":
-		cloudCliKubeconfig = fmt.Sprintf("vultr-cli kubernetes config %q", cluster.ClusterName)
-	case "k3s":
-		cloudCliKubeconfig = "use the kubeconfig file outputted from terraform to access the cluster"
-	}
+	header := `
+##
+# Retrying cluster ` + cluster.ClusterName + `
+
+### :no_entry_sign: Last condition:
+##### ` + lastCondition + `
+` + hints.Markdown(hints.Match(cluster)) + `
+
+### :bulb: To view verbose logs run below command in new terminal:
+` + fmt.Sprintf("##### **tail -f -n +1 %s**", logFile)
+
+	headerMessage := renderMessage(header)
+
+	Progress.Send(headerMsg{
+		message: headerMessage,
+		text:    header,
+	})
+}
+
+// DisplayDeleteHints shows the cluster about to be deleted
+func DisplayDeleteHints(cluster types.Cluster) {
+	logFile := viper.GetString("k1-paths.log-file")
+
+	header := `
+##
+# Deleting cluster ` + cluster.ClusterName + `
+
+### Status      ` + fmt.Sprintf("`%s`", cluster.Status) + `
+### Provider    ` + fmt.Sprintf("`%s/%s`", cluster.CloudProvider, cluster.CloudRegion) + `
+### Domain      ` + fmt.Sprintf("`%s`", cluster.DomainName) + `
+
+### :bulb: To view verbose logs run below command in new terminal:
+` + fmt.Sprintf("##### **tail -f -n +1 %s**", logFile)
+
+	Progress.Send(headerMsg{
+		message: renderMessage(header),
+		text:    header,
+	})
+}
+
+//nolint:revive // will be fixed in the future
+func DisplayDeleteSuccessMessage(clusterName string) successMsg {
+	success := `
+##
+#### :tada: Success` + "`Cluster " + clusterName + " was deleted`" + `
 
-	var fullDomainName string
-	if cluster.SubdomainName != "" {
-		fullDomainName = fmt.Sprintf("%s.%s", cluster.SubdomainName, cluster.DomainName)
-	} else {
-		fullDomainName = cluster.DomainName
+### :bulb: To view the remaining clusters run:
+##### kubefirst launch cluster list
+`
+
+	return successMsg{
+		message: renderMessage(success),
+		text:    success,
 	}
+}
+
+//nolint:revive // will be fixed in the future
+func DisplaySuccessMessage(cluster types.Cluster) successMsg {
+	cloudCliKubeconfig := kubeconfigInstructions(cluster)
+	gitLabel := gitProviderLabel(cluster.GitProvider)
+	fullDomainName := clusterDomain(cluster)
+	repos := repositoryURLs(cluster)
 
 	success := `
 ##
@@ -116,10 +173,10 @@ func DisplaySuccessMessage(cluster types.Cluster) successMsg {
 ### :bulb: To retrieve root credentials for your Kubefirst platform run:
 ##### kubefirst ` + cluster.CloudProvider + ` root-credentials
 
-## ` + fmt.Sprintf("`%s `", gitProviderLabel) + `
+## ` + fmt.Sprintf("`%s `", gitLabel) + `
 ### Git Owner   ` + fmt.Sprintf("`%s`", cluster.GitAuth.Owner) + `
-### Repos       ` + fmt.Sprintf("`https://%s.com/%s/gitops` \n\n", cluster.GitProvider, cluster.GitAuth.Owner) +
-		fmt.Sprintf("`            https://%s.com/%s/metaphor`", cluster.GitProvider, cluster.GitAuth.Owner) + `
+### Repos       ` + fmt.Sprintf("`%s` \n\n", repos[0]) +
+		fmt.Sprintf("`            %s`", repos[1]) + `
 ## Kubefirst Console
 ### URL         ` + fmt.Sprintf("`https://kubefirst.%s`", fullDomainName) + `
 ## Argo CD
@@ -141,6 +198,35 @@ func DisplaySuccessMessage(cluster types.Cluster) successMsg {
 
 	return successMsg{
 		message: successMessage,
+		text:    success,
+	}
+}
+
+//nolint:revive // will be fixed in the future
+func DisplayWorkloadSuccessMessage(parent types.Cluster, workload types.WorkloadCluster) successMsg {
+	environment := workload.Environment.Name
+	if environment == "" {
+		environment = "none"
+	}
+
+	success := `
+##
+#### :tada: Success` + "`Workload cluster " + workload.ClusterName + " is now up and running`" + `
+
+# Cluster ` + workload.ClusterName + ` details:
+
+### Management cluster ` + fmt.Sprintf("`%s`", parent.ClusterName) + `
+### Environment        ` + fmt.Sprintf("`%s`", environment) + `
+### Provider           ` + fmt.Sprintf("`%s/%s`", workload.CloudProvider, workload.CloudRegion) + `
+### Nodes              ` + fmt.Sprintf("`%d x %s`", workload.NodeCount, workload.NodeType) + `
+
+### :bulb: To view the cluster run:
+##### kubefirst launch cluster describe ` + parent.ClusterName + `
+`
+
+	return successMsg{
+		message: renderMessage(success),
+		text:    success,
 	}
 }
 
@@ -165,11 +251,36 @@ func DisplayCredentials(cluster types.Cluster) {
 
 	Progress.Send(headerMsg{
 		message: headerMessage,
+		text:    header,
 	})
 
 	Progress.Quit()
 }
 
+// Confirm asks a yes/no question in the progress terminal and blocks until the
+// user answers. Any key other than y declines.
+func Confirm(prompt string) bool {
+	answer := make(chan bool, 1)
+	Progress.Send(confirmMsg{
+		prompt: prompt,
+		answer: answer,
+	})
+
+	return <-answer
+}
+
+// Prompt asks for a line of text in the progress terminal and blocks until the
+// user presses enter. An empty string is returned when the prompt is dismissed.
+func Prompt(prompt string) string {
+	answer := make(chan string, 1)
+	Progress.Send(inputMsg{
+		prompt: prompt,
+		answer: answer,
+	})
+
+	return <-answer
+}
+
 func AddStep(message string) {
 	renderedMessage := createStep(fmt.Sprintf("%s %s", ":dizzy:", message))
 	Progress.Send(renderedMessage)
@@ -187,10 +298,12 @@ func Success(success string) {
 	Progress.Send(
 		successMsg{
 			message: successMessage,
+			text:    success,
 		})
 }
 
 func Error(message string) {
+	failed.Store(true)
 	renderedMessage := createErrorLog(message)
 	Progress.Send(renderedMessage)
 }
@@ -202,3 +315,24 @@ func StartProvisioning(clusterName string) {
 
 	Progress.Send(provisioningMessage)
 }
+
+func StartWorkloadProvisioning(parentName, clusterName string) {
+	Progress.Send(startProvision{
+		clusterName:         parentName,
+		workloadClusterName: clusterName,
+	})
+}
+
+// StartDeprovisioning follows the deletion of cluster and returns a channel
+// receiving the outcome, nil once the console has removed the cluster. A zero
+// timeout waits until the deletion completes.
+func StartDeprovisioning(cluster types.Cluster, timeout time.Duration) <-chan error {
+	done := make(chan error, 1)
+	Progress.Send(startDeprovision{
+		cluster: cluster,
+		timeout: timeout,
+		done:    done,
+	})
+
+	return done
+}
@@ -0,0 +1,207 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
+	"golang.org/x/term"
+)
+
+// Output modes of the progress terminal
+const (
+	// OutputTTY draws the interactive terminal
+	OutputTTY = "tty"
+	// OutputPlain prints one line per step transition
+	OutputPlain = "plain"
+	// OutputJSON prints one NDJSON event per step transition
+	OutputJSON = "json"
+)
+
+// OutputModes lists the values accepted by the --progress flag
+var OutputModes = []string{OutputTTY, OutputPlain, OutputJSON}
+
+// Status of a step transition
+const (
+	StatusInfo      = "info"
+	StatusStarted   = "started"
+	StatusCompleted = "completed"
+	StatusWaiting   = "waiting"
+	StatusFailed    = "failed"
+	StatusSucceeded = "succeeded"
+)
+
+// Event is a step transition reported by the plain and json output modes
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Status    string    `json:"status"`
+	Step      string    `json:"step,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	// Duration of a completed step
+	Duration float64 `json:"duration_seconds,omitempty"`
+}
+
+var (
+	outputMode   = OutputTTY
+	outputMu     sync.Mutex
+	outputWriter io.Writer = os.Stdout
+	promptWriter io.Writer = os.Stderr
+	promptReader           = bufio.NewReader(os.Stdin)
+)
+
+// DetectOutputMode validates the requested output mode. When none is requested
+// the terminal is drawn only if stdout is an interactive terminal.
+func DetectOutputMode(requested string) (string, error) {
+	if requested != "" {
+		if !slices.Contains(OutputModes, requested) {
+			return "", fmt.Errorf("unsupported output %q - one of: %s", requested, OutputModes)
+		}
+		return requested, nil
+	}
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) || os.Getenv("TERM") == "dumb" {
+		return OutputPlain, nil
+	}
+	return OutputTTY, nil
+}
+
+// report prints a step transition, the tty output mode draws it instead
+func report(event Event) {
+	if outputMode == OutputTTY {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if outputMode == OutputJSON {
+		line, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("unable to encode progress event: %v", err)
+			return
+		}
+		fmt.Fprintln(outputWriter, string(line))
+		return
+	}
+
+	fmt.Fprintln(outputWriter, formatPlainEvent(event))
+}
+
+// ReportFailure reports a failed command as an event, unless its failure was
+// already shown
+func ReportFailure(message string) {
+	if failed.Swap(true) {
+		return
+	}
+	report(Event{Status: StatusFailed, Message: message})
+}
+
+// MessageWriter is where messages outside of the progress are printed. The
+// plain and json outputs only hold events on stdout, messages go to stderr.
+func MessageWriter() io.Writer {
+	if outputMode == OutputTTY {
+		return os.Stdout
+	}
+	return promptWriter
+}
+
+// formatPlainEvent prints the event on one line, continuation lines of long messages are indented
+func formatPlainEvent(event Event) string {
+	text := event.Step
+	switch {
+	case text == "":
+		text = event.Message
+	case event.Message != "":
+		text += ": " + event.Message
+	}
+	if event.Duration > 0 {
+		text += fmt.Sprintf(" (%s)", formatDuration(time.Duration(event.Duration*float64(time.Second))))
+	}
+
+	prefix := fmt.Sprintf("%s %-9s", event.Timestamp.Format(time.RFC3339), event.Status)
+	if event.Cluster != "" {
+		prefix += " [" + event.Cluster + "]"
+	}
+
+	return prefix + " " + strings.ReplaceAll(text, "\n", "\n    ")
+}
+
+var emojiPattern = regexp.MustCompile(`:[a-z][a-z0-9_+-]*:`)
+
+// plainText strips the markdown of a progress message, keeping one line per non-empty line
+func plainText(markdown string) string {
+	lines := []string{}
+	for _, line := range strings.Split(markdown, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "#")
+		line = emojiPattern.ReplaceAllString(line, "")
+		line = strings.TrimSpace(stripInlineMarkup(line))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// stripInlineMarkup removes bold markers and code spans, keeping code spans
+// apart from the word they are attached to
+func stripInlineMarkup(line string) string {
+	line = strings.ReplaceAll(line, "**", "")
+
+	var b strings.Builder
+	inCode := false
+	for _, r := range line {
+		if r != '`' {
+			b.WriteRune(r)
+			continue
+		}
+		if !inCode && b.Len() > 0 && !strings.HasSuffix(b.String(), " ") {
+			b.WriteByte(' ')
+		}
+		inCode = !inCode
+	}
+
+	return b.String()
+}
+
+// askLine asks a question on stderr outside of the tty output mode and passes
+// the line read from stdin to answer, an empty one when stdin is closed
+func askLine(prompt string, answer func(string)) tea.Cmd {
+	outputMu.Lock()
+	fmt.Fprintf(promptWriter, "%s ", plainText(prompt))
+	outputMu.Unlock()
+
+	return func() tea.Msg {
+		line, err := promptReader.ReadString('\n')
+		// a terminal echoes the answer, piped answers need their own line break
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Fprintln(promptWriter)
+		}
+		if err != nil && line == "" {
+			answer("")
+			return nil
+		}
+
+		answer(strings.TrimSpace(line))
+		return nil
+	}
+}
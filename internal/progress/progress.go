@@ -7,15 +7,33 @@ See the LICENSE file for more details.
 package progress
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/hints"
+	"github.com/konstructio/kubefirst/internal/notify"
 	"github.com/spf13/viper"
+	"golang.org/x/exp/slices"
 )
 
 var Progress *tea.Program
 
+// failed records that the terminal showed a failure, including ones reported
+// after the command returned such as a cluster that ended in error
+var failed atomic.Bool
+
+// Failed reports whether the terminal showed a failure
+func Failed() bool {
+	return failed.Load()
+}
+
 //nolint:revive // will be removed after refactoring
 func NewModel() progressModel {
 	return progressModel{
@@ -24,8 +42,15 @@ func NewModel() progressModel {
 }
 
 // Bubbletea functions
-func InitializeProgressTerminal() {
-	Progress = tea.NewProgram(NewModel())
+func InitializeProgressTerminal(mode string) {
+	outputMode = mode
+	if mode == OutputTTY {
+		Progress = tea.NewProgram(NewModel())
+		return
+	}
+
+	// steps are printed as they happen, the terminal is neither drawn nor read
+	Progress = tea.NewProgram(NewModel(), tea.WithoutRenderer(), tea.WithInput(nil))
 }
 
 func (m progressModel) Init() tea.Cmd {
@@ -35,66 +60,429 @@ func (m progressModel) Init() tea.Cmd {
 func (m progressModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.inputAnswer != nil {
+			return m.updateInput(msg)
+		}
+
+		if m.confirmAnswer != nil {
+			confirmed := msg.String() == "y" || msg.String() == "Y"
+			m.confirmAnswer <- confirmed
+			m.confirmAnswer = nil
+			m.confirmPrompt = ""
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c":
+			if m.deprovisionDone != nil {
+				m.deprovisionDone <- fmt.Errorf("stopped waiting for cluster %s to be deleted, deletion continues in the console", m.clusterName)
+				m.deprovisionDone = nil
+			}
 			return m, tea.Quit
 		default:
 			return m, nil
 		}
 
+	case confirmMsg:
+		if outputMode != OutputTTY {
+			return m, askLine(msg.prompt+" [y/N]", func(answer string) {
+				msg.answer <- answer == "y" || answer == "Y"
+			})
+		}
+		m.confirmPrompt = renderMessage(fmt.Sprintf(":question: %s **[y/N]**", msg.prompt))
+		m.confirmAnswer = msg.answer
+		return m, nil
+
+	case inputMsg:
+		if outputMode != OutputTTY {
+			return m, askLine(msg.prompt, func(answer string) {
+				msg.answer <- answer
+			})
+		}
+		m.inputPrompt = renderMessage(fmt.Sprintf(":question: %s", msg.prompt))
+		m.input = ""
+		m.inputAnswer = msg.answer
+		return m, nil
+
 	case headerMsg:
 		m.header = msg.message
+		report(Event{Status: StatusInfo, Message: plainText(msg.text)})
 		return m, nil
 
 	case addStep:
 		m.nextStep = msg.message
+		m.reportProgress(nil, plainText(msg.text))
 		return m, nil
 
 	case completeStep:
 		m.completedSteps = append(m.completedSteps, msg.message)
 		m.nextStep = ""
+		m.reportProgress([]string{msg.message}, "")
 		return m, nil
 
 	case errorMsg:
 		m.error = msg.message
+		report(Event{Cluster: m.reportedCluster(), Status: StatusFailed, Step: m.currentStep, Message: plainText(msg.text)})
 		return m, tea.Quit
 
 	case successMsg:
 		m.successMessage = msg.message + "\n\n"
+		report(Event{Cluster: m.reportedCluster(), Status: StatusSucceeded, Message: plainText(msg.text)})
 		return m, tea.Quit
 
 	case startProvision:
 		m.clusterName = msg.clusterName
-		return m, GetClusterInterval(m.clusterName)
+		m.workloadClusterName = msg.workloadClusterName
+		m.provisionStarted = time.Now()
+		m.stepStarted = m.provisionStarted
+		ctx, cancel := context.WithCancel(context.Background())
+		m.cancelWatch = cancel
+		m.clusterEvents = cluster.WatchCluster(ctx, m.clusterName, clusterWatchInterval)
+		return m, WaitForClusterEvent(m.clusterEvents)
+
+	case startDeprovision:
+		m.clusterName = msg.cluster.ClusterName
+		m.provisioningCluster = msg.cluster
+		m.deprovisioning = true
+		m.deprovisionInitStatus = msg.cluster.Status
+		m.deprovisionDone = msg.done
+		completedSteps, nextStep := buildDeprovisionSteps(msg.cluster)
+		m.completedSteps = completedSteps
+		m.nextStep = renderMessage(fmt.Sprintf(":dizzy: %s", nextStep))
+		m.reportProgress(completedSteps, nextStep)
+		ctx, cancel := context.WithCancel(context.Background())
+		m.cancelWatch = cancel
+		m.clusterEvents = cluster.WatchCluster(ctx, m.clusterName, clusterWatchInterval)
+
+		cmds := []tea.Cmd{WaitForClusterEvent(m.clusterEvents)}
+		if msg.timeout > 0 {
+			cmds = append(cmds, tea.Tick(msg.timeout, func(_ time.Time) tea.Msg {
+				return deprovisionTimeoutMsg{timeout: msg.timeout}
+			}))
+		}
+		return m, tea.Batch(cmds...)
+
+	case deprovisionTimeoutMsg:
+		if m.deprovisionDone == nil {
+			return m, nil
+		}
+		return m.finishDeprovision(fmt.Errorf("timed out after %s waiting for cluster %s to be deleted, deletion continues in the console", msg.timeout, m.clusterName))
+
+	case clusterReconnectingMsg:
+		// the console forgets a cluster once it is deleted
+		if m.deprovisioning && errors.Is(msg.err, cluster.ErrNotFound) {
+			return m.finishDeprovision(nil)
+		}
+
+		reconnecting := fmt.Sprintf("Lost connection to the kubefirst console, reconnecting in %s (%s)", msg.retryIn.Round(time.Second), msg.err)
+		m.reconnecting = renderMessage(":hourglass: " + reconnecting)
+		report(Event{Cluster: m.reportedCluster(), Status: StatusWaiting, Step: m.currentStep, Message: reconnecting})
+		return m, WaitForClusterEvent(m.clusterEvents)
 
 	case CusterProvisioningMsg:
-		m.provisioningCluster = types.Cluster(msg)
-		completedSteps, nextStep := BuildCompletedSteps(types.Cluster(msg))
-		m.completedSteps = append(m.completedSteps, completedSteps...)
+		m.reconnecting = ""
+		if m.deprovisioning {
+			return m.updateDeprovisionProgress(msg.cluster)
+		}
+		if m.workloadClusterName != "" {
+			return m.updateWorkloadProgress(msg.cluster)
+		}
+
+		m.provisioningCluster = msg.cluster
+		completedSteps, nextStep := BuildCompletedSteps(msg.cluster, msg.checks)
+		// every update lists all the completed steps
+		for _, step := range completedSteps {
+			if !slices.Contains(m.completedSteps, step) {
+				m.completedSteps = append(m.completedSteps, step)
+			}
+		}
 		m.nextStep = renderMessage(fmt.Sprintf(":dizzy: %s", nextStep))
 
+		if !m.clusterObserved && len(completedSteps) > 1 {
+			// attached to a cluster that was already provisioning, when its
+			// first steps ran is unknown
+			m.stepStarted = time.Time{}
+		}
+		m.clusterObserved = true
+		m.reportProgress(completedSteps, nextStep)
+
 		if m.provisioningCluster.Status == "error" {
-			errorMessage := createErrorLog(m.provisioningCluster.LastCondition)
-			m.error = errorMessage.message
-			return m, tea.Quit
+			m.cancelWatch()
+			m.fail(m.provisioningCluster.LastCondition)
+			if matched := hints.Match(m.provisioningCluster); len(matched) > 0 {
+				remediation := hints.Markdown(matched)
+				m.error += renderMessage(remediation)
+				report(Event{Cluster: m.reportedCluster(), Status: StatusInfo, Message: plainText(remediation)})
+			}
+			m.recordRun("error")
+			notification := m.sendNotification(m.provisioningCluster.CloudProvider, "error", m.provisioningCluster.LastCondition)
+			m.error += renderMessage("### :stopwatch: Step durations\n\n" + durationTable(m.stepTimings))
+			return m, tea.Sequence(notification, tea.Quit)
 		}
 
 		if m.provisioningCluster.Status == "provisioned" {
 			m.isProvisioned = true
 			m.nextStep = ""
+			m.cancelWatch()
 			viper.Set("kubefirst-checks.cluster-install-complete", true)
 			viper.WriteConfig()
 
-			return m, AddSuccesMessage(m.provisioningCluster)
+			m.reportProgress([]string{m.currentStep}, "")
+			m.recordRun("provisioned")
+			notification := m.sendNotification(m.provisioningCluster.CloudProvider, "provisioned", "")
+
+			summaryPath, err := WriteSummary(NewSummary(m.provisioningCluster, m.stepTimings))
+			if err != nil {
+				log.Printf("unable to write the summary of %s: %v", m.clusterName, err)
+			}
+			return m, tea.Sequence(notification, AddSuccesMessage(m.provisioningCluster, m.stepTimings, summaryPath))
 		}
 
-		return m, GetClusterInterval(m.clusterName)
+		m.updateETA(time.Now())
+		return m, WaitForClusterEvent(m.clusterEvents)
 
 	default:
 		return m, nil
 	}
 }
 
+// updateWorkloadProgress follows a workload cluster through the record of its management cluster
+func (m progressModel) updateWorkloadProgress(parent types.Cluster) (tea.Model, tea.Cmd) {
+	m.provisioningCluster = parent
+	nextStep := fmt.Sprintf("Waiting for %s to register workload cluster %s", parent.ClusterName, m.workloadClusterName)
+
+	for _, workload := range parent.WorkloadClusters {
+		if workload.ClusterName != m.workloadClusterName {
+			continue
+		}
+
+		switch workload.Status {
+		case "error":
+			m.cancelWatch()
+			failure := fmt.Sprintf("workload cluster %s failed to provision, check the Kubefirst console of %s for details", workload.ClusterName, parent.ClusterName)
+			m.fail(failure)
+			return m, tea.Sequence(m.sendNotification(workload.CloudProvider, "error", failure), tea.Quit)
+		case "provisioned":
+			m.isProvisioned = true
+			m.nextStep = ""
+			m.cancelWatch()
+			return m, tea.Sequence(m.sendNotification(workload.CloudProvider, "provisioned", ""), AddWorkloadSuccessMessage(parent, workload))
+		}
+
+		nextStep = fmt.Sprintf("Provisioning workload cluster %s (%s)", workload.ClusterName, workload.Status)
+	}
+
+	m.nextStep = renderMessage(fmt.Sprintf(":dizzy: %s", nextStep))
+	m.reportProgress(nil, nextStep)
+	return m, WaitForClusterEvent(m.clusterEvents)
+}
+
+// updateInput edits the answer of a text prompt, enter submits it and esc or ctrl+c submit nothing
+func (m progressModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter, tea.KeyEsc, tea.KeyCtrlC:
+		answer := m.input
+		if msg.Type != tea.KeyEnter {
+			answer = ""
+		}
+		m.inputAnswer <- answer
+		m.inputAnswer = nil
+		m.inputPrompt = ""
+		m.input = ""
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+	case tea.KeyBackspace:
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.input += string(msg.Runes)
+	default:
+	}
+
+	return m, nil
+}
+
+// updateDeprovisionProgress follows a cluster being deleted until the console reports an outcome
+func (m progressModel) updateDeprovisionProgress(cl types.Cluster) (tea.Model, tea.Cmd) {
+	m.provisioningCluster = cl
+
+	switch cl.Status {
+	case "deleting":
+		m.deprovisionSeen = true
+	case "deleted":
+		return m.finishDeprovision(nil)
+	case "error":
+		// a cluster that failed to provision reports its old error until the deletion starts
+		if m.deprovisionSeen || m.deprovisionInitStatus != "error" {
+			lastCondition := cl.LastCondition
+			if lastCondition == "" {
+				lastCondition = "no condition was reported by the console"
+			}
+			return m.finishDeprovision(fmt.Errorf("cluster %s failed to deprovision: %s", cl.ClusterName, lastCondition))
+		}
+	}
+
+	completedSteps, nextStep := buildDeprovisionSteps(cl)
+	m.completedSteps = completedSteps
+	m.nextStep = renderMessage(fmt.Sprintf(":dizzy: %s", nextStep))
+	m.reportProgress(completedSteps, nextStep)
+	return m, WaitForClusterEvent(m.clusterEvents)
+}
+
+// finishDeprovision reports the outcome of a deletion to the waiting command and quits
+func (m progressModel) finishDeprovision(err error) (tea.Model, tea.Cmd) {
+	m.cancelWatch()
+	if m.deprovisionDone != nil {
+		m.deprovisionDone <- err
+		m.deprovisionDone = nil
+	}
+
+	if err != nil {
+		m.fail(err.Error())
+		return m, tea.Quit
+	}
+
+	// the console may forget the cluster before its last steps were seen
+	completedSteps := make([]string, 0, len(deprovisionSteps))
+	for _, step := range deprovisionSteps {
+		completedSteps = append(completedSteps, step.label)
+	}
+	m.reportProgress(completedSteps, "")
+
+	m.nextStep = ""
+	success := DisplayDeleteSuccessMessage(m.clusterName)
+	m.successMessage = success.message + "\n\n"
+	report(Event{Cluster: m.clusterName, Status: StatusSucceeded, Message: plainText(success.text)})
+	return m, tea.Quit
+}
+
+// reportedCluster is the cluster named in the events of the plain and json output modes
+func (m progressModel) reportedCluster() string {
+	if m.workloadClusterName != "" {
+		return m.workloadClusterName
+	}
+	return m.clusterName
+}
+
+// reportProgress reports the steps completed since the last update and the step
+// now in progress. The console lists every completed check on each update, only
+// the new ones are reported.
+func (m *progressModel) reportProgress(completedSteps []string, nextStep string) {
+	now := time.Now()
+	for _, step := range completedSteps {
+		if step == "" || slices.Contains(m.reportedSteps, step) {
+			continue
+		}
+		m.reportedSteps = append(m.reportedSteps, step)
+
+		// steps completed between two updates of the console share its time
+		timing := StepTiming{Step: step, Started: m.stepStarted, Finished: now}
+		m.stepTimings = append(m.stepTimings, timing)
+		m.stepStarted = now
+		report(Event{Cluster: m.reportedCluster(), Status: StatusCompleted, Step: step, Duration: timing.Duration().Seconds()})
+	}
+
+	if nextStep != "" && nextStep != m.currentStep {
+		m.stepStarted = now
+		report(Event{Cluster: m.reportedCluster(), Status: StatusStarted, Step: nextStep})
+	}
+	m.currentStep = nextStep
+}
+
+// recordRun adds the step timings of the provisioning run that just ended to
+// the history of the cluster, a failed step is recorded up to the failure
+func (m *progressModel) recordRun(status string) {
+	if m.clusterName == "" || m.workloadClusterName != "" {
+		return
+	}
+
+	now := time.Now()
+	if m.currentStep != "" && !slices.Contains(m.reportedSteps, m.currentStep) {
+		m.stepTimings = append(m.stepTimings, StepTiming{Step: m.currentStep, Started: m.stepStarted, Finished: now})
+	}
+
+	run := ProvisioningRun{
+		Cluster:  m.clusterName,
+		Provider: m.provisioningCluster.CloudProvider,
+		Status:   status,
+		Started:  m.provisionStarted,
+		Finished: now,
+		Steps:    m.stepTimings,
+	}
+	if err := saveRun(run); err != nil {
+		log.Printf("unable to record the provisioning history of %s: %v", m.clusterName, err)
+	}
+}
+
+// sendNotification tells the webhooks and command of the kubefirst config that
+// provisioning ended. They are sent from a command so they don't block the
+// terminal, callers sequence it before quitting so it is sent before kubefirst exits.
+// The console of a workload cluster is the one of its management cluster.
+func (m *progressModel) sendNotification(provider, status, lastCondition string) tea.Cmd {
+	cfg := notify.Current()
+	if cfg.Empty() {
+		return nil
+	}
+
+	notification := notify.Notification{
+		Cluster:       m.reportedCluster(),
+		Provider:      provider,
+		Status:        status,
+		ConsoleURL:    fmt.Sprintf("https://kubefirst.%s", clusterDomain(m.provisioningCluster)),
+		LastCondition: lastCondition,
+	}
+	if !m.provisionStarted.IsZero() {
+		notification.Duration = time.Since(m.provisionStarted).Seconds()
+	}
+
+	return func() tea.Msg {
+		if err := notify.Send(context.Background(), cfg, notification); err != nil {
+			log.Printf("unable to notify that the provisioning of %s ended: %v", notification.Cluster, err)
+		}
+		return nil
+	}
+}
+
+// updateETA estimates the time left from past runs on the provider of the cluster
+func (m *progressModel) updateETA(now time.Time) {
+	if !m.estimateLoaded {
+		m.estimateLoaded = true
+		if est, ok := estimateFor(m.provisioningCluster.CloudProvider); ok {
+			m.estimate = &est
+		}
+	}
+	if m.estimate == nil {
+		return
+	}
+
+	remaining := m.estimate.remaining(m.reportedSteps, m.currentStep, m.stepStarted, now)
+	if remaining <= 0 {
+		m.eta = renderMessage(fmt.Sprintf(":alarm_clock: Taking longer than expected from %s", m.estimate.describe(m.provisioningCluster.CloudProvider)))
+		return
+	}
+	m.eta = renderMessage(fmt.Sprintf(":alarm_clock: Estimated time remaining: `%s` (from %s)", formatEstimate(remaining), m.estimate.describe(m.provisioningCluster.CloudProvider)))
+}
+
+// fail shows message as the error of the terminal and reports it
+func (m *progressModel) fail(message string) {
+	failed.Store(true)
+	m.error = createErrorLog(message).message
+	report(Event{Cluster: m.reportedCluster(), Status: StatusFailed, Step: m.currentStep, Message: message})
+}
+
+// renderInput shows the active text prompt with what was typed so far
+func (m progressModel) renderInput() string {
+	if m.inputAnswer == nil {
+		return ""
+	}
+	return m.inputPrompt + "  > " + m.input + "█\n\n"
+}
+
 func (m progressModel) View() string {
 	if !m.isProvisioned && m.successMessage == "" {
 		index := 0
@@ -112,6 +500,10 @@ func (m progressModel) View() string {
 			return m.header + "\n\n" +
 				completedSteps +
 				m.nextStep + "\n\n" +
+				m.eta +
+				m.confirmPrompt +
+				m.renderInput() +
+				m.reconnecting +
 				m.error + "\n\n"
 		}
 	}
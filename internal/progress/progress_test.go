@@ -0,0 +1,609 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/notify"
+)
+
+// captureEvents reports the events of the json output mode to the returned
+// buffer and keeps the local files of the test in a temporary home
+func captureEvents(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("K1_CONTEXT", "")
+
+	var buf bytes.Buffer
+	previousMode, previousWriter := outputMode, outputWriter
+	outputMode, outputWriter = OutputJSON, &buf
+	failed.Store(false)
+	t.Cleanup(func() {
+		outputMode, outputWriter = previousMode, previousWriter
+		failed.Store(false)
+	})
+
+	return &buf
+}
+
+func decodeEvents(t *testing.T, buf *bytes.Buffer) []Event {
+	t.Helper()
+
+	var events []Event
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("event %q is not JSON: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func countEvents(events []Event, status, step string) int {
+	count := 0
+	for _, event := range events {
+		if event.Status == status && event.Step == step {
+			count++
+		}
+	}
+	return count
+}
+
+func newProvisioningModel(clusterName string) progressModel {
+	m := NewModel()
+	m.clusterName = clusterName
+	m.provisionStarted = time.Now()
+	m.stepStarted = m.provisionStarted
+	m.cancelWatch = func() {}
+	return m
+}
+
+func update(m progressModel, msg tea.Msg) (progressModel, tea.Cmd) {
+	next, cmd := m.Update(msg)
+	return next.(progressModel), cmd
+}
+
+func provisioningCluster(status string, complete func(cl *types.Cluster)) types.Cluster {
+	cl := types.Cluster{ClusterName: "demo", CloudProvider: "civo", DomainName: "example.com", Status: status}
+	if complete != nil {
+		complete(&cl)
+	}
+	return cl
+}
+
+func everyCheck(cl *types.Cluster) {
+	cl.InstallToolsCheck = true
+	cl.DomainLivenessCheck = true
+	cl.KbotSetupCheck = true
+	cl.GitInitCheck = true
+	cl.GitopsReadyCheck = true
+	cl.GitTerraformApplyCheck = true
+	cl.GitopsPushedCheck = true
+	cl.CloudTerraformApplyCheck = true
+	cl.ClusterSecretsCreatedCheck = true
+	cl.ArgoCDInstallCheck = true
+	cl.ArgoCDInitializeCheck = true
+	cl.VaultInitializedCheck = true
+	cl.VaultTerraformApplyCheck = true
+	cl.UsersTerraformApplyCheck = true
+}
+
+func TestDetectOutputMode(t *testing.T) {
+	t.Setenv("TERM", "dumb")
+
+	tests := []struct {
+		requested string
+		want      string
+		wantErr   bool
+	}{
+		{requested: "", want: OutputPlain},
+		{requested: OutputTTY, want: OutputTTY},
+		{requested: OutputPlain, want: OutputPlain},
+		{requested: OutputJSON, want: OutputJSON},
+		{requested: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.requested, func(t *testing.T) {
+			got, err := DetectOutputMode(tt.requested)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("DetectOutputMode(%q) error = nil, want an unsupported output", tt.requested)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DetectOutputMode(%q) error = %v", tt.requested, err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectOutputMode(%q) = %q, want %q", tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := Event{Timestamp: timestamp, Cluster: "demo", Status: StatusCompleted, Step: "Installing tools", Duration: 90}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: OutputTTY, want: ""},
+		{mode: OutputPlain, want: "2024-05-01T12:00:00Z completed [demo] Installing tools (1m30s)\n"},
+		{mode: OutputJSON, want: `{"timestamp":"2024-05-01T12:00:00Z","cluster":"demo","status":"completed","step":"Installing tools","duration_seconds":90}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			buf := captureEvents(t)
+			outputMode = tt.mode
+
+			report(event)
+			if buf.String() != tt.want {
+				t.Errorf("report() printed %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPlainEvent(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "message",
+			event: Event{Timestamp: timestamp, Status: StatusInfo, Message: "validating the flags"},
+			want:  "2024-05-01T12:00:00Z info      validating the flags",
+		},
+		{
+			name:  "step with a message",
+			event: Event{Timestamp: timestamp, Cluster: "demo", Status: StatusFailed, Step: "Cloud Terraform apply", Message: "unable to create the vpc"},
+			want:  "2024-05-01T12:00:00Z failed    [demo] Cloud Terraform apply: unable to create the vpc",
+		},
+		{
+			name:  "message over several lines",
+			event: Event{Timestamp: timestamp, Status: StatusSucceeded, Message: "cluster provisioned\nconsole: https://kubefirst.example.com"},
+			want:  "2024-05-01T12:00:00Z succeeded cluster provisioned\n    console: https://kubefirst.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatPlainEvent(tt.event); got != tt.want {
+				t.Errorf("formatPlainEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     string
+	}{
+		{markdown: ":dizzy: Installing tools", want: "Installing tools"},
+		{markdown: "### **Cluster** provisioned\n\n### :bulb: run`kubefirst launch cluster list`", want: "Cluster provisioned\nrun kubefirst launch cluster list"},
+		{markdown: "\n\n", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.markdown, func(t *testing.T) {
+			if got := plainText(tt.markdown); got != tt.want {
+				t.Errorf("plainText(%q) = %q, want %q", tt.markdown, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateReportsCompletedStepsOnce(t *testing.T) {
+	buf := captureEvents(t)
+	m := newProvisioningModel("demo")
+
+	firstSteps := provisioningCluster("provisioning", func(cl *types.Cluster) {
+		cl.InstallToolsCheck = true
+		cl.DomainLivenessCheck = true
+	})
+	moreSteps := firstSteps
+	moreSteps.KbotSetupCheck = true
+
+	// the console lists every completed check on each update
+	for _, cl := range []types.Cluster{firstSteps, firstSteps, moreSteps, moreSteps} {
+		m, _ = update(m, CusterProvisioningMsg{cluster: cl})
+	}
+
+	wantSteps := []string{"Installing tools", "Domain liveness check", "Kbot setup"}
+	if fmt.Sprint(m.completedSteps) != fmt.Sprint(wantSteps) {
+		t.Errorf("completed steps = %q, want %q", m.completedSteps, wantSteps)
+	}
+
+	events := decodeEvents(t, buf)
+	for _, step := range wantSteps {
+		if got := countEvents(events, StatusCompleted, step); got != 1 {
+			t.Errorf("%q was reported completed %d times, want once", step, got)
+		}
+	}
+	for _, step := range []string{"Kbot setup", "Initializing Git"} {
+		if got := countEvents(events, StatusStarted, step); got != 1 {
+			t.Errorf("%q was reported started %d times, want once", step, got)
+		}
+	}
+	if len(m.stepTimings) != len(wantSteps) {
+		t.Errorf("step timings = %d, want %d", len(m.stepTimings), len(wantSteps))
+	}
+}
+
+func TestUpdateReportsReconnections(t *testing.T) {
+	buf := captureEvents(t)
+	m := newProvisioningModel("demo")
+
+	m, cmd := update(m, clusterReconnectingMsg{err: cluster.ErrUnavailable, retryIn: 20 * time.Second})
+	if cmd == nil {
+		t.Fatal("a lost connection stopped following the cluster")
+	}
+	if m.reconnecting == "" {
+		t.Error("a lost connection is not shown")
+	}
+	if Failed() {
+		t.Error("a lost connection is reported as a failure")
+	}
+
+	m, _ = update(m, CusterProvisioningMsg{cluster: provisioningCluster("provisioning", nil)})
+	if m.reconnecting != "" {
+		t.Error("the lost connection is still shown once the console answered")
+	}
+	if got := countEvents(decodeEvents(t, buf), StatusWaiting, ""); got != 1 {
+		t.Errorf("waiting events = %d, want 1", got)
+	}
+}
+
+func TestUpdateFailsOnClusterError(t *testing.T) {
+	buf := captureEvents(t)
+	m := newProvisioningModel("demo")
+
+	m, _ = update(m, CusterProvisioningMsg{cluster: provisioningCluster("provisioning", func(cl *types.Cluster) {
+		cl.InstallToolsCheck = true
+	})})
+	m, cmd := update(m, CusterProvisioningMsg{cluster: provisioningCluster("error", func(cl *types.Cluster) {
+		cl.InstallToolsCheck = true
+		cl.LastCondition = "unable to create the vpc"
+	})})
+
+	if cmd == nil {
+		t.Fatal("the terminal does not quit once the cluster ended in error")
+	}
+	if !Failed() {
+		t.Error("Failed() = false once the cluster ended in error")
+	}
+	if m.error == "" {
+		t.Error("the error of the cluster is not shown")
+	}
+
+	var failure *Event
+	for _, event := range decodeEvents(t, buf) {
+		if event.Status == StatusFailed {
+			failure = &event
+		}
+	}
+	if failure == nil || failure.Message != "unable to create the vpc" || failure.Step != "Domain liveness check" {
+		t.Errorf("failed event = %+v, want the last condition on the step in progress", failure)
+	}
+
+	runs, err := LoadHistory("demo")
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != "error" {
+		t.Errorf("history = %+v, want one run in error", runs)
+	}
+}
+
+func TestUpdateRecordsProvisionedClusters(t *testing.T) {
+	buf := captureEvents(t)
+	m := newProvisioningModel("demo")
+
+	m, cmd := update(m, CusterProvisioningMsg{cluster: provisioningCluster("provisioned", everyCheck)})
+	if cmd == nil {
+		t.Fatal("the terminal does not show the success message of a provisioned cluster")
+	}
+	if !m.isProvisioned || Failed() {
+		t.Errorf("provisioned = %t, failed = %t, want a successful provisioning", m.isProvisioned, Failed())
+	}
+
+	events := decodeEvents(t, buf)
+	for _, step := range ProvisionStepsFor("civo") {
+		if got := countEvents(events, StatusCompleted, step.Label); got != 1 {
+			t.Errorf("%q was reported completed %d times, want once", step.Label, got)
+		}
+	}
+
+	home, _ := os.UserHomeDir()
+	if _, err := os.Stat(filepath.Join(home, ".k1", "demo", summaryFileName)); err != nil {
+		t.Errorf("summary of the provisioned cluster was not written: %v", err)
+	}
+	runs, err := LoadHistory("demo")
+	if err != nil {
+		t.Fatalf("LoadHistory() error = %v", err)
+	}
+	if len(runs) != 1 || runs[0].Status != "provisioned" {
+		t.Errorf("history = %+v, want one provisioned run", runs)
+	}
+}
+
+func TestProvisionStepsFor(t *testing.T) {
+	providerStepOverrides["test"] = StepOverrides{
+		Skip:    []string{"kbot_setup_check", "git_init_check", "gitops_ready_check", "git_terraform_apply_check", "gitops_pushed_check", "cluster_secrets_created_check", "argocd_install_check", "argocd_initialize_check", "vault_initialized_check", "vault_terraform_apply_check", "users_terraform_apply_check"},
+		Relabel: map[string]string{"install_tools_check": "Downloading tools"},
+		Add: []AddedStep{
+			{ProvisionStep: ProvisionStep{Check: "quota_check", Label: "Checking quotas"}, After: "install_tools_check"},
+			{ProvisionStep: ProvisionStep{Check: "smoke_test_check", Label: "Smoke test"}},
+		},
+	}
+	t.Cleanup(func() { delete(providerStepOverrides, "test") })
+
+	tests := []struct {
+		provider string
+		want     []string
+	}{
+		{provider: "test", want: []string{"Downloading tools", "Checking quotas", "Domain liveness check", "Cloud Terraform apply", "Smoke test"}},
+		{provider: "civo", want: labels(provisionSteps)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			if got := labels(ProvisionStepsFor(tt.provider)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("ProvisionStepsFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if provisionSteps[0].Label != "Installing tools" {
+		t.Errorf("ProvisionStepsFor() relabeled the steps of every provider")
+	}
+}
+
+func labels(steps []ProvisionStep) []string {
+	labels := make([]string, 0, len(steps))
+	for _, step := range steps {
+		labels = append(labels, step.Label)
+	}
+	return labels
+}
+
+func TestBuildCompletedSteps(t *testing.T) {
+	tests := []struct {
+		name          string
+		cluster       types.Cluster
+		checks        []cluster.Check
+		wantCompleted []string
+		wantNext      string
+	}{
+		{
+			name:     "nothing done",
+			cluster:  types.Cluster{CloudProvider: "civo"},
+			wantNext: "",
+		},
+		{
+			name:          "provider relabels a step",
+			cluster:       types.Cluster{CloudProvider: "k3s", InstallToolsCheck: true, CloudTerraformApplyCheck: true},
+			wantCompleted: []string{"Installing tools", "Installing k3s"},
+			wantNext:      "Creating cluster secrets",
+		},
+		{
+			name:          "provider adds a step",
+			cluster:       types.Cluster{CloudProvider: "aws", CloudTerraformApplyCheck: true},
+			wantCompleted: []string{"Cloud Terraform apply"},
+			wantNext:      "Detokenizing KMS key",
+		},
+		{
+			name:    "console adds a step",
+			cluster: types.Cluster{CloudProvider: "civo"},
+			checks: []cluster.Check{
+				{Name: "users_terraform_apply_check", Done: true},
+				{Name: "catalog_apps_installed_check", Done: true},
+			},
+			wantCompleted: []string{"Users Terraform apply", "Catalog apps installed"},
+			wantNext:      finalProvisionStep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			completed, next := BuildCompletedSteps(tt.cluster, tt.checks)
+			if fmt.Sprint(completed) != fmt.Sprint(tt.wantCompleted) {
+				t.Errorf("completed steps = %q, want %q", completed, tt.wantCompleted)
+			}
+			if next != tt.wantNext {
+				t.Errorf("next step = %q, want %q", next, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestCheckLabel(t *testing.T) {
+	tests := map[string]string{
+		"catalog_apps_installed_check": "Catalog apps installed",
+		"dns_check":                    "Dns",
+		"_check":                       "_check",
+	}
+	for check, want := range tests {
+		if got := checkLabel(check); got != want {
+			t.Errorf("checkLabel(%q) = %q, want %q", check, got, want)
+		}
+	}
+}
+
+func newDeprovisioningModel(initialStatus string) (progressModel, <-chan error) {
+	done := make(chan error, 1)
+	m := NewModel()
+	m.clusterName = "demo"
+	m.deprovisioning = true
+	m.deprovisionInitStatus = initialStatus
+	m.deprovisionDone = done
+	m.cancelWatch = func() {}
+	return m, done
+}
+
+func TestDeprovisionEndsOnceTheClusterIsForgotten(t *testing.T) {
+	buf := captureEvents(t)
+	m, done := newDeprovisioningModel("provisioned")
+
+	m, _ = update(m, CusterProvisioningMsg{cluster: provisioningCluster("deleting", func(cl *types.Cluster) {
+		cl.GitTerraformApplyCheck = true
+	})})
+	if fmt.Sprint(m.completedSteps) != fmt.Sprint([]string{"Cloud Terraform destroy"}) {
+		t.Errorf("completed steps = %q, want the cloud infrastructure destroyed", m.completedSteps)
+	}
+
+	_, cmd := update(m, clusterReconnectingMsg{err: fmt.Errorf("unable to get cluster: %w", cluster.ErrNotFound)})
+	if cmd == nil {
+		t.Error("the terminal does not quit once the cluster is deleted")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("deletion outcome = %v, want nil", err)
+		}
+	default:
+		t.Fatal("the deletion outcome was not reported")
+	}
+	if Failed() {
+		t.Error("Failed() = true after a successful deletion")
+	}
+	if got := countEvents(decodeEvents(t, buf), StatusSucceeded, ""); got != 1 {
+		t.Errorf("succeeded events = %d, want 1", got)
+	}
+}
+
+func TestDeprovisionFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		initialStatus string
+		statuses      []string
+		wantFailure   bool
+	}{
+		{name: "deletion fails", initialStatus: "provisioned", statuses: []string{"deleting", "error"}, wantFailure: true},
+		{name: "failed cluster fails to delete", initialStatus: "error", statuses: []string{"deleting", "error"}, wantFailure: true},
+		{name: "failed cluster not deleting yet", initialStatus: "error", statuses: []string{"error"}, wantFailure: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureEvents(t)
+			m, done := newDeprovisioningModel(tt.initialStatus)
+
+			for _, status := range tt.statuses {
+				m, _ = update(m, CusterProvisioningMsg{cluster: provisioningCluster(status, func(cl *types.Cluster) {
+					cl.LastCondition = "unable to destroy the vpc"
+				})})
+			}
+
+			select {
+			case err := <-done:
+				if !tt.wantFailure {
+					t.Fatalf("deletion outcome = %v, want the deletion to be followed", err)
+				}
+				if err == nil || !strings.Contains(err.Error(), "unable to destroy the vpc") {
+					t.Errorf("deletion outcome = %v, want the last condition", err)
+				}
+			default:
+				if tt.wantFailure {
+					t.Fatal("the deletion outcome was not reported")
+				}
+			}
+			if Failed() != tt.wantFailure {
+				t.Errorf("Failed() = %t, want %t", Failed(), tt.wantFailure)
+			}
+		})
+	}
+}
+
+func TestDeprovisionTimesOut(t *testing.T) {
+	captureEvents(t)
+	m, done := newDeprovisioningModel("provisioned")
+
+	update(m, deprovisionTimeoutMsg{timeout: time.Minute})
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("deletion outcome = %v, want a timeout", err)
+		}
+	default:
+		t.Fatal("the timeout was not reported")
+	}
+	if !Failed() {
+		t.Error("Failed() = false after a timeout")
+	}
+}
+
+func TestSendNotificationLinksTheClusterConsole(t *testing.T) {
+	received := make(chan notify.Notification, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notify.Notification
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &n); err != nil {
+			t.Errorf("notification %q is not JSON: %v", body, err)
+		}
+		received <- n
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+
+	m := newProvisioningModel("demo")
+	m.provisioningCluster = types.Cluster{ClusterName: "demo", DomainName: "example.com", SubdomainName: "dev"}
+
+	notify.SetWebhook("")
+	if cmd := m.sendNotification("civo", "provisioned", ""); cmd != nil {
+		t.Error("sendNotification() returned a command without notifications configured")
+	}
+
+	notify.SetWebhook(webhook.URL)
+	defer notify.SetWebhook("")
+	cmd := m.sendNotification("civo", "provisioned", "")
+	if cmd == nil {
+		t.Fatal("sendNotification() returned no command with a webhook configured")
+	}
+
+	select {
+	case <-received:
+		t.Fatal("the notification was sent before its command ran")
+	default:
+	}
+	cmd()
+
+	n := <-received
+	if n.ConsoleURL != "https://kubefirst.dev.example.com" {
+		t.Errorf("console URL = %q, want the console of the cluster", n.ConsoleURL)
+	}
+	if n.Cluster != "demo" || n.Status != "provisioned" {
+		t.Errorf("notification = %+v, want demo provisioned", n)
+	}
+}
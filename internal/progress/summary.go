@@ -0,0 +1,200 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/contexts"
+)
+
+const (
+	// summaryFileName is kept in ~/.k1/<cluster>, with a JSON sibling
+	summaryFileName     = "summary.md"
+	summaryJSONFileName = "summary.json"
+)
+
+// Summary is what is needed to start using a provisioned cluster. It is kept
+// in the kubefirst directory of the cluster and never holds credentials.
+type Summary struct {
+	Cluster      string   `json:"cluster"`
+	Type         string   `json:"type"`
+	Provider     string   `json:"provider"`
+	Region       string   `json:"region"`
+	Domain       string   `json:"domain"`
+	GitProvider  string   `json:"git_provider"`
+	GitOwner     string   `json:"git_owner"`
+	Repositories []string `json:"repositories"`
+	ConsoleURL   string   `json:"console_url"`
+	ArgoCDURL    string   `json:"argocd_url"`
+	VaultURL     string   `json:"vault_url"`
+	// Kubeconfig tells how to connect to the cluster, KubeconfigCommand is set
+	// when it is a single command
+	Kubeconfig             string       `json:"kubeconfig"`
+	KubeconfigCommand      string       `json:"kubeconfig_command,omitempty"`
+	RootCredentialsCommand string       `json:"root_credentials_command"`
+	Steps                  []StepTiming `json:"steps,omitempty"`
+	Generated              time.Time    `json:"generated"`
+}
+
+// NewSummary describes a provisioned cluster, steps are the timings of its provisioning when known
+func NewSummary(cl types.Cluster, steps []StepTiming) Summary {
+	domain := clusterDomain(cl)
+	command := kubeconfigCommand(cl)
+
+	return Summary{
+		Cluster:                cl.ClusterName,
+		Type:                   cl.ClusterType,
+		Provider:               cl.CloudProvider,
+		Region:                 cl.CloudRegion,
+		Domain:                 domain,
+		GitProvider:            cl.GitProvider,
+		GitOwner:               cl.GitAuth.Owner,
+		Repositories:           repositoryURLs(cl),
+		ConsoleURL:             fmt.Sprintf("https://kubefirst.%s", domain),
+		ArgoCDURL:              fmt.Sprintf("https://argocd.%s", domain),
+		VaultURL:               fmt.Sprintf("https://vault.%s", domain),
+		Kubeconfig:             kubeconfigInstructions(cl),
+		KubeconfigCommand:      command,
+		RootCredentialsCommand: fmt.Sprintf("kubefirst %s root-credentials", cl.CloudProvider),
+		Steps:                  steps,
+		Generated:              time.Now().UTC(),
+	}
+}
+
+// Markdown is the summary as a document
+func (s Summary) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Cluster %s\n\n", s.Cluster)
+	fmt.Fprintf(&b, "Generated by kubefirst on %s.\n\n", s.Generated.Format(time.RFC1123))
+	b.WriteString("| | |\n| --- | --- |\n")
+	fmt.Fprintf(&b, "| Type | %s |\n", s.Type)
+	fmt.Fprintf(&b, "| Provider | %s |\n", s.Provider)
+	fmt.Fprintf(&b, "| Region | %s |\n", s.Region)
+	fmt.Fprintf(&b, "| Domain | %s |\n", s.Domain)
+
+	fmt.Fprintf(&b, "\n## %s\n\n", gitProviderLabel(s.GitProvider))
+	fmt.Fprintf(&b, "- Owner: `%s`\n", s.GitOwner)
+	for _, repo := range s.Repositories {
+		fmt.Fprintf(&b, "- %s\n", repo)
+	}
+
+	b.WriteString("\n## URLs\n\n")
+	fmt.Fprintf(&b, "- Kubefirst Console: %s\n", s.ConsoleURL)
+	fmt.Fprintf(&b, "- Argo CD: %s\n", s.ArgoCDURL)
+	fmt.Fprintf(&b, "- Vault: %s\n", s.VaultURL)
+
+	b.WriteString("\n## Access\n\n")
+	if s.KubeconfigCommand != "" {
+		fmt.Fprintf(&b, "To connect to the cluster run:\n\n```sh\n%s\n```\n\n", s.KubeconfigCommand)
+	} else if s.Kubeconfig != "" {
+		fmt.Fprintf(&b, "To connect to the cluster, %s.\n\n", s.Kubeconfig)
+	}
+	fmt.Fprintf(&b, "To retrieve the root credentials of the platform run:\n\n```sh\n%s\n```\n", s.RootCredentialsCommand)
+
+	if len(s.Steps) > 0 {
+		b.WriteString("\n## Step durations\n\n")
+		b.WriteString(durationTable(s.Steps))
+	}
+
+	return b.String()
+}
+
+// WriteSummary saves the summary of its cluster as Markdown and JSON, returning the Markdown file
+func WriteSummary(s Summary) (string, error) {
+	k1Dir, err := contexts.CurrentK1Dir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate the kubefirst directory: %w", err)
+	}
+	dir := filepath.Join(k1Dir, s.Cluster)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("unable to create %q: %w", dir, err)
+	}
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("unable to encode cluster summary: %w", err)
+	}
+	jsonPath := filepath.Join(dir, summaryJSONFileName)
+	if err := os.WriteFile(jsonPath, append(content, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("unable to write cluster summary %q: %w", jsonPath, err)
+	}
+
+	path := filepath.Join(dir, summaryFileName)
+	if err := os.WriteFile(path, []byte(s.Markdown()), 0o600); err != nil {
+		return "", fmt.Errorf("unable to write cluster summary %q: %w", path, err)
+	}
+	return path, nil
+}
+
+// LastProvisioningSteps are the step timings of the last successful provisioning of a cluster
+func LastProvisioningSteps(clusterName string) ([]StepTiming, error) {
+	runs, err := LoadHistory(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].Status == "provisioned" {
+			return runs[i].Steps, nil
+		}
+	}
+	return nil, nil
+}
+
+func clusterDomain(cl types.Cluster) string {
+	if cl.SubdomainName != "" {
+		return fmt.Sprintf("%s.%s", cl.SubdomainName, cl.DomainName)
+	}
+	return cl.DomainName
+}
+
+func gitProviderLabel(gitProvider string) string {
+	if gitProvider == "gitlab" {
+		return "GitLab"
+	}
+	return "GitHub"
+}
+
+// repositoryURLs are the repositories kubefirst creates for the git owner of a cluster
+func repositoryURLs(cl types.Cluster) []string {
+	return []string{
+		fmt.Sprintf("https://%s.com/%s/gitops", cl.GitProvider, cl.GitAuth.Owner),
+		fmt.Sprintf("https://%s.com/%s/metaphor", cl.GitProvider, cl.GitAuth.Owner),
+	}
+}
+
+// kubeconfigCommand saves the kubeconfig of a cluster with the cli of its cloud provider
+func kubeconfigCommand(cl types.Cluster) string {
+	switch cl.CloudProvider {
+	case "aws":
+		return fmt.Sprintf("aws eks update-kubeconfig --name %q --region %q", cl.ClusterName, cl.CloudRegion)
+	case "civo":
+		return fmt.Sprintf("civo kubernetes config %q --save", cl.ClusterName)
+	case "digitalocean":
+		return "doctl kubernetes cluster kubeconfig save " + cl.ClusterName
+	case "google":
+		return fmt.Sprintf("gcloud container clusters get-credentials %q --region=%q", cl.ClusterName, cl.CloudRegion)
+	case "vultr":
+		return fmt.Sprintf("vultr-cli kubernetes config %q", cl.ClusterName)
+	}
+	return ""
+}
+
+// kubeconfigInstructions tell how to connect to a cluster, for providers without a kubeconfig command too
+func kubeconfigInstructions(cl types.Cluster) string {
+	if cl.CloudProvider == "k3s" {
+		return "use the kubeconfig file outputted from terraform to access the cluster"
+	}
+	return kubeconfigCommand(cl)
+}
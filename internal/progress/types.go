@@ -7,7 +7,11 @@ See the LICENSE file for more details.
 package progress
 
 import (
+	"context"
+	"time"
+
 	"github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
 )
 
 // Terminal model
@@ -20,22 +24,89 @@ type progressModel struct {
 
 	// Provisioning fields
 	clusterName         string
+	workloadClusterName string
 	provisioningCluster types.Cluster
 	completedSteps      []string
 	nextStep            string
 	successMessage      string
+
+	// Step transitions already printed by the plain and json output modes
+	reportedSteps []string
+	currentStep   string
+
+	// Step timings, recorded in the history of the cluster
+	provisionStarted time.Time
+	clusterObserved  bool
+	stepStarted      time.Time
+	stepTimings      []StepTiming
+	estimate         *estimate
+	estimateLoaded   bool
+	eta              string
+
+	// Confirmation prompt
+	confirmPrompt string
+	confirmAnswer chan<- bool
+
+	// Text prompt
+	inputPrompt string
+	input       string
+	inputAnswer chan<- string
+
+	// Deprovisioning fields
+	deprovisioning        bool
+	deprovisionInitStatus string
+	deprovisionSeen       bool
+	deprovisionDone       chan<- error
+
+	// Cluster watch
+	clusterEvents <-chan cluster.WatchEvent
+	cancelWatch   context.CancelFunc
+	reconnecting  string
 }
 
 // Bubbletea messages
 
-type CusterProvisioningMsg types.Cluster
+type CusterProvisioningMsg struct {
+	cluster types.Cluster
+	checks  []cluster.Check
+}
+
+type clusterReconnectingMsg struct {
+	err     error
+	retryIn time.Duration
+}
+
+type confirmMsg struct {
+	prompt string
+	answer chan<- bool
+}
+
+type inputMsg struct {
+	prompt string
+	answer chan<- string
+}
 
 type startProvision struct {
 	clusterName string
+	// set when provisioning a workload cluster of the management cluster clusterName
+	workloadClusterName string
+}
+
+type startDeprovision struct {
+	cluster types.Cluster
+	timeout time.Duration
+	done    chan<- error
+}
+
+type deprovisionTimeoutMsg struct {
+	timeout time.Duration
 }
 
 type addStep struct {
 	message string
+	// text is the markdown source of message, as printed by the plain and json
+	// output modes. errorMsg, headerMsg and successMsg carry it too.
+	text string
 }
 
 type completeStep struct {
@@ -44,31 +115,46 @@ type completeStep struct {
 
 type errorMsg struct {
 	message string
+	text    string
 }
 
 type headerMsg struct {
 	message string
+	text    string
 }
 
 type successMsg struct {
 	message string
+	text    string
 }
 
 // Custom
 
-type ProvisionSteps struct {
-	installToolsCheck          string
-	domainLivenessCheck        string
-	kbotSetupCheck             string
-	gitInitCheck               string
-	gitopsReadyCheck           string
-	gitTerraformApplyCheck     string
-	gitopsPushedCheck          string
-	cloudTerraformApplyCheck   string
-	clusterSecretsCreatedCheck string
-	argoCDInstallCheck         string
-	argoCDInitializeCheck      string
-	vaultInitializedCheck      string
-	vaultTerraformApplyCheck   string
-	usersTerraformApplyCheck   string
+// deprovisionStep is reported complete once the console has cleared its check
+type deprovisionStep struct {
+	label string
+	done  func(cluster types.Cluster) bool
+}
+
+// ProvisionStep is reported complete once the console has set the check of the cluster record
+type ProvisionStep struct {
+	// Check is the field of the cluster record, e.g. install_tools_check
+	Check string
+	Label string
+}
+
+// StepOverrides adapt the provisioning steps to a cloud provider
+type StepOverrides struct {
+	// Skip lists the checks the provider never sets
+	Skip []string
+	// Relabel renames the step of a check
+	Relabel map[string]string
+	// Add inserts steps the provider runs on top of the common ones
+	Add []AddedStep
+}
+
+// AddedStep is a provider step run after the step of check After, or last when After is empty
+type AddedStep struct {
+	ProvisionStep
+	After string
 }
@@ -7,6 +7,9 @@ See the LICENSE file for more details.
 package provision
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -18,29 +21,32 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-func CreateMgmtCluster(gitAuth apiTypes.GitAuth, cliFlags types.CliFlags, catalogApps []apiTypes.GitopsCatalogApp) error {
+func CreateMgmtCluster(ctx context.Context, gitAuth apiTypes.GitAuth, cliFlags types.CliFlags, catalogApps []apiTypes.GitopsCatalogApp) error {
 	clusterRecord := utilities.CreateClusterDefinitionRecordFromRaw(
 		gitAuth,
 		cliFlags,
 		catalogApps,
 	)
 
-	clusterCreated, err := cluster.GetCluster(clusterRecord.ClusterName)
+	clusterCreated, err := cluster.GetCluster(ctx, clusterRecord.ClusterName)
 	if err != nil && !errors.Is(err, cluster.ErrNotFound) {
 		log.Printf("error retrieving cluster %q: %v", clusterRecord.ClusterName, err)
 		return fmt.Errorf("error retrieving cluster: %w", err)
 	}
 
 	if errors.Is(err, cluster.ErrNotFound) {
-		if err := cluster.CreateCluster(clusterRecord); err != nil {
+		if err := cluster.CreateCluster(ctx, clusterRecord); err != nil {
 			progress.Error(err.Error())
 			return fmt.Errorf("error creating cluster: %w", err)
 		}
 	}
 
 	if clusterCreated.Status == "error" {
-		cluster.ResetClusterProgress(clusterRecord.ClusterName)
-		if err := cluster.CreateCluster(clusterRecord); err != nil {
+		if err := cluster.ResetClusterProgress(ctx, clusterRecord.ClusterName); err != nil {
+			progress.Error(err.Error())
+			return fmt.Errorf("error resetting cluster progress after error state: %w", err)
+		}
+		if err := cluster.CreateCluster(ctx, clusterRecord); err != nil {
 			progress.Error(err.Error())
 			return fmt.Errorf("error re-creating cluster after error state: %w", err)
 		}
@@ -49,3 +55,77 @@ func CreateMgmtCluster(gitAuth apiTypes.GitAuth, cliFlags types.CliFlags, catalo
 	progress.StartProvisioning(clusterRecord.ClusterName)
 	return nil
 }
+
+// DryRunMgmtCluster renders the cluster definition CreateMgmtCluster would send
+// to the console, with credentials redacted, without provisioning anything
+func DryRunMgmtCluster(gitAuth apiTypes.GitAuth, cliFlags types.CliFlags, catalogApps []apiTypes.GitopsCatalogApp) error {
+	clusterRecord := utilities.CreateClusterDefinitionRecordFromRaw(
+		gitAuth,
+		cliFlags,
+		catalogApps,
+	)
+
+	log.Info().Msgf("dry run: cluster %q was not provisioned", clusterRecord.ClusterName)
+	return renderDryRun(fmt.Sprintf("/cluster/%s", clusterRecord.ClusterName), cluster.RedactClusterDefinition(clusterRecord))
+}
+
+// renderDryRun shows the request body that would have been sent to the console
+func renderDryRun(path string, definition interface{}) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(definition); err != nil {
+		return fmt.Errorf("failed to marshal cluster definition: %w", err)
+	}
+
+	progress.Success(fmt.Sprintf(
+		"#### :mag: Dry run: cluster definition for `POST %s`\n\n```json\n%s```\n\n##### No resources were created. Re-run without `--dry-run` to provision the cluster.",
+		path,
+		buf.String(),
+	))
+
+	return nil
+}
+
+// CreateWorkloadCluster asks the console of the parent management cluster to
+// provision a workload cluster and follows its progress
+func CreateWorkloadCluster(ctx context.Context, cliFlags types.CliFlags) error {
+	workload, err := utilities.CreateWorkloadClusterRecordFromRaw(cliFlags)
+	if err != nil {
+		return fmt.Errorf("error creating workload cluster record: %w", err)
+	}
+
+	parent, err := cluster.GetCluster(ctx, cliFlags.ParentCluster)
+	if errors.Is(err, cluster.ErrNotFound) {
+		return fmt.Errorf("management cluster %q does not exist: %w", cliFlags.ParentCluster, err)
+	}
+	if err != nil {
+		return fmt.Errorf("error retrieving management cluster %q: %w", cliFlags.ParentCluster, err)
+	}
+
+	if parent.ClusterType != "" && parent.ClusterType != types.ClusterTypeMgmt {
+		return fmt.Errorf("cluster %q is a %s cluster, workload clusters can only be created from a management cluster", parent.ClusterName, parent.ClusterType)
+	}
+	if parent.Status != "provisioned" {
+		return fmt.Errorf("management cluster %q is %q, it must be provisioned before workload clusters can be created", parent.ClusterName, parent.Status)
+	}
+
+	for _, existing := range parent.WorkloadClusters {
+		if existing.ClusterName == workload.ClusterName {
+			return fmt.Errorf("workload cluster %q already exists in management cluster %q with status %q", workload.ClusterName, parent.ClusterName, existing.Status)
+		}
+	}
+
+	if cliFlags.DryRun {
+		log.Info().Msgf("dry run: workload cluster %q was not provisioned", workload.ClusterName)
+		return renderDryRun(fmt.Sprintf("/cluster/%s/workload", parent.ClusterName), workload)
+	}
+
+	if err := cluster.CreateWorkloadCluster(ctx, parent.ClusterName, workload); err != nil {
+		return fmt.Errorf("error creating workload cluster: %w", err)
+	}
+
+	progress.StartWorkloadProvisioning(parent.ClusterName, workload.ClusterName)
+	return nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provision
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/cluster/clustertest"
+	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/types"
+	"github.com/spf13/viper"
+)
+
+// useConsole points the cluster package at a fake console whose clusters
+// complete every provisioning step as soon as they are submitted
+func useConsole(t *testing.T) *clustertest.Server {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("K1_CONTEXT", "")
+
+	console := clustertest.NewServer(clustertest.Options{StepInterval: time.Nanosecond})
+	server := httptest.NewServer(console)
+	t.Cleanup(server.Close)
+
+	cluster.DefaultClient = cluster.NewClient(server.URL)
+	cluster.DefaultClient.RetryWait = time.Millisecond
+	t.Cleanup(func() { cluster.DefaultClient = nil })
+
+	viper.Set("flags.cluster-name", "demo")
+	viper.Set("flags.alerts-email", "admin@example.com")
+	viper.Set("flags.domain-name", "example.com")
+	viper.Set("kubefirst.cloud-provider", "civo")
+	t.Cleanup(viper.Reset)
+
+	return console
+}
+
+// startProgress runs the progress terminal without drawing it, the returned
+// channel is closed once it quits
+func startProgress(t *testing.T) <-chan struct{} {
+	t.Helper()
+
+	progress.InitializeProgressTerminal(progress.OutputJSON)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := progress.Progress.Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+			t.Errorf("progress terminal failed: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		progress.Progress.Kill()
+		<-done
+	})
+
+	return done
+}
+
+func waitForProgress(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the progress terminal did not quit")
+	}
+}
+
+func countRequests(console *clustertest.Server, method, path string) int {
+	count := 0
+	for _, req := range console.Requests() {
+		if req.Method == method && req.Path == path {
+			count++
+		}
+	}
+	return count
+}
+
+func TestCreateMgmtClusterProvisionsNewClusters(t *testing.T) {
+	console := useConsole(t)
+	done := startProgress(t)
+
+	if err := CreateMgmtCluster(context.Background(), apiTypes.GitAuth{Owner: "kubefirst"}, types.CliFlags{}, nil); err != nil {
+		t.Fatalf("CreateMgmtCluster() error = %v", err)
+	}
+	waitForProgress(t, done)
+
+	cl, ok := console.Cluster("demo")
+	if !ok || cl.Status != "provisioned" {
+		t.Fatalf("cluster = %+v, want demo provisioned", cl)
+	}
+	if cl.AlertsEmail != "admin@example.com" || cl.CloudProvider != "civo" || cl.GitAuth.Owner != "kubefirst" {
+		t.Errorf("cluster = %+v, want the definition built from the flags", cl)
+	}
+	if got := countRequests(console, http.MethodPost, "/cluster/demo"); got != 1 {
+		t.Errorf("cluster was submitted %d times, want once", got)
+	}
+
+	home, _ := os.UserHomeDir()
+	if _, err := os.Stat(filepath.Join(home, ".k1", "demo", "summary.md")); err != nil {
+		t.Errorf("summary of the provisioned cluster was not written: %v", err)
+	}
+}
+
+func TestCreateMgmtClusterResubmitsFailedClusters(t *testing.T) {
+	console := useConsole(t)
+	console.Seed(apiTypes.Cluster{ClusterName: "demo", CloudProvider: "civo", Status: "error", LastCondition: "quota exceeded"})
+	done := startProgress(t)
+
+	if err := CreateMgmtCluster(context.Background(), apiTypes.GitAuth{}, types.CliFlags{}, nil); err != nil {
+		t.Fatalf("CreateMgmtCluster() error = %v", err)
+	}
+	waitForProgress(t, done)
+
+	if got := countRequests(console, http.MethodPost, "/cluster/demo/reset_progress"); got != 1 {
+		t.Errorf("progress was reset %d times, want once", got)
+	}
+	if got := countRequests(console, http.MethodPost, "/cluster/demo"); got != 1 {
+		t.Errorf("cluster was submitted %d times, want once", got)
+	}
+	if cl, _ := console.Cluster("demo"); cl.Status != "provisioned" {
+		t.Errorf("cluster = %q, want provisioned", cl.Status)
+	}
+}
+
+func TestCreateMgmtClusterStopsWhenTheConsoleFails(t *testing.T) {
+	console := useConsole(t)
+	console.FailRequests(clustertest.RequestFailure{Method: http.MethodGet, Path: "/cluster/demo", StatusCode: http.StatusUnauthorized})
+	startProgress(t)
+
+	err := CreateMgmtCluster(context.Background(), apiTypes.GitAuth{}, types.CliFlags{}, nil)
+	if !errors.Is(err, cluster.ErrUnauthorized) {
+		t.Fatalf("CreateMgmtCluster() error = %v, want ErrUnauthorized", err)
+	}
+	if got := countRequests(console, http.MethodPost, "/cluster/demo"); got != 0 {
+		t.Errorf("cluster was submitted %d times, want never", got)
+	}
+}
+
+func TestCreateMgmtClusterReportsProvisioningFailures(t *testing.T) {
+	console := useConsole(t)
+	console.FailProvisioning("demo", 3, "quota exceeded")
+	done := startProgress(t)
+
+	if err := CreateMgmtCluster(context.Background(), apiTypes.GitAuth{}, types.CliFlags{}, nil); err != nil {
+		t.Fatalf("CreateMgmtCluster() error = %v", err)
+	}
+	waitForProgress(t, done)
+
+	if !progress.Failed() {
+		t.Error("progress.Failed() = false once the cluster ended in error")
+	}
+	if cl, _ := console.Cluster("demo"); cl.Status != "error" {
+		t.Errorf("cluster = %q, want error", cl.Status)
+	}
+}
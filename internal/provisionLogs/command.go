@@ -9,10 +9,12 @@ package provisionLogs //nolint:revive // allowed during refactoring
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/muesli/termenv"
+	"golang.org/x/exp/slices"
 )
 
 type Log struct {
@@ -21,43 +23,185 @@ type Log struct {
 	Message string `bson:"message" json:"message"`
 }
 
+// Levels are the zerolog levels, from the most verbose
+var Levels = []string{"trace", "debug", "info", "warn", "error", "fatal", "panic"}
+
 var (
 	color      = termenv.EnvColorProfile().Color
+	traceStyle = termenv.Style{}.Foreground(color("240")).Styled
+	debugStyle = termenv.Style{}.Foreground(color("245")).Styled
 	infoStyle  = termenv.Style{}.Foreground(color("27")).Styled
+	warnStyle  = termenv.Style{}.Foreground(color("214")).Styled
 	errorStyle = termenv.Style{}.Foreground(color("196")).Styled
+	fatalStyle = termenv.Style{}.Foreground(color("196")).Bold().Styled
 	timeStyle  = termenv.Style{}.Foreground(color("245")).Bold().Styled
 	textStyle  = termenv.Style{}.Foreground(color("15")).Styled
+	podStyle   = termenv.Style{}.Foreground(color("141")).Styled
+	matchStyle = termenv.Style{}.Reverse().Styled
 )
 
-func AddLog(logMsg string) {
+// Entry is a line of a log file. Lines that are not zerolog JSON, such as the
+// ones of the standard logger, only have Raw.
+type Entry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Raw     string
+	// Source is the pod the entry was read from, or cli when the CLI log is
+	// shown with pod logs
+	Source string
+}
+
+// ParseLine reads a line of a log file
+func ParseLine(line string) Entry {
 	var log Log
-	var formatterMsg string
+	if err := json.Unmarshal([]byte(line), &log); err != nil || log.Level == "" {
+		return Entry{Raw: line}
+	}
 
-	err := json.Unmarshal([]byte(logMsg), &log)
-	if err != nil {
-		formatterMsg = textStyle(logMsg)
-	} else {
-		parsedTime, err := time.Parse(time.RFC3339, log.Time)
-		if err != nil {
-			fmt.Printf("error parsing date: %v\n", err)
-			return
-		}
+	entry := Entry{Level: log.Level, Message: strings.TrimRight(log.Message, "\n"), Raw: line}
+	if parsedTime, err := time.Parse(time.RFC3339, log.Time); err == nil {
+		entry.Time = parsedTime
+	}
+	return entry
+}
+
+// Structured reports whether the line was written by zerolog
+func (e Entry) Structured() bool {
+	return e.Level != ""
+}
+
+// Text is the entry without colors, as searched by --grep and the viewer
+func (e Entry) Text() string {
+	if !e.Structured() {
+		return e.sourceTag() + e.Raw
+	}
+	return fmt.Sprintf("%s%s %s: %s", e.sourceTag(), e.Time.Local().Format("2006-01-02 15:04:05"), strings.ToUpper(e.Level), e.Message)
+}
 
-		formattedDateStr := parsedTime.Format("2006-01-02 15:04:05")
+// Render colors the entry by level
+func (e Entry) Render() string {
+	source := ""
+	if e.Source != "" {
+		source = podStyle(e.sourceTag())
+	}
+	if !e.Structured() {
+		return source + textStyle(e.Raw)
+	}
+	timeLog := timeStyle(e.Time.Local().Format("2006-01-02 15:04:05"))
+	return fmt.Sprintf("%s%s %s: %s", source, timeLog, levelStyle(e.Level)(strings.ToUpper(e.Level)), textStyle(e.Message))
+}
 
-		timeLog := timeStyle(formattedDateStr)
-		level := infoStyle(strings.ToUpper(log.Level))
+func (e Entry) sourceTag() string {
+	if e.Source == "" {
+		return ""
+	}
+	return "[" + e.Source + "] "
+}
 
-		if log.Level == "error" {
-			level = errorStyle(strings.ToUpper(log.Level))
+func levelStyle(level string) func(string) string {
+	switch level {
+	case "trace":
+		return traceStyle
+	case "debug":
+		return debugStyle
+	case "warn":
+		return warnStyle
+	case "error":
+		return errorStyle
+	case "fatal", "panic":
+		return fatalStyle
+	}
+	return infoStyle
+}
+
+// Filter selects the entries shown by the viewer
+type Filter struct {
+	// Level is the least severe level shown, every level when empty
+	Level string
+	// Since hides entries older than it, when set
+	Since time.Time
+	// Grep is matched against the text of the entries, when set
+	Grep *regexp.Regexp
+}
+
+// NewFilter validates the logs flags
+func NewFilter(level string, since time.Duration, grep string) (Filter, error) {
+	filter := Filter{}
+
+	if level != "" {
+		level = strings.ToLower(level)
+		if !slices.Contains(Levels, level) {
+			return filter, fmt.Errorf("unsupported level %q - one of: %s", level, Levels)
+		}
+		filter.Level = level
+	}
+	if since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+	if grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --grep expression %q: %w", grep, err)
 		}
+		filter.Grep = re
+	}
+	return filter, nil
+}
 
-		message := textStyle(log.Message)
+// filterState lets unstructured lines follow the decision made for the last
+// structured entry of their source, they usually continue it
+type filterState struct {
+	filter   Filter
+	previous map[string]bool
+}
+
+func newFilterState(filter Filter) filterState {
+	return filterState{filter: filter, previous: map[string]bool{}}
+}
 
-		formatterMsg = fmt.Sprintf("%s %s: %s", timeLog, level, message)
+func (s *filterState) keep(e Entry) bool {
+	if !e.Structured() {
+		previous, ok := s.previous[e.Source]
+		return (previous || !ok) && s.matchesGrep(e)
 	}
 
-	renderedMessage := formatterMsg
+	keep := true
+	if s.filter.Level != "" && slices.Index(Levels, e.Level) < slices.Index(Levels, s.filter.Level) {
+		keep = false
+	}
+	if !s.filter.Since.IsZero() && !e.Time.IsZero() && e.Time.Before(s.filter.Since) {
+		keep = false
+	}
+	s.previous[e.Source] = keep
+	return keep && s.matchesGrep(e)
+}
+
+func (s *filterState) matchesGrep(e Entry) bool {
+	return s.filter.Grep == nil || s.filter.Grep.MatchString(e.Text())
+}
 
-	ProvisionLogs.Send(logMessage{message: renderedMessage})
+// highlight marks the occurrences of search in the text of a line
+func highlight(text, search string) string {
+	if search == "" {
+		return text
+	}
+
+	lower := strings.ToLower(text)
+	needle := strings.ToLower(search)
+	if len(lower) != len(text) || len(needle) != len(search) {
+		// lower casing changed the byte offsets, match the exact case instead
+		lower, needle = text, search
+	}
+	var b strings.Builder
+	for {
+		index := strings.Index(lower, needle)
+		if index < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:index])
+		b.WriteString(matchStyle(text[index : index+len(search)]))
+		text, lower = text[index+len(search):], lower[index+len(search):]
+	}
 }
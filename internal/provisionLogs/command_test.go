@@ -0,0 +1,319 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provisionLogs //nolint:revive // allowed during refactoring
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logLine is a line of the CLI log as written by zerolog
+func logLine(level string, at time.Time, message string) string {
+	return fmt.Sprintf(`{"level":%q,"time":%q,"message":%q}`, level, at.Format(time.RFC3339), message)
+}
+
+// writeLog writes a log file of lines in a temporary directory
+func writeLog(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "log_demo.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseLine(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want Entry
+	}{
+		{
+			name: "zerolog",
+			line: logLine("info", at, "cluster created"),
+			want: Entry{Time: at, Level: "info", Message: "cluster created"},
+		},
+		{
+			name: "message ending with a line break",
+			line: logLine("error", at, "unable to reach the console\n"),
+			want: Entry{Time: at, Level: "error", Message: "unable to reach the console"},
+		},
+		{
+			name: "invalid time",
+			line: `{"level":"warn","time":"yesterday","message":"retrying"}`,
+			want: Entry{Level: "warn", Message: "retrying"},
+		},
+		{
+			name: "standard logger",
+			line: "2024/05/01 12:00:00 downloading terraform",
+			want: Entry{},
+		},
+		{
+			name: "json without a level",
+			line: `{"message":"not zerolog"}`,
+			want: Entry{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Raw = tt.line
+			got := ParseLine(tt.line)
+			if !got.Time.Equal(tt.want.Time) || got.Level != tt.want.Level || got.Message != tt.want.Message || got.Raw != tt.want.Raw || got.Source != "" {
+				t.Errorf("ParseLine() = %+v, want %+v", got, tt.want)
+			}
+			if got.Structured() != (tt.want.Level != "") {
+				t.Errorf("Structured() = %t, want %t", got.Structured(), tt.want.Level != "")
+			}
+		})
+	}
+}
+
+func TestEntryText(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	local := at.Local().Format("2006-01-02 15:04:05")
+
+	tests := []struct {
+		entry Entry
+		want  string
+	}{
+		{entry: Entry{Time: at, Level: "warn", Message: "retrying"}, want: local + " WARN: retrying"},
+		{entry: Entry{Time: at, Level: "info", Message: "listening", Source: "kubefirst-api-7d9"}, want: "[kubefirst-api-7d9] " + local + " INFO: listening"},
+		{entry: Entry{Raw: "downloading terraform"}, want: "downloading terraform"},
+		{entry: Entry{Raw: "downloading terraform", Source: SourceCLI}, want: "[cli] downloading terraform"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.entry.Text(); got != tt.want {
+			t.Errorf("Text() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNewFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		since     time.Duration
+		grep      string
+		wantLevel string
+		wantSince bool
+		wantGrep  string
+		wantErr   string
+	}{
+		{name: "no filter"},
+		{name: "level", level: "WARN", wantLevel: "warn"},
+		{name: "since", since: time.Hour, wantSince: true},
+		{name: "grep", grep: "vault|argocd", wantGrep: "vault|argocd"},
+		{name: "unknown level", level: "verbose", wantErr: `unsupported level "verbose"`},
+		{name: "invalid grep", grep: "(unclosed", wantErr: `invalid --grep expression "(unclosed"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			filter, err := NewFilter(tt.level, tt.since, tt.grep)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewFilter() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFilter() error = %v", err)
+			}
+
+			if filter.Level != tt.wantLevel {
+				t.Errorf("level = %q, want %q", filter.Level, tt.wantLevel)
+			}
+			if tt.wantSince {
+				if filter.Since.Before(before.Add(-tt.since)) || filter.Since.After(time.Now().Add(-tt.since)) {
+					t.Errorf("since = %s, want %s ago", filter.Since, tt.since)
+				}
+			} else if !filter.Since.IsZero() {
+				t.Errorf("since = %s, want none", filter.Since)
+			}
+			if (filter.Grep == nil) != (tt.wantGrep == "") || (filter.Grep != nil && filter.Grep.String() != tt.wantGrep) {
+				t.Errorf("grep = %v, want %q", filter.Grep, tt.wantGrep)
+			}
+		})
+	}
+}
+
+func TestFilterKeep(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+	recent := now.Add(-time.Minute)
+
+	lines := []Entry{
+		ParseLine(logLine("debug", recent, "loading config")),
+		{Raw: "continuation of the debug entry"},
+		ParseLine(logLine("info", old, "downloading tools")),
+		ParseLine(logLine("info", recent, "applying vault terraform")),
+		{Raw: "continuation of the info entry"},
+		ParseLine(logLine("error", recent, "unable to unseal vault")),
+		{Raw: "stack of the pod", Source: "kubefirst-api-7d9"},
+	}
+
+	tests := []struct {
+		name  string
+		level string
+		since time.Duration
+		grep  string
+		want  []string
+	}{
+		{
+			name: "everything",
+			want: []string{"loading config", "continuation of the debug entry", "downloading tools", "applying vault terraform", "continuation of the info entry", "unable to unseal vault", "stack of the pod"},
+		},
+		{
+			name:  "level",
+			level: "info",
+			want:  []string{"downloading tools", "applying vault terraform", "continuation of the info entry", "unable to unseal vault", "stack of the pod"},
+		},
+		{
+			name:  "since",
+			since: time.Hour,
+			want:  []string{"loading config", "continuation of the debug entry", "applying vault terraform", "continuation of the info entry", "unable to unseal vault", "stack of the pod"},
+		},
+		{
+			name: "grep",
+			grep: "(?i)vault",
+			want: []string{"applying vault terraform", "unable to unseal vault"},
+		},
+		{
+			name:  "every filter",
+			level: "warn",
+			since: time.Hour,
+			grep:  "vault|stack",
+			want:  []string{"unable to unseal vault", "stack of the pod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.level, tt.since, tt.grep)
+			if err != nil {
+				t.Fatalf("NewFilter() error = %v", err)
+			}
+
+			state := newFilterState(filter)
+			got := []string{}
+			for _, entry := range lines {
+				if state.keep(entry) {
+					got = append(got, content(entry))
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// content is the message of an entry, or its line when it is not structured
+func content(e Entry) string {
+	if e.Structured() {
+		return e.Message
+	}
+	return e.Raw
+}
+
+func TestPrintFiltersTheCLILog(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	path := writeLog(t,
+		logLine("debug", at, "loading config"),
+		logLine("error", at.Add(time.Second), "unable to unseal vault"),
+		"goroutine 1 [running]:",
+	)
+
+	filter, err := NewFilter("warn", 0, "")
+	if err != nil {
+		t.Fatalf("NewFilter() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, path, SourceCLI, filter); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	want := at.Add(time.Second).Local().Format("2006-01-02 15:04:05") + " ERROR: unable to unseal vault\ngoroutine 1 [running]:\n"
+	if out.String() != want {
+		t.Errorf("Print() = %q, want %q", out.String(), want)
+	}
+
+	if err := Print(&out, filepath.Join(t.TempDir(), "missing.log"), SourceCLI, Filter{}); err == nil {
+		t.Error("Print() of a missing log file error = nil")
+	}
+}
+
+func TestViewerKeepsEntriesInTimeOrder(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := NewModel("log_demo.log", SourceAPI, Filter{})
+
+	m.addEntries([]Entry{
+		{Time: at, Level: "info", Message: "first"},
+		{Time: at.Add(2 * time.Second), Level: "info", Message: "third"},
+	})
+	m.addEntries([]Entry{
+		{Time: at.Add(time.Second), Level: "info", Message: "second", Source: "kubefirst-api-7d9"},
+		{Raw: "no time"},
+	})
+
+	got := []string{}
+	for _, entry := range m.entries {
+		got = append(got, content(entry))
+	}
+	if want := []string{"first", "second", "third", "no time"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+	if len(m.rendered) != len(m.entries) {
+		t.Errorf("rendered %d entries, want %d", len(m.rendered), len(m.entries))
+	}
+
+	m.setSearch("THIRD")
+	if fmt.Sprint(m.matches) != "[2]" {
+		t.Errorf("matches = %v, want the third entry", m.matches)
+	}
+}
+
+func TestViewerDropsTheOldestEntries(t *testing.T) {
+	m := NewModel("log_demo.log", SourceCLI, Filter{})
+
+	entries := make([]Entry, maxEntries+10)
+	for i := range entries {
+		entries[i] = Entry{Raw: fmt.Sprintf("line %d", i)}
+	}
+	m.addEntries(entries)
+
+	if len(m.entries) != maxEntries || len(m.rendered) != maxEntries {
+		t.Fatalf("kept %d entries, want %d", len(m.entries), maxEntries)
+	}
+	if m.entries[0].Raw != "line 10" {
+		t.Errorf("oldest entry = %q, want line 10", m.entries[0].Raw)
+	}
+}
+
+func TestFilterDescribe(t *testing.T) {
+	filter, err := NewFilter("error", 0, "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filter.describe(); got != "level >= error, grep vault" {
+		t.Errorf("describe() = %q", got)
+	}
+	if got := (Filter{}).describe(); got != "" {
+		t.Errorf("describe() of no filter = %q, want empty", got)
+	}
+}
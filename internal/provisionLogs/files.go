@@ -0,0 +1,207 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provisionLogs //nolint:revive // allowed during refactoring
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// commandLines bounds the lines read from the top of a log file to find the
+// command that created it
+const commandLines = 20
+
+// File is a log file of the logs directory
+type File struct {
+	Name     string
+	Path     string
+	Size     int64
+	Modified time.Time
+	// Cluster is set for the logs of create commands, named after the cluster
+	Cluster string
+	// Command is the kubefirst command that created the log, when recorded and
+	// read by ReadCommands
+	Command string
+}
+
+// ListFiles returns the log files of dir, the most recent first. Only their
+// metadata is read, see ReadCommands for the commands that created them.
+func ListFiles(dir string) ([]File, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "log_*.log"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list log files: %w", err)
+	}
+
+	files := make([]File, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("unable to read log file %q: %w", path, err)
+		}
+		if info.IsDir() {
+			continue
+		}
+
+		name := info.Name()
+		files = append(files, File{
+			Name:     name,
+			Path:     path,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			Cluster:  fileCluster(name),
+		})
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Modified.After(files[j].Modified)
+	})
+	return files, nil
+}
+
+// ReadCommands sets the Command of files, reading the top of each log file
+func ReadCommands(files []File) {
+	for i := range files {
+		files[i].Command = fileCommand(files[i].Path)
+	}
+}
+
+// fileCluster reads the cluster name of log_<cluster>.log, the other commands
+// name their log after the epoch they started at
+func fileCluster(name string) string {
+	cluster := strings.TrimSuffix(strings.TrimPrefix(name, "log_"), ".log")
+	if _, err := strconv.ParseInt(cluster, 10, 64); err == nil {
+		return ""
+	}
+	return cluster
+}
+
+// fileCommand finds the command recorded when the log file was created, older
+// logs don't have one
+func fileCommand(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for i := 0; i < commandLines && scanner.Scan(); i++ {
+		var entry struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil && entry.Command != "" {
+			return entry.Command
+		}
+	}
+	return ""
+}
+
+// Retention selects the log files to delete
+type Retention struct {
+	// OlderThan deletes the files not modified for longer, when set
+	OlderThan time.Duration
+	// Keep deletes the files past the most recent ones, when set
+	Keep int
+}
+
+// Empty reports whether the retention deletes nothing
+func (r Retention) Empty() bool {
+	return r.OlderThan <= 0 && r.Keep <= 0
+}
+
+// CurrentRetention is the retention applied when kubefirst starts. It is read
+// from the logs section of the kubefirst config:
+//
+//	logs:
+//	  retention:
+//	    older-than: 720h
+//	    keep: 50
+func CurrentRetention() (Retention, error) {
+	retention := Retention{Keep: viper.GetInt("logs.retention.keep")}
+	if olderThan := viper.GetString("logs.retention.older-than"); olderThan != "" {
+		duration, err := time.ParseDuration(olderThan)
+		if err != nil {
+			return retention, fmt.Errorf("invalid logs.retention.older-than %q: %w", olderThan, err)
+		}
+		retention.OlderThan = duration
+	}
+	if retention.Keep < 0 || retention.OlderThan < 0 {
+		return Retention{}, errors.New("logs retention can't be negative")
+	}
+	return retention, nil
+}
+
+// Expired returns the files deleted by the retention, a file is deleted when it
+// is older than OlderThan or when it is not one of the Keep most recent files.
+// files must be sorted the most recent first, as returned by ListFiles.
+func (r Retention) Expired(files []File, now time.Time) []File {
+	expired := []File{}
+	for i, file := range files {
+		switch {
+		case r.Keep > 0 && i >= r.Keep:
+			expired = append(expired, file)
+		case r.OlderThan > 0 && now.Sub(file.Modified) > r.OlderThan:
+			expired = append(expired, file)
+		}
+	}
+	return expired
+}
+
+// ExpiredFiles returns the log files of dir expired by the retention, except the
+// one at current which the running command writes to
+func ExpiredFiles(dir string, retention Retention, current string) ([]File, error) {
+	if retention.Empty() {
+		return nil, nil
+	}
+
+	files, err := ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []File{}
+	for _, file := range retention.Expired(files, time.Now()) {
+		if current != "" && filepath.Clean(file.Path) == filepath.Clean(current) {
+			continue
+		}
+		expired = append(expired, file)
+	}
+	return expired, nil
+}
+
+// Clean deletes the log files returned by ExpiredFiles, the deleted ones are returned
+func Clean(dir string, retention Retention, current string) ([]File, error) {
+	expired, err := ExpiredFiles(dir, retention, current)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []File{}
+	var errs []error
+	for _, file := range expired {
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("unable to delete log file %q: %w", file.Path, err))
+			continue
+		}
+		deleted = append(deleted, file)
+	}
+	return deleted, errors.Join(errs...)
+}
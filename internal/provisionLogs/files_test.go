@@ -0,0 +1,168 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provisionLogs //nolint:revive // allowed during refactoring
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLogs writes log files in a temporary directory, each modified its age ago,
+// and returns the directory
+func writeLogs(t *testing.T, ages map[string]time.Duration) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, age := range ages {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(`{"level":"info","command":"kubefirst civo create"}`+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		modified := time.Now().Add(-age)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func names(files []File) []string {
+	got := []string{}
+	for _, file := range files {
+		got = append(got, file.Name)
+	}
+	return got
+}
+
+func TestListFiles(t *testing.T) {
+	dir := writeLogs(t, map[string]time.Duration{
+		"log_1714564800.log": 2 * time.Hour,
+		"log_demo.log":       time.Hour,
+		"notes.txt":          0,
+	})
+
+	files, err := ListFiles(dir)
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	if got := names(files); fmt.Sprint(got) != "[log_demo.log log_1714564800.log]" {
+		t.Fatalf("ListFiles() = %q, want the log files the most recent first", got)
+	}
+	if files[0].Cluster != "demo" || files[1].Cluster != "" {
+		t.Errorf("clusters = %q and %q, want demo and none", files[0].Cluster, files[1].Cluster)
+	}
+	if files[0].Command != "" {
+		t.Errorf("ListFiles() read the command %q, want only the file metadata", files[0].Command)
+	}
+
+	ReadCommands(files)
+	if files[0].Command != "kubefirst civo create" {
+		t.Errorf("ReadCommands() = %q, want the command of the log header", files[0].Command)
+	}
+}
+
+func TestRetentionExpired(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	files := []File{
+		{Name: "log_a.log", Modified: now.Add(-time.Hour)},
+		{Name: "log_b.log", Modified: now.Add(-48 * time.Hour)},
+		{Name: "log_c.log", Modified: now.Add(-72 * time.Hour)},
+		{Name: "log_d.log", Modified: now.Add(-96 * time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		retention Retention
+		want      []string
+	}{
+		{name: "no retention", want: []string{}},
+		{name: "older than", retention: Retention{OlderThan: 50 * time.Hour}, want: []string{"log_c.log", "log_d.log"}},
+		{name: "keep", retention: Retention{Keep: 3}, want: []string{"log_d.log"}},
+		{name: "keep more than there are", retention: Retention{Keep: 10}, want: []string{}},
+		{name: "either", retention: Retention{OlderThan: 80 * time.Hour, Keep: 2}, want: []string{"log_c.log", "log_d.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(tt.retention.Expired(files, now)); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Expired() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name        string
+		retention   Retention
+		current     string
+		wantDeleted []string
+	}{
+		{
+			name:        "no retention",
+			wantDeleted: []string{},
+		},
+		{
+			name:        "keep",
+			retention:   Retention{Keep: 1},
+			wantDeleted: []string{"log_1714564800.log", "log_old.log"},
+		},
+		{
+			name:        "older than",
+			retention:   Retention{OlderThan: 36 * time.Hour},
+			wantDeleted: []string{"log_old.log"},
+		},
+		{
+			name:        "current log past keep",
+			retention:   Retention{Keep: 1},
+			current:     "log_old.log",
+			wantDeleted: []string{"log_1714564800.log"},
+		},
+		{
+			name:        "current log older than",
+			retention:   Retention{OlderThan: time.Minute},
+			current:     "log_old.log",
+			wantDeleted: []string{"log_demo.log", "log_1714564800.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeLogs(t, map[string]time.Duration{
+				"log_demo.log":       time.Hour,
+				"log_1714564800.log": 24 * time.Hour,
+				"log_old.log":        48 * time.Hour,
+			})
+			current := ""
+			if tt.current != "" {
+				current = filepath.Join(dir, tt.current)
+			}
+
+			deleted, err := Clean(dir, tt.retention, current)
+			if err != nil {
+				t.Fatalf("Clean() error = %v", err)
+			}
+			if got := names(deleted); fmt.Sprint(got) != fmt.Sprint(tt.wantDeleted) {
+				t.Errorf("Clean() = %q, want %q", got, tt.wantDeleted)
+			}
+
+			for _, file := range deleted {
+				if _, err := os.Stat(file.Path); !os.IsNotExist(err) {
+					t.Errorf("%s was not deleted: %v", file.Name, err)
+				}
+			}
+			if current != "" {
+				if _, err := os.Stat(current); err != nil {
+					t.Errorf("the current log was deleted: %v", err)
+				}
+			}
+		})
+	}
+}
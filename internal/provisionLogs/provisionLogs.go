@@ -7,22 +7,269 @@ See the LICENSE file for more details.
 package provisionLogs //nolint:revive // allowed during refactoring
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/nxadm/tail"
+	"golang.org/x/exp/slices"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// maxEntries bounds the lines kept by the viewer
+	maxEntries = 20000
+	// batchSize and batchInterval group the lines read from the log so a large
+	// file is not rendered one line at a time
+	batchSize     = 1000
+	batchInterval = 100 * time.Millisecond
+)
+
+var (
+	titleStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
+	statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("252"))
+	quitStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render
 )
 
-var quitStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render
+const logsHelp = "↑/↓ pgup/pgdn scroll • g/G top/bottom • / search • n/N next/previous match • q quit"
 
 var ProvisionLogs *tea.Program
 
 //nolint:revive // will be removed after refactoring
-func NewModel() provisionLogsModel {
-	return provisionLogsModel{}
+func NewModel(path, source string, filter Filter) provisionLogsModel {
+	searchInput := textinput.New()
+	searchInput.Prompt = "/"
+
+	return provisionLogsModel{
+		path:        path,
+		source:      source,
+		filter:      newFilterState(filter),
+		follow:      true,
+		searchInput: searchInput,
+	}
 }
 
 // Bubbletea functions
-func InitializeProvisionLogsTerminal() {
-	ProvisionLogs = tea.NewProgram(NewModel())
+func InitializeProvisionLogsTerminal(path, source string, filter Filter) {
+	ProvisionLogs = tea.NewProgram(NewModel(path, source, filter), tea.WithAltScreen(), tea.WithMouseCellMotion())
+}
+
+// Follow tails the log file at path into the viewer until it is closed, along
+// with the logs of the pods of source in the console cluster
+func Follow(path, source string, filter Filter) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var clientset kubernetes.Interface
+	if source != SourceCLI {
+		var err error
+		clientset, err = ConsoleClientset()
+		if err != nil {
+			return err
+		}
+	}
+
+	t, err := tail.TailFile(path, tail.Config{Follow: true, ReOpen: true, Logger: tail.DiscardingLogger})
+	if err != nil {
+		return fmt.Errorf("error tailing log file: %w", err)
+	}
+	defer t.Cleanup()
+
+	entries := make(chan Entry)
+	errs := make(chan error)
+
+	InitializeProvisionLogsTerminal(path, source, filter)
+	go followFile(ctx, t, source != SourceCLI, entries, errs)
+	if clientset != nil {
+		go followPods(ctx, clientset, source, filter.Since, entries, errs)
+	}
+	go sendBatches(ctx, entries, errs)
+
+	if _, err := ProvisionLogs.Run(); err != nil {
+		return fmt.Errorf("failed to run provision logs: %w", err)
+	}
+	return nil
+}
+
+// cliLog reads the lines of the CLI log. Lines of the standard logger take the
+// time of the entry before them so they stay with it once merged with pod logs.
+type cliLog struct {
+	tagged bool
+	last   time.Time
+}
+
+func (c *cliLog) entry(line string) Entry {
+	entry := ParseLine(line)
+	if c.tagged {
+		entry.Source = SourceCLI
+	}
+	if entry.Time.IsZero() {
+		entry.Time = c.last
+	} else {
+		c.last = entry.Time
+	}
+	return entry
+}
+
+// followFile sends the lines of the tailed CLI log to entries
+func followFile(ctx context.Context, t *tail.Tail, tagged bool, entries chan<- Entry, errs chan<- error) {
+	cli := cliLog{tagged: tagged}
+	for {
+		select {
+		case line, ok := <-t.Lines:
+			if !ok {
+				if err := t.Err(); err != nil {
+					sendError(ctx, errs, err)
+				}
+				return
+			}
+			if line.Err != nil {
+				sendError(ctx, errs, line.Err)
+				continue
+			}
+			select {
+			case entries <- cli.entry(line.Text):
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sendError reports an error of a source unless the viewer is closed
+func sendError(ctx context.Context, errs chan<- error, err error) {
+	select {
+	case errs <- err:
+	case <-ctx.Done():
+	}
+}
+
+// sendBatches forwards the entries and errors of the sources to the viewer, the
+// entries in batches
+func sendBatches(ctx context.Context, entries <-chan Entry, errs <-chan error) {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := []Entry{}
+	flush := func() {
+		if len(batch) > 0 {
+			ProvisionLogs.Send(logBatchMsg{entries: batch})
+			batch = []Entry{}
+		}
+	}
+
+	for {
+		select {
+		case entry := <-entries:
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case err := <-errs:
+			flush()
+			ProvisionLogs.Send(logErrorMsg{err: err})
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Print writes the matching lines of the log file at path without colors, for
+// when the output is not a terminal. The logs of the pods of source are merged
+// with them in time order.
+func Print(w io.Writer, path, source string, filter Filter) error {
+	return printLogs(w, ConsoleClientset, path, source, filter)
+}
+
+// printLogs is Print, connecting to the console cluster with connect when the
+// logs of pods are merged
+func printLogs(w io.Writer, connect func() (kubernetes.Interface, error), path, source string, filter Filter) error {
+	entries, err := readFile(path, source != SourceCLI)
+	if err != nil {
+		return err
+	}
+
+	var podsErr error
+	if source != SourceCLI {
+		var clientset kubernetes.Interface
+		if clientset, podsErr = connect(); podsErr == nil {
+			var podEntries []Entry
+			podEntries, podsErr = PodLogs(context.Background(), clientset, source, filter.Since)
+			entries = mergeEntries(entries, podEntries)
+		}
+	}
+
+	state := newFilterState(filter)
+	for _, entry := range entries {
+		if state.keep(entry) {
+			fmt.Fprintln(w, entry.Text())
+		}
+	}
+	if podsErr != nil {
+		return fmt.Errorf("unable to read the %s logs: %w", source, podsErr)
+	}
+	return nil
+}
+
+// mergeEntries orders the entries of the CLI log and of the pods by time, the
+// entries of a same time keep their order, the CLI log first
+func mergeEntries(cli, pods []Entry) []Entry {
+	entries := append(append([]Entry{}, cli...), pods...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries
+}
+
+// readFile reads the entries of the CLI log at path
+func readFile(path string, tagged bool) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening log file: %w", err)
+	}
+	defer file.Close()
+
+	cli := cliLog{tagged: tagged}
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		entries = append(entries, cli.entry(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading log file: %w", err)
+	}
+	return entries, nil
+}
+
+// PodLogs reads the logs of the pods of source in the console cluster newer than
+// since, clientset is connected to the console cluster by ConsoleClientset
+func PodLogs(ctx context.Context, clientset kubernetes.Interface, source string, since time.Time) ([]Entry, error) {
+	ch := make(chan Entry)
+	done := make(chan error, 1)
+	go func() {
+		done <- readPods(ctx, clientset, source, since, ch)
+		close(ch)
+	}()
+
+	entries := []Entry{}
+	for entry := range ch {
+		entries = append(entries, entry)
+	}
+	return entries, <-done
 }
 
 func (m provisionLogsModel) Init() tea.Cmd {
@@ -31,16 +278,67 @@ func (m provisionLogsModel) Init() tea.Cmd {
 
 func (m provisionLogsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		height := max(msg.Height-3, 1)
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, height)
+			m.ready = true
+		} else {
+			m.viewport.Width = msg.Width
+			m.viewport.Height = height
+		}
+		m.refresh()
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+
 		switch msg.String() {
-		case "ctrl+c":
+		case "ctrl+c", "q":
 			return m, tea.Quit
-		default:
+		case "/":
+			m.searching = true
+			m.searchInput.SetValue(m.search)
+			m.searchInput.CursorEnd()
+			return m, m.searchInput.Focus()
+		case "n":
+			m.jumpToMatch(m.match + 1)
+			return m, nil
+		case "N":
+			m.jumpToMatch(m.match - 1)
+			return m, nil
+		case "g", "home":
+			m.viewport.GotoTop()
+			m.follow = false
+			return m, nil
+		case "G", "end":
+			m.viewport.GotoBottom()
+			m.follow = true
+			return m, nil
+		case "esc":
+			m.setSearch("")
 			return m, nil
 		}
 
-	case logMessage:
-		m.logs = append(m.logs, msg.message)
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		m.follow = m.viewport.AtBottom()
+		return m, cmd
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		m.follow = m.viewport.AtBottom()
+		return m, cmd
+
+	case logBatchMsg:
+		m.addEntries(msg.entries)
+		return m, nil
+
+	case logErrorMsg:
+		m.err = msg.err
 		return m, nil
 
 	default:
@@ -48,11 +346,159 @@ func (m provisionLogsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// updateSearch edits the search, enter applies it and esc leaves it unchanged
+func (m provisionLogsModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.searching = false
+		m.searchInput.Blur()
+		m.setSearch(m.searchInput.Value())
+		return m, nil
+	case tea.KeyEsc:
+		m.searching = false
+		m.searchInput.Blur()
+		return m, nil
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
+// addEntries appends the lines matching the filter, dropping the oldest past maxEntries
+func (m *provisionLogsModel) addEntries(entries []Entry) {
+	for _, entry := range entries {
+		if !m.filter.keep(entry) {
+			continue
+		}
+		index := m.insertIndex(entry)
+		m.entries = slices.Insert(m.entries, index, entry)
+		m.rendered = slices.Insert(m.rendered, index, m.renderEntry(entry))
+	}
+
+	if overflow := len(m.entries) - maxEntries; overflow > 0 {
+		m.entries = append([]Entry(nil), m.entries[overflow:]...)
+		m.rendered = append([]string(nil), m.rendered[overflow:]...)
+	}
+
+	m.findMatches()
+	m.refresh()
+}
+
+// insertIndex keeps the entries of the sources in time order, the lines of a
+// source usually come after the ones shown
+func (m provisionLogsModel) insertIndex(entry Entry) int {
+	count := len(m.entries)
+	if count == 0 || entry.Time.IsZero() || !entry.Time.Before(m.entries[count-1].Time) {
+		return count
+	}
+	return sort.Search(count, func(i int) bool {
+		return m.entries[i].Time.After(entry.Time)
+	})
+}
+
+func (m provisionLogsModel) renderEntry(entry Entry) string {
+	if m.search != "" && strings.Contains(strings.ToLower(entry.Text()), strings.ToLower(m.search)) {
+		return highlight(entry.Text(), m.search)
+	}
+	return entry.Render()
+}
+
+// setSearch highlights search and moves to its first match after the current position
+func (m *provisionLogsModel) setSearch(search string) {
+	m.search = search
+	for i, entry := range m.entries {
+		m.rendered[i] = m.renderEntry(entry)
+	}
+	m.findMatches()
+	m.refresh()
+
+	if search == "" {
+		return
+	}
+	for i, line := range m.matches {
+		if line >= m.viewport.YOffset {
+			m.jumpToMatch(i)
+			return
+		}
+	}
+	m.jumpToMatch(0)
+}
+
+func (m *provisionLogsModel) findMatches() {
+	m.matches = m.matches[:0]
+	if m.search == "" {
+		return
+	}
+	search := strings.ToLower(m.search)
+	for i, entry := range m.entries {
+		if strings.Contains(strings.ToLower(entry.Text()), search) {
+			m.matches = append(m.matches, i)
+		}
+	}
+}
+
+// jumpToMatch scrolls to a match, wrapping around the first and last ones
+func (m *provisionLogsModel) jumpToMatch(index int) {
+	if len(m.matches) == 0 {
+		return
+	}
+	m.match = (index%len(m.matches) + len(m.matches)) % len(m.matches)
+	m.viewport.SetYOffset(m.matches[m.match] - m.viewport.Height/2)
+	m.follow = m.viewport.AtBottom()
+}
+
+// refresh shows the entries in the viewport, at the end of the log when following it
+func (m *provisionLogsModel) refresh() {
+	if !m.ready {
+		return
+	}
+	m.viewport.SetContent(strings.Join(m.rendered, "\n"))
+	if m.follow {
+		m.viewport.GotoBottom()
+	}
+}
+
 func (m provisionLogsModel) View() string {
-	logs := ""
-	for i := 0; i < len(m.logs); i++ {
-		logs += m.logs[i] + "\n"
+	if !m.ready {
+		return "\n  Loading logs..."
+	}
+
+	title := "kubefirst logs - " + m.path
+	if m.source != SourceCLI {
+		title += fmt.Sprintf(" + %s pods", m.source)
+	}
+	header := titleStyle.Render(title)
+	if filter := m.filter.filter.describe(); filter != "" {
+		header += statusStyle.Render(" (" + filter + ")")
 	}
 
-	return logs + "\n" + quitStyle("ctrl+c to quit") + "\n"
+	footer := quitStyle(logsHelp)
+	switch {
+	case m.searching:
+		footer = m.searchInput.View()
+	case m.err != nil:
+		footer = errorStyle(m.err.Error())
+	case m.search != "":
+		footer = statusStyle.Render(fmt.Sprintf("/%s - %d matches", m.search, len(m.matches))) + "  " + footer
+	}
+
+	return header + "\n" + m.viewport.View() + "\n\n" + footer
+}
+
+// describe summarizes the filter for the header of the viewer
+func (f Filter) describe() string {
+	parts := []string{}
+	if f.Level != "" {
+		parts = append(parts, "level >= "+f.Level)
+	}
+	if !f.Since.IsZero() {
+		parts = append(parts, "since "+f.Since.Local().Format("2006-01-02 15:04:05"))
+	}
+	if f.Grep != nil {
+		parts = append(parts, "grep "+f.Grep.String())
+	}
+	return strings.Join(parts, ", ")
 }
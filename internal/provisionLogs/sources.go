@@ -0,0 +1,244 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provisionLogs //nolint:revive // allowed during refactoring
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/konstructio/kubefirst/internal/contexts"
+	"golang.org/x/exp/slices"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Sources of the logs viewer, the CLI log is always shown
+const (
+	SourceCLI     = "cli"
+	SourceAPI     = "api"
+	SourceConsole = "console"
+	SourceAll     = "all"
+)
+
+var Sources = []string{SourceCLI, SourceAPI, SourceConsole, SourceAll}
+
+const (
+	// consoleClusterDir is the directory of the local console cluster created by
+	// `kubefirst launch up` in the kubefirst directory
+	consoleClusterDir = "kubefirst-console"
+	consoleNamespace  = "kubefirst"
+	// podsInterval is how often new pods are looked for, such as restarted ones
+	podsInterval = 5 * time.Second
+)
+
+// podLabels are the app.kubernetes.io/name labels of the pods followed for each source
+var podLabels = map[string][]string{
+	SourceAPI:     {"kubefirst-api", "kubefirst-api-ee"},
+	SourceConsole: {"console"},
+}
+
+// ValidateSource checks the --source flag of the logs command
+func ValidateSource(source string) error {
+	if slices.Contains(Sources, source) {
+		return nil
+	}
+	return fmt.Errorf("unsupported source %q - one of: %s", source, Sources)
+}
+
+// sourceLabels returns the pod labels followed for a source, none for the CLI log alone
+func sourceLabels(source string) []string {
+	if source == SourceAll {
+		return append(append([]string{}, podLabels[SourceAPI]...), podLabels[SourceConsole]...)
+	}
+	return podLabels[source]
+}
+
+// ConsoleKubeconfig returns the path of the kubeconfig of the local console cluster
+func ConsoleKubeconfig() (string, error) {
+	k1Dir, err := contexts.CurrentK1Dir()
+	if err != nil {
+		return "", fmt.Errorf("unable to get the active context directory: %w", err)
+	}
+
+	kubeconfig := filepath.Join(k1Dir, consoleClusterDir, "kubeconfig")
+	if _, err := os.Stat(kubeconfig); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("console kubeconfig %s not found - is the console running? Start it with `kubefirst launch up`", kubeconfig)
+		}
+		return "", fmt.Errorf("unable to read console kubeconfig: %w", err)
+	}
+	return kubeconfig, nil
+}
+
+// ConsoleClientset connects to the local console cluster with its kubeconfig
+func ConsoleClientset() (kubernetes.Interface, error) {
+	kubeconfig, err := ConsoleKubeconfig()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load console kubeconfig %s: %w", kubeconfig, err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create console cluster client: %w", err)
+	}
+	return clientset, nil
+}
+
+// listPods returns the pods of the console cluster with the given labels
+func listPods(ctx context.Context, clientset kubernetes.Interface, labels []string) ([]v1.Pod, error) {
+	pods := []v1.Pod{}
+	for _, label := range labels {
+		list, err := clientset.CoreV1().Pods(consoleNamespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=" + label,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %s pods: %w", label, err)
+		}
+		pods = append(pods, list.Items...)
+	}
+	return pods, nil
+}
+
+// parsePodLine reads a line of pod logs requested with timestamps, the entries
+// are timed by kubernetes so they can be ordered with the CLI log
+func parsePodLine(pod, line string) Entry {
+	timestamp, text, found := strings.Cut(line, " ")
+	podTime, err := time.Parse(time.RFC3339Nano, timestamp)
+	if !found || err != nil {
+		entry := ParseLine(line)
+		entry.Source = pod
+		return entry
+	}
+
+	entry := ParseLine(text)
+	entry.Source = pod
+	entry.Time = podTime
+	return entry
+}
+
+// readPod sends the logs of the first container of a pod newer than since to
+// entries, until they end or ctx is canceled. The time of the last entry is returned.
+func readPod(ctx context.Context, clientset kubernetes.Interface, pod v1.Pod, follow bool, since time.Time, entries chan<- Entry) (time.Time, error) {
+	options := &v1.PodLogOptions{Follow: follow, Timestamps: true}
+	if len(pod.Spec.Containers) > 0 {
+		options.Container = pod.Spec.Containers[0].Name
+	}
+	if !since.IsZero() {
+		options.SinceTime = &metav1.Time{Time: since}
+	}
+
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, options).Stream(ctx)
+	if err != nil {
+		return since, fmt.Errorf("unable to read logs of pod %s: %w", pod.Name, err)
+	}
+	defer stream.Close()
+
+	last := since
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		entry := parsePodLine(pod.Name, scanner.Text())
+		// SinceTime is rounded to the second, skip the lines already sent
+		if !entry.Time.After(last) && !last.IsZero() {
+			continue
+		}
+		last = entry.Time
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+			return last, nil
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return last, fmt.Errorf("error reading logs of pod %s: %w", pod.Name, err)
+	}
+	return last, nil
+}
+
+// readPods sends the logs of the pods of a source newer than since to entries
+func readPods(ctx context.Context, clientset kubernetes.Interface, source string, since time.Time, entries chan<- Entry) error {
+	pods, err := listPods(ctx, clientset, sourceLabels(source))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pod := range pods {
+		if _, err := readPod(ctx, clientset, pod, false, since, entries); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// followPods follows the logs of the pods of a source into entries until ctx is
+// canceled, picking up the pods created meanwhile. Errors are sent to errs.
+func followPods(ctx context.Context, clientset kubernetes.Interface, source string, since time.Time, entries chan<- Entry, errs chan<- error) {
+	var (
+		mu     sync.Mutex
+		active = map[string]bool{}
+		last   = map[string]time.Time{}
+	)
+
+	ticker := time.NewTicker(podsInterval)
+	defer ticker.Stop()
+
+	for {
+		pods, err := listPods(ctx, clientset, sourceLabels(source))
+		if err != nil && ctx.Err() == nil {
+			sendError(ctx, errs, err)
+		}
+
+		for _, pod := range pods {
+			mu.Lock()
+			_, read := last[pod.Name]
+			finished := pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+			if active[pod.Name] || pod.Status.Phase == v1.PodPending || (finished && read) {
+				mu.Unlock()
+				continue
+			}
+			active[pod.Name] = true
+			podSince, ok := last[pod.Name]
+			if !ok {
+				podSince = since
+			}
+			mu.Unlock()
+
+			go func(pod v1.Pod, since time.Time) {
+				podLast, err := readPod(ctx, clientset, pod, true, since, entries)
+				if err != nil && ctx.Err() == nil {
+					sendError(ctx, errs, err)
+				}
+
+				// a restarted container is followed again from its last entry
+				mu.Lock()
+				delete(active, pod.Name)
+				last[pod.Name] = podLast
+				mu.Unlock()
+			}(pod, podSince)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package provisionLogs //nolint:revive // allowed during refactoring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// consolePod is a running pod of the console cluster with the given name label
+func consolePod(name, label string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: consoleNamespace,
+			Labels:    map[string]string{"app.kubernetes.io/name": label},
+		},
+		Spec:   v1.PodSpec{Containers: []v1.Container{{Name: label}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	for _, source := range Sources {
+		if err := ValidateSource(source); err != nil {
+			t.Errorf("ValidateSource(%q) error = %v", source, err)
+		}
+	}
+	if err := ValidateSource("pods"); err == nil {
+		t.Error(`ValidateSource("pods") error = nil`)
+	}
+}
+
+func TestSourceLabels(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{source: SourceCLI, want: nil},
+		{source: SourceAPI, want: []string{"kubefirst-api", "kubefirst-api-ee"}},
+		{source: SourceConsole, want: []string{"console"}},
+		{source: SourceAll, want: []string{"kubefirst-api", "kubefirst-api-ee", "console"}},
+	}
+
+	for _, tt := range tests {
+		if got := sourceLabels(tt.source); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("sourceLabels(%q) = %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParsePodLine(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	zerolog := logLine("info", at.Truncate(time.Second), "listening on :8081")
+
+	tests := []struct {
+		name        string
+		line        string
+		wantTime    time.Time
+		wantLevel   string
+		wantMessage string
+		wantRaw     string
+	}{
+		{
+			name:        "zerolog line",
+			line:        "2024-05-01T12:00:00.123456789Z " + zerolog,
+			wantTime:    at,
+			wantLevel:   "info",
+			wantMessage: "listening on :8081",
+			wantRaw:     zerolog,
+		},
+		{
+			name:     "plain line",
+			line:     "2024-05-01T12:00:00.123456789Z [GIN] GET /api/v1/health 200",
+			wantTime: at,
+			wantRaw:  "[GIN] GET /api/v1/health 200",
+		},
+		{
+			name:    "without timestamp",
+			line:    "fake logs",
+			wantRaw: "fake logs",
+		},
+		{
+			name:    "invalid timestamp",
+			line:    "yesterday listening",
+			wantRaw: "yesterday listening",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parsePodLine("kubefirst-api-7d9", tt.line)
+			if !got.Time.Equal(tt.wantTime) || got.Level != tt.wantLevel || got.Message != tt.wantMessage || got.Raw != tt.wantRaw {
+				t.Errorf("parsePodLine() = %+v", got)
+			}
+			if got.Source != "kubefirst-api-7d9" {
+				t.Errorf("source = %q, want the pod", got.Source)
+			}
+		})
+	}
+}
+
+func TestMergeEntries(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cli := []Entry{
+		{Time: at, Raw: "cli 1"},
+		{Time: at.Add(2 * time.Second), Raw: "cli 2"},
+		{Time: at.Add(2 * time.Second), Raw: "cli 2 continued"},
+	}
+	pods := []Entry{
+		{Time: at.Add(time.Second), Raw: "pod 1"},
+		{Time: at.Add(2 * time.Second), Raw: "pod 2"},
+		{Time: at.Add(3 * time.Second), Raw: "pod 3"},
+	}
+
+	got := []string{}
+	for _, entry := range mergeEntries(cli, pods) {
+		got = append(got, content(entry))
+	}
+	want := []string{"cli 1", "pod 1", "cli 2", "cli 2 continued", "pod 2", "pod 3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("mergeEntries() = %q, want %q", got, want)
+	}
+}
+
+func TestPodLogs(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		consolePod("kubefirst-api-7d9", "kubefirst-api"),
+		consolePod("console-5f4", "console"),
+	)
+
+	entries, err := PodLogs(context.Background(), clientset, SourceAll, time.Time{})
+	if err != nil {
+		t.Fatalf("PodLogs() error = %v", err)
+	}
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Text())
+	}
+	if want := []string{"[kubefirst-api-7d9] fake logs", "[console-5f4] fake logs"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("PodLogs() = %q, want %q", got, want)
+	}
+
+	entries, err = PodLogs(context.Background(), clientset, SourceCLI, time.Time{})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("PodLogs() of the CLI = %v, %v, want no pod logs", entries, err)
+	}
+}
+
+func TestPrintMergesThePodLogs(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	path := writeLog(t, logLine("info", at, "creating cluster"))
+	connect := func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(consolePod("kubefirst-api-7d9", "kubefirst-api")), nil
+	}
+
+	var out bytes.Buffer
+	if err := printLogs(&out, connect, path, SourceAPI, Filter{}); err != nil {
+		t.Fatalf("printLogs() error = %v", err)
+	}
+	// the fake pod logs have no timestamp so they come first
+	want := "[kubefirst-api-7d9] fake logs\n[cli] " + at.Local().Format("2006-01-02 15:04:05") + " INFO: creating cluster\n"
+	if out.String() != want {
+		t.Errorf("printLogs() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrintWithoutConsole(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	path := writeLog(t, logLine("info", at, "creating cluster"))
+	connect := func() (kubernetes.Interface, error) {
+		return nil, errors.New("console kubeconfig not found")
+	}
+
+	var out bytes.Buffer
+	err := printLogs(&out, connect, path, SourceAll, Filter{})
+	if err == nil || !strings.Contains(err.Error(), "unable to read the all logs: console kubeconfig not found") {
+		t.Errorf("printLogs() error = %v, want the console error", err)
+	}
+	if !strings.Contains(out.String(), "creating cluster") {
+		t.Errorf("printLogs() = %q, want the CLI log printed without the console", out.String())
+	}
+}
@@ -6,12 +6,45 @@ See the LICENSE file for more details.
 */
 package provisionLogs //nolint:revive // allowed during refactoring
 
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
 // Terminal model
 type provisionLogsModel struct {
-	logs []string
+	// path of the CLI log and source of the pod logs shown with it
+	path   string
+	source string
+	filter filterState
+
+	// entries kept in the viewer, the oldest are dropped past maxEntries
+	entries []Entry
+	// rendered holds the colored line of each entry
+	rendered []string
+
+	viewport viewport.Model
+	ready    bool
+	// follow keeps the view at the end of the log as lines are added
+	follow bool
+
+	// Search
+	searchInput textinput.Model
+	searching   bool
+	search      string
+	matches     []int
+	match       int
+
+	err error
 }
 
 // Bubbletea messages
-type logMessage struct {
-	message string
+
+// logBatchMsg carries the lines read from the log since the last batch
+type logBatchMsg struct {
+	entries []Entry
+}
+
+type logErrorMsg struct {
+	err error
 }
@@ -0,0 +1,242 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slices"
+)
+
+// Placeholder replaces secret values
+const Placeholder = "<redacted>"
+
+// minSecretLength keeps short values, such as booleans or ports, from being
+// registered and masking unrelated text
+const minSecretLength = 6
+
+// secretEnvs are the environment variables kubefirst reads credentials from
+var secretEnvs = []string{
+	"GITHUB_TOKEN",
+	"GITLAB_TOKEN",
+	"CIVO_TOKEN",
+	"DO_TOKEN",
+	"DO_SPACES_SECRET",
+	"VULTR_API_KEY",
+	"LINODE_TOKEN",
+	"CF_API_TOKEN",
+	"CF_ORIGIN_CA_ISSUER_API_TOKEN",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"NGROK_AUTHTOKEN",
+}
+
+// secretKeys are the parts of config keys whose values are secrets. Keys are
+// matched with their underscores read as dashes. A bare "key" would also match
+// keys such as kbot.public-key or kbot.gitlab-user-based-ssh-key-title.
+var secretKeys = []string{"token", "secret", "password", "credential", "private-key", "privatekey", "api-key", "apikey", "access-key"}
+
+// patterns match credentials whether they were registered or not
+var patterns = []*regexp.Regexp{
+	// PEM blocks, their line breaks may be escaped in JSON log entries
+	regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----[\s\S]*?-----END [A-Z0-9 ]+-----`),
+	// GitHub personal, OAuth, app and refresh tokens
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}`),
+	// GitLab personal access tokens
+	regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`),
+	// DigitalOcean personal access tokens
+	regexp.MustCompile(`\bdop_v1_[a-f0-9]{64}`),
+}
+
+var (
+	mu sync.RWMutex
+	// secrets are sorted the longest first so a secret containing another is masked whole
+	secrets []string
+)
+
+// Register masks values wherever they are redacted from now on, as written and
+// as escaped in JSON
+func Register(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, value := range values {
+		if len(value) < minSecretLength {
+			continue
+		}
+		forms := []string{value}
+		if escaped, err := json.Marshal(value); err == nil {
+			if form := string(escaped[1 : len(escaped)-1]); form != value {
+				forms = append(forms, form)
+			}
+		}
+		for _, form := range forms {
+			if !slices.Contains(secrets, form) {
+				secrets = append(secrets, form)
+			}
+		}
+	}
+
+	sort.SliceStable(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// RegisterEnv registers the credentials of the environment
+func RegisterEnv() {
+	for _, env := range secretEnvs {
+		Register(os.Getenv(env))
+	}
+}
+
+// RegisterSettings registers the values of the secret keys of the kubefirst config
+func RegisterSettings(settings map[string]interface{}) {
+	for key, value := range settings {
+		registerSetting(key, value)
+	}
+}
+
+func registerSetting(key string, value interface{}) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		RegisterSettings(value)
+	case []interface{}:
+		for _, item := range value {
+			registerSetting(key, item)
+		}
+	case string:
+		if IsSecretKey(key) {
+			Register(value)
+		}
+	}
+}
+
+// IsSecretKey reports whether a key of the kubefirst config holds a secret
+func IsSecretKey(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "_", "-")
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks the registered secrets and the known credential formats of s
+func String(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		if strings.Contains(s, secret) {
+			s = strings.ReplaceAll(s, secret, Placeholder)
+		}
+	}
+	mu.RUnlock()
+
+	for _, pattern := range patterns {
+		s = pattern.ReplaceAllLiteralString(s, Placeholder)
+	}
+	return s
+}
+
+// Settings returns a copy of the kubefirst config with the values of its secret
+// keys replaced by Placeholder, and the secrets in the other values masked
+func Settings(settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		redacted[key] = setting(key, value)
+	}
+	return redacted
+}
+
+func setting(key string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return Settings(value)
+	case []interface{}:
+		values := make([]interface{}, len(value))
+		for i, item := range value {
+			values[i] = setting(key, item)
+		}
+		return values
+	case string:
+		if value != "" && IsSecretKey(key) {
+			return Placeholder
+		}
+		return String(value)
+	default:
+		return value
+	}
+}
+
+// maxPending bounds the end of a line the Writer holds until its line break
+const maxPending = 64 * 1024
+
+// Writer masks secrets before writing to the underlying writer. Lines are
+// written once complete so a secret split across writes is still masked, Flush
+// writes the end of the last line.
+type Writer struct {
+	mu      sync.Mutex
+	w       io.Writer
+	pending []byte
+}
+
+// NewWriter wraps w, such as a log file
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the redacted complete lines of p, reporting the length of p so
+// callers don't see a short write when a secret was masked or a line is held
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	end := bytes.LastIndexByte(w.pending, '\n') + 1
+	if end == 0 && len(w.pending) < maxPending {
+		return len(p), nil
+	}
+	if end == 0 {
+		end = len(w.pending)
+	}
+
+	err := w.write(w.pending[:end])
+	w.pending = append(w.pending[:0], w.pending[end:]...)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the redacted end of the last line, written without a line break
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+	err := w.write(w.pending)
+	w.pending = w.pending[:0]
+	return err
+}
+
+func (w *Writer) write(p []byte) error {
+	if _, err := io.WriteString(w.w, String(string(p))); err != nil {
+		return fmt.Errorf("unable to write redacted output: %w", err)
+	}
+	return nil
+}
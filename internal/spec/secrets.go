@@ -0,0 +1,122 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/konstructio/kubefirst/internal/redact"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SecretRef points at a secret kept outside of the spec. Exactly one of
+// FromEnv and FromFile should be set; Value is only meant for local testing
+// as it stores the secret in the spec itself.
+type SecretRef struct {
+	Value    string `json:"value,omitempty"`
+	FromEnv  string `json:"fromEnv,omitempty"`
+	FromFile string `json:"fromFile,omitempty"`
+}
+
+func (r SecretRef) validate() error {
+	set := 0
+	for _, source := range []string{r.Value, r.FromEnv, r.FromFile} {
+		if source != "" {
+			set++
+		}
+	}
+
+	switch {
+	case set == 0:
+		return errors.New("one of value, fromEnv or fromFile is required")
+	case set > 1:
+		return errors.New("only one of value, fromEnv or fromFile may be set")
+	case r.FromEnv != "" && !envNamePattern.MatchString(r.FromEnv):
+		return fmt.Errorf("fromEnv %q is not an environment variable name", r.FromEnv)
+	}
+
+	return nil
+}
+
+// Resolve returns the secret value, field names the reference in errors. The
+// value is masked in the logs from then on.
+func (r SecretRef) Resolve(field string) (string, error) {
+	value, err := r.resolve(field)
+	if err != nil {
+		return "", err
+	}
+	redact.Register(value)
+	return value, nil
+}
+
+func (r SecretRef) resolve(field string) (string, error) {
+	switch {
+	case r.FromEnv != "":
+		value, ok := os.LookupEnv(r.FromEnv)
+		if !ok || value == "" {
+			return "", fmt.Errorf("%s: environment variable %q is not set", field, r.FromEnv)
+		}
+		return value, nil
+	case r.FromFile != "":
+		path, err := expandHome(r.FromFile)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", field, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s: unable to read secret file: %w", field, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	default:
+		return r.Value, nil
+	}
+}
+
+// ResolveSecrets exports every secret of the spec as an environment variable,
+// which is where the create commands read provider and git credentials from
+func (s *ClusterSpec) ResolveSecrets() error {
+	var errs []error
+	resolved := make(map[string]string, len(s.Spec.Secrets))
+	for name, ref := range s.Spec.Secrets {
+		value, err := ref.Resolve(fmt.Sprintf("spec.secrets.%s", name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		resolved[name] = value
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	for name, value := range resolved {
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("unable to export secret %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	homePath, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve home directory: %w", err)
+	}
+
+	return filepath.Join(homePath, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/konstructio/kubefirst/internal/redact"
+)
+
+func TestSecretRefResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("KUBEFIRST_TEST_TOKEN", "from-env")
+	t.Setenv("KUBEFIRST_TEST_EMPTY", "")
+	if err := os.WriteFile(filepath.Join(home, "token"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ref     SecretRef
+		want    string
+		wantErr string
+	}{
+		{name: "value", ref: SecretRef{Value: "inline"}, want: "inline"},
+		{name: "environment", ref: SecretRef{FromEnv: "KUBEFIRST_TEST_TOKEN"}, want: "from-env"},
+		{name: "file in the home directory", ref: SecretRef{FromFile: "~/token"}, want: "from-file"},
+		{name: "file", ref: SecretRef{FromFile: filepath.Join(home, "token")}, want: "from-file"},
+		{name: "unset environment", ref: SecretRef{FromEnv: "KUBEFIRST_TEST_UNSET"}, wantErr: `field: environment variable "KUBEFIRST_TEST_UNSET" is not set`},
+		{name: "empty environment", ref: SecretRef{FromEnv: "KUBEFIRST_TEST_EMPTY"}, wantErr: `environment variable "KUBEFIRST_TEST_EMPTY" is not set`},
+		{name: "missing file", ref: SecretRef{FromFile: "~/missing"}, wantErr: "field: unable to read secret file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ref.Resolve("field")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+			if masked := redact.String("token " + got); strings.Contains(masked, got) {
+				t.Errorf("redact.String() = %q, want the resolved secret masked", masked)
+			}
+		})
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("KUBEFIRST_TEST_SOURCE", "exported")
+	t.Setenv("KUBEFIRST_TEST_TARGET", "")
+	t.Setenv("KUBEFIRST_TEST_OTHER", "")
+
+	s := validSpec()
+	s.Spec.Secrets = map[string]SecretRef{
+		"KUBEFIRST_TEST_TARGET": {FromEnv: "KUBEFIRST_TEST_SOURCE"},
+		"KUBEFIRST_TEST_OTHER":  {Value: "inline"},
+	}
+	if err := s.ResolveSecrets(); err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+	if got := os.Getenv("KUBEFIRST_TEST_TARGET"); got != "exported" {
+		t.Errorf("KUBEFIRST_TEST_TARGET = %q, want %q", got, "exported")
+	}
+	if got := os.Getenv("KUBEFIRST_TEST_OTHER"); got != "inline" {
+		t.Errorf("KUBEFIRST_TEST_OTHER = %q, want %q", got, "inline")
+	}
+}
+
+func TestResolveSecretsExportsNothingOnFailure(t *testing.T) {
+	t.Setenv("KUBEFIRST_TEST_TARGET", "")
+
+	s := validSpec()
+	s.Spec.Secrets = map[string]SecretRef{
+		"KUBEFIRST_TEST_TARGET":  {Value: "inline"},
+		"KUBEFIRST_TEST_MISSING": {FromEnv: "KUBEFIRST_TEST_UNSET"},
+	}
+	err := s.ResolveSecrets()
+	if err == nil || !strings.Contains(err.Error(), "spec.secrets.KUBEFIRST_TEST_MISSING") {
+		t.Fatalf("ResolveSecrets() error = %v, want the missing secret reported", err)
+	}
+	if got := os.Getenv("KUBEFIRST_TEST_TARGET"); got != "" {
+		t.Errorf("KUBEFIRST_TEST_TARGET = %q, want nothing exported when a secret is missing", got)
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the only supported version of the cluster spec
+	APIVersion = "kubefirst.konstruct.io/v1alpha1"
+	// KindManagementCluster describes a management cluster provisioned by the console
+	KindManagementCluster = "ManagementCluster"
+)
+
+var (
+	supportedProviders    = []string{"akamai", "aws", "civo", "digitalocean", "google", "k3s", "vultr"}
+	supportedGitProviders = []string{"github", "gitlab"}
+	supportedGitProtocols = []string{"https", "ssh"}
+
+	clusterNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// ClusterSpec is a versioned, declarative description of a cluster
+type ClusterSpec struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Metadata   Metadata              `json:"metadata"`
+	Spec       ManagementClusterSpec `json:"spec"`
+}
+
+type Metadata struct {
+	Name string `json:"name"`
+}
+
+type ManagementClusterSpec struct {
+	Provider            string               `json:"provider"`
+	Region              string               `json:"region"`
+	AlertsEmail         string               `json:"alertsEmail"`
+	NodePools           []NodePool           `json:"nodePools,omitempty"`
+	DNS                 DNSSpec              `json:"dns"`
+	Git                 GitSpec              `json:"git"`
+	CatalogApps         []string             `json:"catalogApps,omitempty"`
+	InstallKubefirstPro *bool                `json:"installKubefirstPro,omitempty"`
+	UseTelemetry        *bool                `json:"useTelemetry,omitempty"`
+	AWS                 *AWSSpec             `json:"aws,omitempty"`
+	Google              *GoogleSpec          `json:"google,omitempty"`
+	K3s                 *K3sSpec             `json:"k3s,omitempty"`
+	Secrets             map[string]SecretRef `json:"secrets,omitempty"`
+}
+
+type NodePool struct {
+	Name         string `json:"name,omitempty"`
+	InstanceType string `json:"instanceType,omitempty"`
+	Count        int    `json:"count,omitempty"`
+}
+
+type DNSSpec struct {
+	Provider  string `json:"provider"`
+	Domain    string `json:"domain"`
+	Subdomain string `json:"subdomain,omitempty"`
+}
+
+type GitSpec struct {
+	Provider       string `json:"provider"`
+	Protocol       string `json:"protocol,omitempty"`
+	Owner          string `json:"owner"`
+	TemplateURL    string `json:"templateURL,omitempty"`
+	TemplateBranch string `json:"templateBranch,omitempty"`
+}
+
+type AWSSpec struct {
+	ECR bool `json:"ecr,omitempty"`
+}
+
+type GoogleSpec struct {
+	Project string `json:"project"`
+}
+
+type K3sSpec struct {
+	ServersPrivateIPs []string  `json:"serversPrivateIPs"`
+	ServersPublicIPs  []string  `json:"serversPublicIPs,omitempty"`
+	ServersArgs       []string  `json:"serversArgs,omitempty"`
+	SSHUser           string    `json:"sshUser"`
+	SSHPrivateKey     SecretRef `json:"sshPrivateKey"`
+}
+
+// ReadFile parses a YAML or JSON cluster spec, rejecting unknown fields
+func ReadFile(path string) (*ClusterSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cluster spec %q: %w", path, err)
+	}
+
+	var s ClusterSpec
+	if err := yaml.UnmarshalStrict(content, &s); err != nil {
+		return nil, fmt.Errorf("unable to parse cluster spec %q: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+// Validate reports every structural problem found in the spec
+func (s *ClusterSpec) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if s.APIVersion != APIVersion {
+		invalid("apiVersion", "unsupported version %q, expected %q", s.APIVersion, APIVersion)
+	}
+	if s.Kind != KindManagementCluster {
+		invalid("kind", "unsupported kind %q, expected %q", s.Kind, KindManagementCluster)
+	}
+	if !clusterNamePattern.MatchString(s.Metadata.Name) {
+		invalid("metadata.name", "%q must consist of lower case alphanumeric characters or '-'", s.Metadata.Name)
+	}
+
+	spec := s.Spec
+	if !slices.Contains(supportedProviders, spec.Provider) {
+		invalid("spec.provider", "%q is not one of %s", spec.Provider, supportedProviders)
+	}
+	if spec.Region == "" && spec.Provider != "k3s" {
+		invalid("spec.region", "required")
+	}
+	if spec.AlertsEmail == "" {
+		invalid("spec.alertsEmail", "required")
+	}
+
+	if len(spec.NodePools) > 1 {
+		invalid("spec.nodePools", "only a single node pool is currently supported, got %d", len(spec.NodePools))
+	}
+	for i, pool := range spec.NodePools {
+		if pool.Count < 0 {
+			invalid(fmt.Sprintf("spec.nodePools[%d].count", i), "must not be negative")
+		}
+	}
+
+	if spec.DNS.Provider == "" {
+		invalid("spec.dns.provider", "required")
+	}
+	if spec.DNS.Domain == "" {
+		invalid("spec.dns.domain", "required")
+	}
+
+	if !slices.Contains(supportedGitProviders, spec.Git.Provider) {
+		invalid("spec.git.provider", "%q is not one of %s", spec.Git.Provider, supportedGitProviders)
+	}
+	if spec.Git.Protocol != "" && !slices.Contains(supportedGitProtocols, spec.Git.Protocol) {
+		invalid("spec.git.protocol", "%q is not one of %s", spec.Git.Protocol, supportedGitProtocols)
+	}
+	if spec.Git.Owner == "" {
+		invalid("spec.git.owner", "required")
+	}
+
+	if spec.AWS != nil && spec.Provider != "aws" {
+		invalid("spec.aws", "only allowed when spec.provider is aws")
+	}
+	if spec.Google != nil && spec.Provider != "google" {
+		invalid("spec.google", "only allowed when spec.provider is google")
+	}
+	if spec.Provider == "google" && (spec.Google == nil || spec.Google.Project == "") {
+		invalid("spec.google.project", "required for the google provider")
+	}
+	if spec.K3s != nil && spec.Provider != "k3s" {
+		invalid("spec.k3s", "only allowed when spec.provider is k3s")
+	}
+	if spec.Provider == "k3s" {
+		switch {
+		case spec.K3s == nil:
+			invalid("spec.k3s", "required for the k3s provider")
+		default:
+			if len(spec.K3s.ServersPrivateIPs) == 0 {
+				invalid("spec.k3s.serversPrivateIPs", "required")
+			}
+			if spec.K3s.SSHUser == "" {
+				invalid("spec.k3s.sshUser", "required")
+			}
+			if err := spec.K3s.SSHPrivateKey.validate(); err != nil {
+				invalid("spec.k3s.sshPrivateKey", "%s", err)
+			}
+		}
+	}
+
+	for name, ref := range spec.Secrets {
+		if !envNamePattern.MatchString(name) {
+			invalid(fmt.Sprintf("spec.secrets.%s", name), "must be an environment variable name")
+		}
+		if err := ref.validate(); err != nil {
+			invalid(fmt.Sprintf("spec.secrets.%s", name), "%s", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Flag is a single create command flag derived from the spec
+type Flag struct {
+	Name  string
+	Value string
+}
+
+// Flags translates the spec into the flags of the provider's create command.
+// Unset optional values are omitted so the command's defaults still apply.
+func (s *ClusterSpec) Flags() ([]Flag, error) {
+	spec := s.Spec
+	var flags []Flag
+	add := func(name, value string) {
+		if value != "" {
+			flags = append(flags, Flag{Name: name, Value: value})
+		}
+	}
+
+	add("cluster-name", s.Metadata.Name)
+	add("cluster-type", "mgmt")
+	add("alerts-email", spec.AlertsEmail)
+	add("cloud-region", spec.Region)
+	add("dns-provider", spec.DNS.Provider)
+	add("domain-name", spec.DNS.Domain)
+	add("subdomain", spec.DNS.Subdomain)
+	add("git-provider", spec.Git.Provider)
+	add("git-protocol", spec.Git.Protocol)
+	add("gitops-template-url", spec.Git.TemplateURL)
+	add("gitops-template-branch", spec.Git.TemplateBranch)
+	add("install-catalog-apps", strings.Join(spec.CatalogApps, ","))
+
+	switch spec.Git.Provider {
+	case "github":
+		add("github-org", spec.Git.Owner)
+	case "gitlab":
+		add("gitlab-group", spec.Git.Owner)
+	}
+
+	if len(spec.NodePools) == 1 {
+		add("node-type", spec.NodePools[0].InstanceType)
+		if spec.NodePools[0].Count > 0 {
+			add("node-count", strconv.Itoa(spec.NodePools[0].Count))
+		}
+	}
+
+	if spec.InstallKubefirstPro != nil {
+		add("install-kubefirst-pro", strconv.FormatBool(*spec.InstallKubefirstPro))
+	}
+	if spec.UseTelemetry != nil {
+		add("use-telemetry", strconv.FormatBool(*spec.UseTelemetry))
+	}
+
+	if spec.AWS != nil {
+		add("ecr", strconv.FormatBool(spec.AWS.ECR))
+	}
+	if spec.Google != nil {
+		add("google-project", spec.Google.Project)
+	}
+	if spec.K3s != nil {
+		privateKey, err := spec.K3s.SSHPrivateKey.Resolve("spec.k3s.sshPrivateKey")
+		if err != nil {
+			return nil, err
+		}
+
+		add("servers-private-ips", strings.Join(spec.K3s.ServersPrivateIPs, ","))
+		add("servers-public-ips", strings.Join(spec.K3s.ServersPublicIPs, ","))
+		add("servers-args", strings.Join(spec.K3s.ServersArgs, ","))
+		add("ssh-user", spec.K3s.SSHUser)
+		add("ssh-privatekey", privateKey)
+	}
+
+	return flags, nil
+}
@@ -0,0 +1,315 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package spec
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validSpec returns a civo spec that passes validation
+func validSpec() ClusterSpec {
+	return ClusterSpec{
+		APIVersion: APIVersion,
+		Kind:       KindManagementCluster,
+		Metadata:   Metadata{Name: "kubefirst"},
+		Spec: ManagementClusterSpec{
+			Provider:    "civo",
+			Region:      "NYC1",
+			AlertsEmail: "admin@example.com",
+			NodePools:   []NodePool{{InstanceType: "g4s.kube.large", Count: 4}},
+			DNS:         DNSSpec{Provider: "civo", Domain: "example.com"},
+			Git:         GitSpec{Provider: "github", Owner: "example-org"},
+			Secrets:     map[string]SecretRef{"CIVO_TOKEN": {FromEnv: "CIVO_TOKEN"}},
+		},
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	s, err := ReadFile(write("spec.yaml", `
+apiVersion: kubefirst.konstruct.io/v1alpha1
+kind: ManagementCluster
+metadata:
+  name: kubefirst
+spec:
+  provider: civo
+  dns:
+    domain: example.com
+`))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if s.Metadata.Name != "kubefirst" || s.Spec.Provider != "civo" || s.Spec.DNS.Domain != "example.com" {
+		t.Errorf("ReadFile() = %+v, want the spec of the file", s)
+	}
+
+	if _, err := ReadFile(write("unknown.yaml", "spec:\n  providr: civo\n")); err == nil {
+		t.Error("ReadFile() error = nil, want unknown fields to be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(s *ClusterSpec)
+		wantErr []string
+	}{
+		{
+			name:   "valid",
+			mutate: func(*ClusterSpec) {},
+		},
+		{
+			name: "wrong version and kind",
+			mutate: func(s *ClusterSpec) {
+				s.APIVersion = "v1"
+				s.Kind = "Cluster"
+			},
+			wantErr: []string{"apiVersion: unsupported version", "kind: unsupported kind"},
+		},
+		{
+			name:    "invalid name",
+			mutate:  func(s *ClusterSpec) { s.Metadata.Name = "Kubefirst_1" },
+			wantErr: []string{"metadata.name"},
+		},
+		{
+			name: "missing required fields",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Region = ""
+				s.Spec.AlertsEmail = ""
+				s.Spec.DNS = DNSSpec{}
+				s.Spec.Git.Owner = ""
+			},
+			wantErr: []string{"spec.region: required", "spec.alertsEmail: required", "spec.dns.provider: required", "spec.dns.domain: required", "spec.git.owner: required"},
+		},
+		{
+			name: "unsupported providers",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Provider = "azure"
+				s.Spec.Git.Provider = "bitbucket"
+				s.Spec.Git.Protocol = "ftp"
+			},
+			wantErr: []string{"spec.provider", "spec.git.provider", "spec.git.protocol"},
+		},
+		{
+			name: "several node pools",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.NodePools = []NodePool{{Count: 1}, {Count: -1}}
+			},
+			wantErr: []string{"spec.nodePools: only a single node pool", "spec.nodePools[1].count: must not be negative"},
+		},
+		{
+			name: "provider settings of another provider",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.AWS = &AWSSpec{ECR: true}
+				s.Spec.Google = &GoogleSpec{Project: "project"}
+			},
+			wantErr: []string{"spec.aws: only allowed when spec.provider is aws", "spec.google: only allowed when spec.provider is google"},
+		},
+		{
+			name: "google without a project",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Provider = "google"
+			},
+			wantErr: []string{"spec.google.project: required"},
+		},
+		{
+			name: "k3s without servers",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Provider = "k3s"
+				s.Spec.Region = ""
+				s.Spec.K3s = &K3sSpec{}
+			},
+			wantErr: []string{"spec.k3s.serversPrivateIPs: required", "spec.k3s.sshUser: required", "spec.k3s.sshPrivateKey: one of value, fromEnv or fromFile is required"},
+		},
+		{
+			name: "invalid secrets",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Secrets = map[string]SecretRef{
+					"NOT-AN-ENV": {Value: "secret"},
+					"BOTH":       {FromEnv: "TOKEN", FromFile: "token"},
+					"BAD_ENV":    {FromEnv: "1TOKEN"},
+				}
+			},
+			wantErr: []string{
+				"spec.secrets.NOT-AN-ENV: must be an environment variable name",
+				"spec.secrets.BOTH: only one of value, fromEnv or fromFile may be set",
+				`spec.secrets.BAD_ENV: fromEnv "1TOKEN" is not an environment variable name`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := validSpec()
+			tt.mutate(&s)
+
+			err := s.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want it to report %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFlags(t *testing.T) {
+	t.Setenv("K3S_SSH_KEY", "private-key")
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		mutate func(s *ClusterSpec)
+		want   map[string]string
+	}{
+		{
+			name:   "defaults are left to the create command",
+			mutate: func(*ClusterSpec) {},
+			want: map[string]string{
+				"cluster-name": "kubefirst",
+				"cluster-type": "mgmt",
+				"alerts-email": "admin@example.com",
+				"cloud-region": "NYC1",
+				"dns-provider": "civo",
+				"domain-name":  "example.com",
+				"git-provider": "github",
+				"github-org":   "example-org",
+				"node-type":    "g4s.kube.large",
+				"node-count":   "4",
+			},
+		},
+		{
+			name: "every optional value",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.DNS.Subdomain = "platform"
+				s.Spec.Git = GitSpec{Provider: "gitlab", Protocol: "https", Owner: "example-group", TemplateURL: "https://example.com/template.git", TemplateBranch: "main"}
+				s.Spec.CatalogApps = []string{"datadog", "ngrok"}
+				s.Spec.InstallKubefirstPro = &no
+				s.Spec.UseTelemetry = &yes
+				s.Spec.NodePools = []NodePool{{InstanceType: "g4s.kube.large"}}
+			},
+			want: map[string]string{
+				"cluster-name":           "kubefirst",
+				"cluster-type":           "mgmt",
+				"alerts-email":           "admin@example.com",
+				"cloud-region":           "NYC1",
+				"dns-provider":           "civo",
+				"domain-name":            "example.com",
+				"subdomain":              "platform",
+				"git-provider":           "gitlab",
+				"git-protocol":           "https",
+				"gitlab-group":           "example-group",
+				"gitops-template-url":    "https://example.com/template.git",
+				"gitops-template-branch": "main",
+				"install-catalog-apps":   "datadog,ngrok",
+				"install-kubefirst-pro":  "false",
+				"use-telemetry":          "true",
+				"node-type":              "g4s.kube.large",
+			},
+		},
+		{
+			name: "aws",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Provider = "aws"
+				s.Spec.NodePools = nil
+				s.Spec.AWS = &AWSSpec{ECR: true}
+			},
+			want: map[string]string{
+				"cluster-name": "kubefirst",
+				"cluster-type": "mgmt",
+				"alerts-email": "admin@example.com",
+				"cloud-region": "NYC1",
+				"dns-provider": "civo",
+				"domain-name":  "example.com",
+				"git-provider": "github",
+				"github-org":   "example-org",
+				"ecr":          "true",
+			},
+		},
+		{
+			name: "k3s",
+			mutate: func(s *ClusterSpec) {
+				s.Spec.Provider = "k3s"
+				s.Spec.Region = ""
+				s.Spec.NodePools = nil
+				s.Spec.K3s = &K3sSpec{
+					ServersPrivateIPs: []string{"10.0.0.1", "10.0.0.2"},
+					ServersArgs:       []string{"--disable=traefik"},
+					SSHUser:           "root",
+					SSHPrivateKey:     SecretRef{FromEnv: "K3S_SSH_KEY"},
+				}
+			},
+			want: map[string]string{
+				"cluster-name":        "kubefirst",
+				"cluster-type":        "mgmt",
+				"alerts-email":        "admin@example.com",
+				"dns-provider":        "civo",
+				"domain-name":         "example.com",
+				"git-provider":        "github",
+				"github-org":          "example-org",
+				"servers-private-ips": "10.0.0.1,10.0.0.2",
+				"servers-args":        "--disable=traefik",
+				"ssh-user":            "root",
+				"ssh-privatekey":      "private-key",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := validSpec()
+			tt.mutate(&s)
+
+			flags, err := s.Flags()
+			if err != nil {
+				t.Fatalf("Flags() error = %v", err)
+			}
+			got := make(map[string]string, len(flags))
+			for _, flag := range flags {
+				if _, ok := got[flag.Name]; ok {
+					t.Errorf("Flags() sets --%s twice", flag.Name)
+				}
+				got[flag.Name] = flag.Value
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagsFailsOnMissingSSHKey(t *testing.T) {
+	s := validSpec()
+	s.Spec.Provider = "k3s"
+	s.Spec.K3s = &K3sSpec{SSHPrivateKey: SecretRef{FromEnv: "KUBEFIRST_TEST_UNSET_KEY"}}
+
+	_, err := s.Flags()
+	if err == nil || !strings.Contains(err.Error(), "spec.k3s.sshPrivateKey") {
+		t.Fatalf("Flags() error = %v, want the unresolved ssh key to be reported", err)
+	}
+}
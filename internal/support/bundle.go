@@ -0,0 +1,387 @@
+/*
+Copyright (C) 2021-2023, Kubefirst
+
+This program is licensed under MIT.
+See the LICENSE file for more details.
+*/
+package support
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/cluster"
+	"github.com/konstructio/kubefirst/internal/provisionLogs"
+	"github.com/konstructio/kubefirst/internal/redact"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// collectTimeout bounds each call to the console API, the console cluster and helm
+const collectTimeout = 30 * time.Second
+
+// Options selects what is collected in a bundle
+type Options struct {
+	// Info is the output of `kubefirst info`
+	Info string
+	// ClusterName selects the cluster record, every cluster of the console when empty
+	ClusterName string
+	// KubefirstVersion is recorded in the manifest
+	KubefirstVersion string
+}
+
+// Manifest describes the content of a bundle, it is written to manifest.json
+type Manifest struct {
+	Generated        time.Time `json:"generated"`
+	KubefirstVersion string    `json:"kubefirst_version"`
+	Files            []File    `json:"files"`
+}
+
+// File is an entry of the manifest, Error is set when it couldn't be collected
+type File struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	Size        int64  `json:"size"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Errors returns the files that couldn't be collected
+func (m Manifest) Errors() []File {
+	failed := []File{}
+	for _, file := range m.Files {
+		if file.Error != "" {
+			failed = append(failed, file)
+		}
+	}
+	return failed
+}
+
+type bundle struct {
+	tw       *tar.Writer
+	manifest Manifest
+}
+
+// add writes a file to the archive and records it in the manifest
+func (b *bundle) add(path, description string, content []byte) error {
+	header := &tar.Header{
+		Name:    path,
+		Mode:    0o600,
+		Size:    int64(len(content)),
+		ModTime: b.manifest.Generated,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("unable to add %s to the bundle: %w", path, err)
+	}
+	if _, err := b.tw.Write(content); err != nil {
+		return fmt.Errorf("unable to add %s to the bundle: %w", path, err)
+	}
+
+	b.manifest.Files = append(b.manifest.Files, File{Path: path, Description: description, Size: int64(len(content))})
+	return nil
+}
+
+// collect adds the content returned by collector, or records why it failed.
+// Only the errors of the archive itself are returned.
+func (b *bundle) collect(path, description string, collector func() ([]byte, error)) error {
+	content, err := collector()
+	if err != nil {
+		b.manifest.Files = append(b.manifest.Files, File{Path: path, Description: description, Error: err.Error()})
+		return nil
+	}
+	return b.add(path, description, content)
+}
+
+// WriteBundle writes a tar.gz diagnostic archive to path. What can't be
+// collected, such as the console cluster when it isn't running, is recorded in
+// the manifest instead of failing the bundle.
+func WriteBundle(ctx context.Context, path string, opts Options) (Manifest, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("unable to create support bundle: %w", err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	b := &bundle{
+		tw: tar.NewWriter(gz),
+		manifest: Manifest{
+			Generated:        time.Now().UTC(),
+			KubefirstVersion: opts.KubefirstVersion,
+		},
+	}
+
+	if err := b.collectAll(ctx, opts); err != nil {
+		return b.manifest, err
+	}
+
+	manifest, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return b.manifest, fmt.Errorf("unable to marshal bundle manifest: %w", err)
+	}
+	if err := b.add("manifest.json", "What was collected in this bundle", manifest); err != nil {
+		return b.manifest, err
+	}
+
+	if err := b.tw.Close(); err != nil {
+		return b.manifest, fmt.Errorf("unable to write support bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return b.manifest, fmt.Errorf("unable to write support bundle: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return b.manifest, fmt.Errorf("unable to write support bundle: %w", err)
+	}
+	return b.manifest, nil
+}
+
+func (b *bundle) collectAll(ctx context.Context, opts Options) error {
+	if err := b.addLogs(); err != nil {
+		return err
+	}
+
+	if err := b.collect("config.yaml", "kubefirst config, secrets redacted", redactedConfig); err != nil {
+		return err
+	}
+	if err := b.collect("info.txt", "Output of kubefirst info", func() ([]byte, error) {
+		return []byte(opts.Info), nil
+	}); err != nil {
+		return err
+	}
+	if err := b.collect("clusters.json", "Cluster records of the console API, secrets redacted", func() ([]byte, error) {
+		return clusterRecords(ctx, opts.ClusterName)
+	}); err != nil {
+		return err
+	}
+
+	clientset, clientErr := provisionLogs.ConsoleClientset()
+	consoleCollector := func(collector func(kubernetes.Interface) ([]byte, error)) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			if clientErr != nil {
+				return nil, clientErr
+			}
+			return collector(clientset)
+		}
+	}
+	if err := b.collect("console/events.txt", "Events of the console cluster", consoleCollector(func(clientset kubernetes.Interface) ([]byte, error) {
+		return events(ctx, clientset)
+	})); err != nil {
+		return err
+	}
+	if err := b.collect("console/pods.txt", "Pod statuses of the console cluster", consoleCollector(func(clientset kubernetes.Interface) ([]byte, error) {
+		return pods(ctx, clientset)
+	})); err != nil {
+		return err
+	}
+	if err := b.collect("console/kubefirst-api.log", "Logs of the kubefirst-api pods", consoleCollector(func(clientset kubernetes.Interface) ([]byte, error) {
+		return apiLogs(ctx, clientset)
+	})); err != nil {
+		return err
+	}
+
+	return b.collect("console/helm.txt", "Helm releases of the console cluster", func() ([]byte, error) {
+		return helmReleases(ctx)
+	})
+}
+
+// addLogs adds the CLI log files, the most recent first
+func (b *bundle) addLogs() error {
+	files, err := provisionLogs.ListFiles(viper.GetString("k1-paths.logs-dir"))
+	if err != nil {
+		b.manifest.Files = append(b.manifest.Files, File{Path: "logs/", Description: "kubefirst CLI logs", Error: err.Error()})
+		return nil
+	}
+	provisionLogs.ReadCommands(files)
+
+	for _, file := range files {
+		description := "kubefirst CLI log"
+		if file.Command != "" {
+			description += " of " + file.Command
+		}
+		if err := b.collect(filepath.ToSlash(filepath.Join("logs", file.Name)), description, func() ([]byte, error) {
+			content, err := os.ReadFile(file.Path)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read log file: %w", err)
+			}
+			// logs written before secrets were masked may still hold some
+			return []byte(redact.String(string(content))), nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactedConfig dumps the kubefirst config with its secrets redacted
+func redactedConfig() ([]byte, error) {
+	content, err := yaml.Marshal(redact.Settings(viper.AllSettings()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal kubefirst config: %w", err)
+	}
+	return content, nil
+}
+
+// clusterRecords returns the records of the console API, secrets redacted
+func clusterRecords(ctx context.Context, clusterName string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+
+	var records []apiTypes.Cluster
+	if clusterName != "" {
+		cl, err := cluster.GetCluster(ctx, clusterName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get cluster %q: %w", clusterName, err)
+		}
+		records = []apiTypes.Cluster{cl}
+	} else {
+		clusters, err := cluster.GetClusters(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get clusters: %w", err)
+		}
+		records = clusters
+	}
+
+	for i := range records {
+		records[i] = cluster.RedactCluster(records[i])
+	}
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal clusters: %w", err)
+	}
+	return content, nil
+}
+
+// events lists the events of the console cluster, the oldest first
+func events(ctx context.Context, clientset kubernetes.Interface) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+
+	list, err := clientset.CoreV1().Events(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list events: %w", err)
+	}
+
+	items := list.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].LastTimestamp.Before(&items[j].LastTimestamp)
+	})
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LAST SEEN\tNAMESPACE\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+	for _, event := range items {
+		object := fmt.Sprintf("%s/%s", strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", event.LastTimestamp.UTC().Format(time.RFC3339), event.Namespace, event.Type, event.Reason, object, event.Count, strings.TrimSpace(event.Message))
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, fmt.Errorf("unable to format events: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// pods lists the pods of the console cluster with the state of their containers
+func pods(ctx context.Context, clientset kubernetes.Interface) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+
+	list, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods: %w", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tNAME\tPHASE\tREADY\tRESTARTS\tCREATED\tCONTAINERS")
+	for _, pod := range list.Items {
+		ready, restarts := 0, int32(0)
+		containers := []string{}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+			restarts += status.RestartCount
+
+			state := "running"
+			switch {
+			case status.State.Waiting != nil:
+				state = "waiting: " + status.State.Waiting.Reason
+			case status.State.Terminated != nil:
+				state = fmt.Sprintf("terminated: %s (exit code %d)", status.State.Terminated.Reason, status.State.Terminated.ExitCode)
+			}
+			containers = append(containers, fmt.Sprintf("%s %s", status.Name, state))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%d\t%s\t%s\n", pod.Namespace, pod.Name, pod.Status.Phase, ready, len(pod.Spec.Containers), restarts, pod.CreationTimestamp.UTC().Format(time.RFC3339), strings.Join(containers, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, fmt.Errorf("unable to format pods: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// apiLogs reads the logs of the kubefirst-api pods
+func apiLogs(ctx context.Context, clientset kubernetes.Interface) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+
+	entries, err := provisionLogs.PodLogs(ctx, clientset, provisionLogs.SourceAPI, time.Time{})
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		fmt.Fprintln(&buf, redact.String(entry.Text()))
+	}
+	if err != nil && buf.Len() == 0 {
+		return nil, fmt.Errorf("unable to read kubefirst-api logs: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// helmReleases runs the helm client downloaded by `kubefirst launch up` against
+// the console cluster
+func helmReleases(ctx context.Context) ([]byte, error) {
+	kubeconfig, err := provisionLogs.ConsoleKubeconfig()
+	if err != nil {
+		return nil, err
+	}
+
+	helmClient := filepath.Join(filepath.Dir(kubeconfig), "tools", "helm")
+	if _, err := os.Stat(helmClient); err != nil {
+		return nil, fmt.Errorf("helm client not found: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+
+	var buf bytes.Buffer
+	var errs []error
+	for _, args := range [][]string{
+		{"list", "--all-namespaces", "--all"},
+		{"status", "kubefirst", "--namespace", "kubefirst"},
+	} {
+		args = append(args, "--kubeconfig", kubeconfig)
+		fmt.Fprintf(&buf, "$ helm %s\n", strings.Join(args, " "))
+		output, err := exec.CommandContext(ctx, helmClient, args...).CombinedOutput()
+		buf.Write(output)
+		fmt.Fprintln(&buf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("helm %s failed: %w", args[0], err))
+		}
+	}
+	// the output of a failed command still tells why, only fail when neither ran
+	if len(errs) == 2 {
+		return nil, errors.Join(errs...)
+	}
+	return buf.Bytes(), nil
+}
@@ -6,6 +6,12 @@ See the LICENSE file for more details.
 */
 package types
 
+// Cluster types accepted by the --cluster-type flag
+const (
+	ClusterTypeMgmt     = "mgmt"
+	ClusterTypeWorkload = "workload"
+)
+
 type CliFlags struct {
 	AlertsEmail          string
 	Ci                   bool
@@ -34,4 +40,7 @@ type CliFlags struct {
 	K3sServersPublicIPs  []string
 	K3sServersArgs       []string
 	InstallKubefirstPro  bool
+	DryRun               bool
+	ParentCluster        string
+	Environment          string
 }
@@ -15,6 +15,11 @@ type ProxyCreateClusterRequest struct {
 	URL  string                     `bson:"url" json:"url"`
 }
 
+type ProxyCreateWorkloadClusterRequest struct {
+	Body apiTypes.WorkloadCluster `bson:"body" json:"body"`
+	URL  string                   `bson:"url" json:"url"`
+}
+
 type ProxyResetClusterRequest struct {
 	URL string `bson:"url" json:"url"`
 }
@@ -37,6 +37,37 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 		return cliFlags, fmt.Errorf("failed to get cluster-name flag: %w", err)
 	}
 
+	clusterTypeFlag, err := cmd.Flags().GetString("cluster-type")
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, fmt.Errorf("failed to get cluster-type flag: %w", err)
+	}
+
+	parentClusterFlag, err := cmd.Flags().GetString("parent")
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, fmt.Errorf("failed to get parent flag: %w", err)
+	}
+
+	environmentFlag, err := cmd.Flags().GetString("environment")
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, fmt.Errorf("failed to get environment flag: %w", err)
+	}
+
+	switch clusterTypeFlag {
+	case types.ClusterTypeMgmt:
+		if parentClusterFlag != "" {
+			return cliFlags, fmt.Errorf("--parent can only be used with --cluster-type %s", types.ClusterTypeWorkload)
+		}
+	case types.ClusterTypeWorkload:
+		if parentClusterFlag == "" {
+			return cliFlags, fmt.Errorf("--parent is required with --cluster-type %s", types.ClusterTypeWorkload)
+		}
+	default:
+		return cliFlags, fmt.Errorf("invalid cluster type %q - must be one of %s or %s", clusterTypeFlag, types.ClusterTypeMgmt, types.ClusterTypeWorkload)
+	}
+
 	dnsProviderFlag, err := cmd.Flags().GetString("dns-provider")
 	if err != nil {
 		progress.Error(err.Error())
@@ -123,6 +154,12 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 		return cliFlags, fmt.Errorf("failed to get install-kubefirst-pro flag: %w", err)
 	}
 
+	dryRunFlag, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		progress.Error(err.Error())
+		return cliFlags, fmt.Errorf("failed to get dry-run flag: %w", err)
+	}
+
 	if cloudProvider == "aws" {
 		ecrFlag, err := cmd.Flags().GetBool("ecr")
 		if err != nil {
@@ -183,6 +220,9 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 	cliFlags.AlertsEmail = alertsEmailFlag
 	cliFlags.CloudRegion = cloudRegionFlag
 	cliFlags.ClusterName = clusterNameFlag
+	cliFlags.ClusterType = clusterTypeFlag
+	cliFlags.ParentCluster = parentClusterFlag
+	cliFlags.Environment = environmentFlag
 	cliFlags.DNSProvider = dnsProviderFlag
 	cliFlags.SubDomainName = subdomainFlag
 	cliFlags.DomainName = domainNameFlag
@@ -198,6 +238,12 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 	cliFlags.NodeCount = nodeCountFlag
 	cliFlags.InstallCatalogApps = installCatalogAppsFlag
 	cliFlags.InstallKubefirstPro = installKubefirstProFlag
+	cliFlags.DryRun = dryRunFlag
+
+	// the local config describes the management cluster, workload clusters live in its console
+	if cliFlags.ClusterType == types.ClusterTypeWorkload {
+		return cliFlags, nil
+	}
 
 	viper.Set("flags.alerts-email", cliFlags.AlertsEmail)
 	viper.Set("flags.cluster-name", cliFlags.ClusterName)
@@ -206,6 +252,7 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 	viper.Set("flags.git-provider", cliFlags.GitProvider)
 	viper.Set("flags.git-protocol", cliFlags.GitProtocol)
 	viper.Set("flags.cloud-region", cliFlags.CloudRegion)
+	viper.Set("flags.install-kubefirst-pro", cliFlags.InstallKubefirstPro)
 	viper.Set("kubefirst.cloud-provider", cloudProvider)
 	if cloudProvider == "k3s" {
 		viper.Set("flags.servers-private-ips", cliFlags.K3sServersPrivateIPs)
@@ -220,3 +267,11 @@ func GetFlags(cmd *cobra.Command, cloudProvider string) (types.CliFlags, error)
 
 	return cliFlags, nil
 }
+
+// AddProvisioningFlags declares the flags shared by the create commands and
+// apply. main reads --progress before the command runs.
+func AddProvisioningFlags(cmd *cobra.Command) {
+	cmd.Flags().String("progress", "", fmt.Sprintf("Progress output - one of: %s, plain when stdout is not a terminal", progress.OutputModes))
+	cmd.Flags().String("notify-webhook", "", "Webhook receiving a JSON notification once the provisioning of a cluster ends, overrides notifications.webhook of the kubefirst config")
+	cmd.Flags().Bool("catalog-offline", false, "Read the gitops catalog index from the cache instead of GitHub, the cache is refreshed whenever the catalog is read online")
+}
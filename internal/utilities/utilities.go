@@ -17,6 +17,7 @@ import (
 	"github.com/konstructio/kubefirst-api/pkg/configs"
 	"github.com/konstructio/kubefirst-api/pkg/k8s"
 	apiTypes "github.com/konstructio/kubefirst-api/pkg/types"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
 	"github.com/konstructio/kubefirst/internal/types"
 	"github.com/rs/zerolog/log"
@@ -29,13 +30,13 @@ import (
 // CreateK1ClusterDirectory
 func CreateK1ClusterDirectory(clusterName string) {
 	// Create k1 dir if it doesn't exist
-	homePath, err := os.UserHomeDir()
+	contextDir, err := contexts.CurrentK1Dir()
 	if err != nil {
 		log.Info().Msg(err.Error())
 		return
 	}
 
-	k1Dir := fmt.Sprintf("%s/.k1/%s", homePath, clusterName)
+	k1Dir := fmt.Sprintf("%s/%s", contextDir, clusterName)
 	if _, err := os.Stat(k1Dir); os.IsNotExist(err) {
 		err := os.MkdirAll(k1Dir, os.ModePerm)
 		if err != nil {
@@ -152,7 +153,7 @@ func CreateClusterDefinitionRecordFromRaw(gitAuth apiTypes.GitAuth, cliFlags typ
 		CloudRegion:            viper.GetString("flags.cloud-region"),
 		DomainName:             domainName,
 		SubdomainName:          cliFlags.SubDomainName,
-		Type:                   "mgmt",
+		Type:                   cliFlags.ClusterType,
 		NodeType:               cliFlags.NodeType,
 		NodeCount:              stringToIntNodeCount,
 		GitopsTemplateURL:      cliFlags.GitopsTemplateURL,
@@ -175,6 +176,10 @@ func CreateClusterDefinitionRecordFromRaw(gitAuth apiTypes.GitAuth, cliFlags typ
 		},
 	}
 
+	if cl.Type == "" {
+		cl.Type = types.ClusterTypeMgmt
+	}
+
 	if cl.GitopsTemplateBranch == "" {
 		cl.GitopsTemplateBranch = configs.K1Version
 
@@ -228,6 +233,31 @@ func CreateClusterDefinitionRecordFromRaw(gitAuth apiTypes.GitAuth, cliFlags typ
 	return cl
 }
 
+// CreateWorkloadClusterRecordFromRaw builds the workload cluster submitted to the
+// console of the management cluster named by cliFlags.ParentCluster
+func CreateWorkloadClusterRecordFromRaw(cliFlags types.CliFlags) (apiTypes.WorkloadCluster, error) {
+	stringToIntNodeCount, err := strconv.Atoi(cliFlags.NodeCount)
+	if err != nil {
+		return apiTypes.WorkloadCluster{}, fmt.Errorf("invalid node count %q: %w", cliFlags.NodeCount, err)
+	}
+
+	return apiTypes.WorkloadCluster{
+		AdminEmail:    cliFlags.AlertsEmail,
+		CloudProvider: cliFlags.CloudProvider,
+		ClusterName:   cliFlags.ClusterName,
+		ClusterType:   types.ClusterTypeWorkload,
+		CloudRegion:   cliFlags.CloudRegion,
+		DomainName:    cliFlags.DomainName,
+		DnsProvider:   cliFlags.DNSProvider,
+		Environment: apiTypes.Environment{
+			Name: cliFlags.Environment,
+		},
+		InstanceSize: cliFlags.NodeType,
+		NodeType:     cliFlags.NodeType,
+		NodeCount:    stringToIntNodeCount,
+	}, nil
+}
+
 func ExportCluster(cluster apiTypes.Cluster, kcfg *k8s.KubernetesClient) error {
 	cluster.Status = "provisioned"
 	cluster.InProgress = false
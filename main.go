@@ -8,14 +8,21 @@ package main
 
 import (
 	"fmt"
+	"io"
 	stdLog "log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/konstructio/kubefirst-api/pkg/configs"
 	utils "github.com/konstructio/kubefirst-api/pkg/utils"
 	"github.com/konstructio/kubefirst/cmd"
+	"github.com/konstructio/kubefirst/internal/contexts"
 	"github.com/konstructio/kubefirst/internal/progress"
+	"github.com/konstructio/kubefirst/internal/provisionLogs"
+	"github.com/konstructio/kubefirst/internal/redact"
+	"github.com/konstructio/kubefirst/internal/spec"
 	zeroLog "github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -23,31 +30,72 @@ import (
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run sets up configuration and logging, executes the command and returns the process exit code
+func run() int {
 	argsWithProg := os.Args
 
-	bubbleTeaBlacklist := []string{"completion", "help", "--help", "-h", "quota", "logs"}
+	// commands without progress, matched with their subcommands
+	bubbleTeaBlacklist := []string{"completion", "help", "aws quota", "civo quota", "logs", "login", "launch cluster get", "launch cluster describe", "context", "launch cluster ui", "launch cluster summary", "support-bundle"}
+	bubbleTeaFlagBlacklist := []string{"--help", "-h", "--mock"}
+	command := cmd.CommandPath(argsWithProg[1:])
 	canRunBubbleTea := true
 
+	for _, blacklisted := range bubbleTeaBlacklist {
+		if command == blacklisted || strings.HasPrefix(command, blacklisted+" ") {
+			canRunBubbleTea = false
+		}
+	}
 	for _, arg := range argsWithProg {
-		isBlackListed := slices.Contains(bubbleTeaBlacklist, arg)
-
-		if isBlackListed {
+		if slices.Contains(bubbleTeaFlagBlacklist, arg) {
 			canRunBubbleTea = false
 		}
 	}
 
+	// without a terminal, such as in CI, progress is printed line by line instead
+	outputMode, err := progress.DetectOutputMode(flagValue(argsWithProg, "--progress"))
+	if err != nil && canRunBubbleTea {
+		log.Error().Msgf("invalid --progress flag: %v", err)
+		return 1
+	}
+
+	activeContext, err := contexts.Current()
+	if err != nil {
+		log.Error().Msgf("failed to load the active context: %v", err)
+		return 1
+	}
+
+	k1Dir := activeContext.K1Dir
+
+	// * create k1Dir if it doesn't exist
+	if _, err := os.Stat(k1Dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(k1Dir, os.ModePerm); err != nil {
+			log.Error().Msgf("error creating directory %q: %v", k1Dir, err)
+			return 1
+		}
+	}
+
+	// every context keeps its flags, checks and launch state in its own config file
 	config := configs.ReadConfig()
+	config.KubefirstConfigFilePath = activeContext.ConfigFile
 	if err := utils.SetupViper(config, true); err != nil {
 		log.Error().Msgf("failed to setup Viper: %v", err)
-		return
+		return 1
 	}
 
+	// credentials of the environment and the config are masked in the logs
+	redact.RegisterEnv()
+	redact.RegisterSettings(viper.AllSettings())
+
 	now := time.Now()
 	epoch := now.Unix()
 	logfileName := fmt.Sprintf("log_%d.log", epoch)
 
-	isProvision := slices.Contains(argsWithProg, "create")
-	isLogs := slices.Contains(argsWithProg, "logs")
+	isProvision := strings.HasSuffix(command, " create")
+	isApply := command == "apply"
+	isLogs := command == "logs" || strings.HasPrefix(command, "logs ")
 
 	// don't create a new log file for logs, using the previous one
 	if isLogs {
@@ -71,72 +119,169 @@ func main() {
 		logfileName = fmt.Sprintf("log_%s.log", clusterName)
 	}
 
-	homePath, err := os.UserHomeDir()
-	if err != nil {
-		log.Error().Msgf("failed to get user home directory: %v", err)
-		return
+	// apply provisions the cluster named by its spec, an invalid spec is reported by the command
+	if isApply {
+		if clusterSpec, err := spec.ReadFile(flagValue(argsWithProg, "--file", "-f")); err == nil && clusterSpec.Metadata.Name != "" {
+			logfileName = fmt.Sprintf("log_%s.log", clusterSpec.Metadata.Name)
+		}
 	}
 
-	k1Dir := fmt.Sprintf("%s/.k1", homePath)
+	// a dry run leaves no trace of the cluster: no log file named after it and
+	// no change to the config, its logs are discarded
+	isDryRun := (isProvision || isApply) && boolFlag(argsWithProg, "--dry-run")
 
-	// * create k1Dir if it doesn't exist
-	if _, err := os.Stat(k1Dir); os.IsNotExist(err) {
-		if err := os.MkdirAll(k1Dir, os.ModePerm); err != nil {
-			log.Error().Msgf("error creating directory %q: %v", k1Dir, err)
-			return
+	logsFolder := fmt.Sprintf("%s/logs", k1Dir)
+	logfile := fmt.Sprintf("%s/%s", logsFolder, logfileName)
+	var logOutput io.Writer = io.Discard
+	if !isDryRun {
+		// * create log directory if it doesn't exist
+		if _, err := os.Stat(logsFolder); os.IsNotExist(err) {
+			if err := os.Mkdir(logsFolder, 0o700); err != nil {
+				log.Error().Msgf("error creating logs directory: %v", err)
+				return 1
+			}
 		}
-	}
 
-	// * create log directory if it doesn't exist
-	logsFolder := fmt.Sprintf("%s/logs", k1Dir)
-	if _, err := os.Stat(logsFolder); os.IsNotExist(err) {
-		if err := os.Mkdir(logsFolder, 0o700); err != nil {
-			log.Error().Msgf("error creating logs directory: %v", err)
-			return
+		// * create session log file
+		logFileObj, err := utils.OpenLogFile(logfile)
+		if err != nil {
+			log.Error().Msgf("unable to store log location, error is: %v - please verify the current user has write access to this directory", err)
+			return 1
 		}
-	}
 
-	// * create session log file
-	logfile := fmt.Sprintf("%s/%s", logsFolder, logfileName)
-	logFileObj, err := utils.OpenLogFile(logfile)
-	if err != nil {
-		log.Error().Msgf("unable to store log location, error is: %v - please verify the current user has write access to this directory", err)
-		return
+		// handle file close request
+		defer func(logFileObj *os.File) {
+			if err := logFileObj.Close(); err != nil {
+				log.Error().Msgf("error closing log file: %v", err)
+			}
+		}(logFileObj)
+		logOutput = logFileObj
 	}
 
-	// handle file close request
-	defer func(logFileObj *os.File) {
-		if err := logFileObj.Close(); err != nil {
-			log.Error().Msgf("error closing log file: %v", err)
-		}
-	}(logFileObj)
-
 	// setup default logging
 	// this Go standard log is active to keep compatibility with current code base
-	stdLog.SetOutput(logFileObj)
+	logWriter := redact.NewWriter(logOutput)
+	// write the end of a last log line that has no line break
+	defer func() {
+		if err := logWriter.Flush(); err != nil {
+			log.Error().Msgf("error writing log file: %v", err)
+		}
+	}()
+	stdLog.SetOutput(logWriter)
 	stdLog.SetPrefix("LOG: ")
 	stdLog.SetFlags(stdLog.Ldate)
 
-	log.Logger = zeroLog.New(logFileObj).With().Timestamp().Logger()
+	log.Logger = zeroLog.New(logWriter).With().Timestamp().Logger()
 
-	viper.Set("k1-paths.logs-dir", logsFolder)
-	viper.Set("k1-paths.log-file", logfile)
-	viper.Set("k1-paths.log-file-name", logfileName)
+	if !isDryRun {
+		viper.Set("k1-paths.logs-dir", logsFolder)
+		viper.Set("k1-paths.log-file", logfile)
+		viper.Set("k1-paths.log-file-name", logfileName)
 
-	if err := viper.WriteConfig(); err != nil {
-		log.Error().Msgf("failed to write config: %v", err)
-		return
+		if err := viper.WriteConfig(); err != nil {
+			log.Error().Msgf("failed to write config: %v", err)
+			return 1
+		}
 	}
 
+	// record the command so `kubefirst logs list` can tell what created the log
+	if !isLogs {
+		command := commandPath(argsWithProg)
+		log.Info().Str("command", command).Msgf("running kubefirst %s", command)
+	}
+
+	if !isDryRun {
+		applyLogsRetention(logsFolder, logfile)
+	}
+
+	exitCode := cmd.ExitCodeSuccess
 	if canRunBubbleTea {
-		progress.InitializeProgressTerminal()
+		progress.InitializeProgressTerminal(outputMode)
 
+		executed := make(chan int, 1)
 		go func() {
-			cmd.Execute()
+			executed <- cmd.Execute()
 		}()
 
 		progress.Progress.Run()
+
+		// a failing command quits the terminal before returning its error, give it
+		// a moment to return its exit code without hanging on interrupted commands
+		select {
+		case exitCode = <-executed:
+		case <-time.After(time.Second):
+			exitCode = cmd.ExitCodeInterrupted
+		}
+
+		// provisioning is followed by the terminal after the command returned, a
+		// cluster that ended in error fails the process too
+		if exitCode == cmd.ExitCodeSuccess && progress.Failed() {
+			exitCode = cmd.ExitCodeError
+		}
 	} else {
-		cmd.Execute()
+		exitCode = cmd.Execute()
+	}
+
+	return exitCode
+}
+
+// commandPath is the command and its arguments up to the first flag, flag
+// values may hold credentials and are not recorded
+func commandPath(args []string) string {
+	path := []string{}
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		path = append(path, arg)
+	}
+	return strings.Join(path, " ")
+}
+
+// applyLogsRetention deletes the log files expired by the retention of the
+// kubefirst config, keeping the one of the running command
+func applyLogsRetention(logsFolder, logfile string) {
+	retention, err := provisionLogs.CurrentRetention()
+	if err != nil {
+		log.Error().Msgf("logs retention is disabled: %v", err)
+		return
+	}
+
+	deleted, err := provisionLogs.Clean(logsFolder, retention, logfile)
+	if err != nil {
+		log.Error().Msgf("error applying logs retention: %v", err)
+	}
+	if len(deleted) > 0 {
+		log.Info().Msgf("logs retention deleted %d log files", len(deleted))
+	}
+}
+
+// boolFlag reads a boolean flag before cobra parses the arguments, such as --dry-run
+func boolFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == name {
+			return true
+		}
+		if value, ok := strings.CutPrefix(arg, name+"="); ok {
+			enabled, err := strconv.ParseBool(value)
+			return err == nil && enabled
+		}
+	}
+	return false
+}
+
+// flagValue reads a flag before cobra parses the arguments, such as --progress
+// which selects the progress output
+func flagValue(args []string, names ...string) string {
+	for i, arg := range args {
+		for _, name := range names {
+			if value, ok := strings.CutPrefix(arg, name+"="); ok {
+				return value
+			}
+			if arg == name && i+1 < len(args) {
+				return args[i+1]
+			}
+		}
 	}
+	return ""
 }